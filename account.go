@@ -1,5 +1,11 @@
 package bitfinex
 
+import (
+    "context"
+    "errors"
+    "strings"
+)
+
 type AccountService struct {
     client *Client
 }
@@ -18,7 +24,12 @@ type AccountInfo struct {
 
 // GET account_infos
 func (a *AccountService) Info() (AccountInfo, error) {
-    req, err := a.client.newAuthenticatedRequest("GET", "account_infos", nil)
+    return a.InfoContext(context.Background())
+}
+
+// InfoContext is like Info with a context for the request
+func (a *AccountService) InfoContext(ctx context.Context) (AccountInfo, error) {
+    req, err := a.client.newAuthenticatedRequest(ctx, "GET", "account_infos", nil)
 
     if err != nil {
         return AccountInfo{}, err
@@ -49,8 +60,48 @@ type Permissions struct {
     Withdraw  KeyPerm
 }
 
+// Require returns an error naming every scope of required that the key
+// lacks, so that applications can check their key at startup:
+//   perm, err := client.Account.KeyPermission()
+//   ...
+//   err = perm.Require(Permissions{Orders: KeyPerm{Read: true, Write: true}})
+func (p Permissions) Require(required Permissions) error {
+    scopes := []struct {
+        name       string
+        have, want KeyPerm
+    }{
+        {"account", p.Account, required.Account},
+        {"history", p.History, required.History},
+        {"orders", p.Orders, required.Orders},
+        {"positions", p.Positions, required.Positions},
+        {"funding", p.Funding, required.Funding},
+        {"wallets", p.Wallets, required.Wallets},
+        {"withdraw", p.Withdraw, required.Withdraw},
+    }
+
+    var missing []string
+    for _, s := range scopes {
+        if s.want.Read && !s.have.Read {
+            missing = append(missing, s.name+" read")
+        }
+        if s.want.Write && !s.have.Write {
+            missing = append(missing, s.name+" write")
+        }
+    }
+    if len(missing) > 0 {
+        return errors.New("API key lacks permissions: " + strings.Join(missing, ", "))
+    }
+    return nil
+}
+
+// GET key_info
 func (a *AccountService) KeyPermission() (Permissions, error) {
-    req, err := a.client.newAuthenticatedRequest("GET", "key_info", nil)
+    return a.KeyPermissionContext(context.Background())
+}
+
+// KeyPermissionContext is like KeyPermission with a context for the request
+func (a *AccountService) KeyPermissionContext(ctx context.Context) (Permissions, error) {
+    req, err := a.client.newAuthenticatedRequest(ctx, "GET", "key_info", nil)
 
     if err != nil {
         return Permissions{}, err
@@ -80,7 +131,12 @@ type Summary struct {
 }
 
 func (a *AccountService) Summary() (Summary, error) {
-    req, err := a.client.newAuthenticatedRequest("GET", "summary", nil)
+    return a.SummaryContext(context.Background())
+}
+
+// SummaryContext is like Summary with a context for the request
+func (a *AccountService) SummaryContext(ctx context.Context) (Summary, error) {
+    req, err := a.client.newAuthenticatedRequest(ctx, "GET", "summary", nil)
 
     if err != nil {
         return Summary{}, err
@@ -100,4 +100,14 @@ func TestAccountKeyPermission(t *testing.T) {
         t.Error("Expected", false)
         t.Error("Actual ", perm.History.Write)
     }
+
+    if err := perm.Require(Permissions{Orders: KeyPerm{Read: true, Write: true}}); err != nil {
+        t.Error(err)
+    }
+
+    err = perm.Require(Permissions{History: KeyPerm{Read: true, Write: true}, Withdraw: KeyPerm{Write: true}})
+    if err == nil || err.Error() != "API key lacks permissions: history write, withdraw write" {
+        t.Error("Expected", "API key lacks permissions: history write, withdraw write")
+        t.Error("Actual ", err)
+    }
 }
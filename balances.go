@@ -1,5 +1,7 @@
 package bitfinex
 
+import "context"
+
 type BalancesService struct {
     client *Client
 }
@@ -13,7 +15,12 @@ type WalletBalance struct {
 
 // GET balances
 func (b *BalancesService) All() ([]WalletBalance, error) {
-    req, err := b.client.newAuthenticatedRequest("GET", "balances", nil)
+    return b.AllContext(context.Background())
+}
+
+// AllContext is like All with a context for the request
+func (b *BalancesService) AllContext(ctx context.Context) ([]WalletBalance, error) {
+    req, err := b.client.newAuthenticatedRequest(ctx, "GET", "balances", nil)
     if err != nil {
         return nil, err
     }
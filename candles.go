@@ -0,0 +1,52 @@
+package bitfinex
+
+import (
+    "context"
+    "net/url"
+    "strconv"
+    "time"
+)
+
+// CandlesService serves the historical candles of the v2 API, see
+// SubscribeCandles for the live ones.
+type CandlesService struct {
+    client *Client
+}
+
+// Get returns up to limit candles of pair for timeframe, e.g. "1m", "1h"
+// or "1D", oldest first. Zero start, end or limit use the defaults of
+// Bitfinex.
+func (s *CandlesService) Get(pair, timeframe string, start, end time.Time, limit int) ([]Candle, error) {
+    return s.GetContext(context.Background(), pair, timeframe, start, end, limit)
+}
+
+// GetContext is like Get with a context for the request
+func (s *CandlesService) GetContext(ctx context.Context, pair, timeframe string, start, end time.Time, limit int) ([]Candle, error) {
+    params := url.Values{}
+    params.Add("sort", "1")
+    if !start.IsZero() {
+        params.Add("start", strconv.FormatInt(start.UnixNano()/int64(time.Millisecond), 10))
+    }
+    if !end.IsZero() {
+        params.Add("end", strconv.FormatInt(end.UnixNano()/int64(time.Millisecond), 10))
+    }
+    if limit != 0 {
+        params.Add("limit", strconv.Itoa(limit))
+    }
+
+    req, err := s.client.newV2Request(ctx, "GET", "candles/"+CandleKey(pair, timeframe)+"/hist", params)
+
+    if err != nil {
+        return nil, err
+    }
+
+    var v [][]float64
+
+    _, err = s.client.do(req, &v)
+
+    if err != nil {
+        return nil, err
+    }
+
+    return decodeCandles(v), nil
+}
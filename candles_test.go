@@ -0,0 +1,37 @@
+package bitfinex
+
+import (
+    "bytes"
+    "io/ioutil"
+    "net/http"
+    "testing"
+    "time"
+)
+
+func TestCandlesGet(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        expected := "https://api.bitfinex.com/v2/candles/trade:1m:tBTCUSD/hist?end=1364824500000&limit=2&sort=1&start=1364824380000"
+        if req.URL.String() != expected {
+            t.Error("Expected", expected)
+            t.Error("Actual ", req.URL.String())
+        }
+        msg := `[[1364824380000,4,5,6,3,1.5],[1364824440000,5,6,7,4,2]]`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    candles, err := NewClient().Candles.Get("btcusd", "1m", time.Unix(1364824380, 0), time.Unix(1364824500, 0), 2)
+
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(candles) != 2 {
+        t.Fatal("Expected 2 candles, got", candles)
+    }
+    if !candles[1].Timestamp.Equal(time.Unix(1364824440, 0)) || candles[1].Close != 6 || candles[1].Volume != 2 {
+        t.Error("Unexpected candle", candles[1])
+    }
+}
@@ -2,6 +2,8 @@
 package bitfinex
 
 import (
+	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha512"
 	"encoding/base64"
@@ -11,17 +13,29 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	DefaultBaseURL        = "https://api.bitfinex.com/v1/"
-	DefaultBaseV2URL      = "https://api.bitfinex.com/v1/"
+	DefaultBaseV2URL      = "https://api.bitfinex.com/v2/"
 	DefaultWebSocketURL   = "wss://api.bitfinex.com/ws"
 	DefaultWebSocketV2URL = "wss://api.bitfinex.com/ws/2"
 )
 
-var nonce int64
+// the last nonce sent, shared by all clients since nonces must increase
+// for every request made with an API key
+var (
+	nonceMu sync.Mutex
+	nonce   int64
+)
+
+// Clock is the time source of a Client.
+type Clock interface {
+	Now() time.Time
+}
 
 type Param struct {
 	Key string
@@ -29,19 +43,33 @@ type Param struct {
 }
 
 type Client struct {
-	// Base URL for API requests.
-	BaseURL                *url.URL
+	// Base URL for API requests, DefaultBaseURL unless set. Point it at
+	// a sandbox or a mock server to redirect every REST method.
+	BaseURL *url.URL
+	// Base URL for the few requests only served by v2, DefaultBaseV2URL
+	// unless set.
+	BaseV2URL              *url.URL
 	WebSocketURL           string
 	WebSocketTLSSkipVerify bool
 
-	// Auth data
+	// Auth data. Once the client is in use, change them with Auth only:
+	// requests and RotateCredentials read and write them under credMu.
 	ApiKey    string
 	ApiSecret string
+	credMu    sync.RWMutex
+
+	// Optional limiter every REST request waits on before it is sent.
+	RateLimiter *RateLimiter
+
+	// Clock is used for nonces and authentication payloads, the system
+	// clock when nil. Tests can set a fixed clock.
+	Clock Clock
 
 	// Services
 	Pairs         *PairsService
 	Stats         *StatsService
 	Ticker        *TickerService
+	Candles       *CandlesService
 	Account       *AccountService
 	Balances      *BalancesService
 	Offers        *OffersService
@@ -57,17 +85,23 @@ type Client struct {
 	History       *HistoryService
 	WebSocket     *WebSocketService
 	Wallet        *WalletService
+
+	// the timer of SetCancelAllAfter
+	deadmanMu sync.Mutex
+	deadman   *time.Timer
 }
 
 // NewClient creates new Bitfinex.com API http client
 func NewClient() *Client {
 	baseURL, _ := url.Parse(DefaultBaseURL)
+	baseV2URL, _ := url.Parse(DefaultBaseV2URL)
 
-	c := &Client{BaseURL: baseURL, WebSocketURL: DefaultWebSocketURL}
+	c := &Client{BaseURL: baseURL, BaseV2URL: baseV2URL, WebSocketURL: DefaultWebSocketURL}
 	c.Pairs = &PairsService{client: c}
 	c.Stats = &StatsService{client: c}
 	c.Account = &AccountService{client: c}
 	c.Ticker = &TickerService{client: c}
+	c.Candles = &CandlesService{client: c}
 	c.Balances = &BalancesService{client: c}
 	c.Offers = &OffersService{client: c}
 	c.Credits = &CreditsService{client: c}
@@ -88,7 +122,8 @@ func NewClient() *Client {
 }
 
 // NewRequest create new API request. Relative url can be provided in refUrl.
-func (c *Client) newRequest(method string, refUrl string, params url.Values) (*http.Request, error) {
+// The request is bound to ctx, cancelling it aborts the request.
+func (c *Client) newRequest(ctx context.Context, method string, refUrl string, params url.Values) (*http.Request, error) {
 	rel, err := url.Parse(refUrl)
 	if err != nil {
 		return nil, err
@@ -98,7 +133,7 @@ func (c *Client) newRequest(method string, refUrl string, params url.Values) (*h
 	}
 	var req *http.Request
 	u := c.BaseURL.ResolveReference(rel)
-	req, err = http.NewRequest(method, u.String(), nil)
+	req, err = http.NewRequestWithContext(ctx, method, u.String(), nil)
 
 	if err != nil {
 		return nil, err
@@ -107,25 +142,46 @@ func (c *Client) newRequest(method string, refUrl string, params url.Values) (*h
 	return req, nil
 }
 
-// getNonce - getting unique nonce
-func getNonce() int64 {
-	if nonce == 0 {
-		nonce = time.Now().UnixNano()
+// newV2Request is like newRequest for a path relative to BaseV2URL.
+func (c *Client) newV2Request(ctx context.Context, method string, refUrl string, params url.Values) (*http.Request, error) {
+	rel, err := url.Parse(refUrl)
+	if err != nil {
+		return nil, err
+	}
+	return c.newRequest(ctx, method, c.BaseV2URL.ResolveReference(rel).String(), params)
+}
+
+func (c *Client) now() time.Time {
+	if c.Clock != nil {
+		return c.Clock.Now()
+	}
+	return time.Now()
+}
+
+// getNonce - getting unique nonce, the current time in nanoseconds unless
+// a previous nonce was larger
+func (c *Client) getNonce() int64 {
+	n := c.now().UnixNano()
+	nonceMu.Lock()
+	defer nonceMu.Unlock()
+	if n <= nonce {
+		n = nonce + 1
 	}
-	nonce++
-	return nonce
+	nonce = n
+	return n
 }
 
 // NewAuthenticatedRequest creates new http request for authenticated routes
-func (c *Client) newAuthenticatedRequest(m string, refUrl string, data map[string]interface{}) (*http.Request, error) {
-	req, err := c.newRequest(m, refUrl, nil)
+func (c *Client) newAuthenticatedRequest(ctx context.Context, m string, refUrl string, data map[string]interface{}) (*http.Request, error) {
+	req, err := c.newRequest(ctx, m, refUrl, nil)
 	if err != nil {
 		return nil, err
 	}
 
+	// the signed request path must match the URL the request is sent to
 	payload := map[string]interface{}{
-		"request": "/v1/" + refUrl,
-		"nonce":   fmt.Sprintf("%v", getNonce()),
+		"request": req.URL.Path,
+		"nonce":   fmt.Sprintf("%v", c.getNonce()),
 	}
 
 	if len(data) > 0 {
@@ -135,19 +191,57 @@ func (c *Client) newAuthenticatedRequest(m string, refUrl string, data map[strin
 	}
 
 	payload_json, _ := json.Marshal(payload)
-	payload_enc := base64.StdEncoding.EncodeToString(payload_json)
+	payload_enc, signature := c.signRESTPayload(payload_json)
 
 	req.Header.Add("Content-Type", "application/json")
 	req.Header.Add("Accept", "application/json")
-	req.Header.Add("X-BFX-APIKEY", c.ApiKey)
+	req.Header.Add("X-BFX-APIKEY", c.apiKey())
 	req.Header.Add("X-BFX-PAYLOAD", payload_enc)
-	req.Header.Add("X-BFX-SIGNATURE", c.signPayload(payload_enc))
+	req.Header.Add("X-BFX-SIGNATURE", signature)
+
+	return req, nil
+}
+
+// newAuthenticatedV2Request creates a POST request for an authenticated
+// route of BaseV2URL, e.g. auth/r/ledgers/hist, with data as JSON body. v2
+// signs the request path, the nonce and the body instead of a payload
+// header.
+func (c *Client) newAuthenticatedV2Request(ctx context.Context, refUrl string, data map[string]interface{}) (*http.Request, error) {
+	rel, err := url.Parse(refUrl)
+	if err != nil {
+		return nil, err
+	}
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	body, _ := json.Marshal(data)
+	u := c.BaseV2URL.ResolveReference(rel)
+	req, err := http.NewRequestWithContext(ctx, "POST", u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := fmt.Sprintf("%v", c.getNonce())
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("bfx-nonce", nonce)
+	req.Header.Add("bfx-apikey", c.apiKey())
+	req.Header.Add("bfx-signature", c.signPayload("/api"+u.Path+nonce+string(body)))
 
 	return req, nil
 }
 
+// signRESTPayload signs the JSON body of an authenticated REST request.
+// It returns the base64 encoded body and its HMAC-SHA384 signature, sent
+// as the X-BFX-PAYLOAD and X-BFX-SIGNATURE headers.
+func (c *Client) signRESTPayload(body []byte) (payloadB64, signature string) {
+	payloadB64 = base64.StdEncoding.EncodeToString(body)
+	return payloadB64, c.signPayload(payloadB64)
+}
+
 func (c *Client) signPayload(payload string) string {
-	sig := hmac.New(sha512.New384, []byte(c.ApiSecret))
+	_, secret := c.credentials()
+	sig := hmac.New(sha512.New384, []byte(secret))
 	sig.Write([]byte(payload))
 	return hex.EncodeToString(sig.Sum(nil))
 }
@@ -155,18 +249,38 @@ func (c *Client) signPayload(payload string) string {
 // Auth sets api key and secret for usage is requests that
 // requires authentication
 func (c *Client) Auth(key string, secret string) *Client {
+	c.credMu.Lock()
 	c.ApiKey = key
 	c.ApiSecret = secret
+	c.credMu.Unlock()
 
 	return c
 }
 
+// credentials returns the api key and secret set with Auth.
+func (c *Client) credentials() (key, secret string) {
+	c.credMu.RLock()
+	defer c.credMu.RUnlock()
+	return c.ApiKey, c.ApiSecret
+}
+
+func (c *Client) apiKey() string {
+	key, _ := c.credentials()
+	return key
+}
+
 var httpDo = func(req *http.Request) (*http.Response, error) {
 	return http.DefaultClient.Do(req)
 }
 
 // Do executes API request created by NewRequest method or custom *http.Request.
 func (c *Client) do(req *http.Request, v interface{}) (*Response, error) {
+	if c.RateLimiter != nil {
+		if err := c.RateLimiter.Wait(req.Context()); err != nil {
+			return nil, err
+		}
+	}
+
 	resp, err := httpDo(req)
 
 	if err != nil {
@@ -178,6 +292,8 @@ func (c *Client) do(req *http.Request, v interface{}) (*Response, error) {
 
 	err = checkResponse(response)
 	if err != nil {
+		errorResponse := err.(*ErrorResponse)
+		errorResponse.Message = c.redact(errorResponse.Message, req.Header.Get("X-BFX-SIGNATURE"))
 		// Return response in case caller need to debug it.
 		return response, err
 	}
@@ -193,6 +309,21 @@ func (c *Client) do(req *http.Request, v interface{}) (*Response, error) {
 	return response, nil
 }
 
+// REDACTED replaces the API key, secret and signatures in error messages
+// and in the frames passed to OnSendFrame.
+const REDACTED = "***"
+
+// redact replaces the credentials of c and the given values in s.
+func (c *Client) redact(s string, values ...string) string {
+	key, secret := c.credentials()
+	for _, v := range append([]string{key, secret}, values...) {
+		if v != "" {
+			s = strings.ReplaceAll(s, v, REDACTED)
+		}
+	}
+	return s
+}
+
 // Response is wrapper for standard http.Response and provides
 // more methods.
 type Response struct {
@@ -0,0 +1,216 @@
+package bitfinex
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignRESTPayload(t *testing.T) {
+	c := NewClient().Auth("api-key", "api-secret")
+
+	payload, signature := c.signRESTPayload([]byte(`{"nonce":"1444276597000000001","request":"/v1/orders"}`))
+
+	expectedPayload := "eyJub25jZSI6IjE0NDQyNzY1OTcwMDAwMDAwMDEiLCJyZXF1ZXN0IjoiL3YxL29yZGVycyJ9"
+	if payload != expectedPayload {
+		t.Error("Expected", expectedPayload)
+		t.Error("Actual ", payload)
+	}
+
+	expectedSignature := "a89382beeb4efed4bc5f4fbf5807e0c57e8e92f8b0c0e3761323237562ff7a2fbd3a486472161999b8eddb73ab54591f"
+	if signature != expectedSignature {
+		t.Error("Expected", expectedSignature)
+		t.Error("Actual ", signature)
+	}
+}
+
+func TestAuthenticatedRequestHeaders(t *testing.T) {
+	c := NewClient().Auth("api-key", "api-secret")
+
+	req, err := c.newAuthenticatedRequest(context.Background(), "POST", "orders", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload := req.Header.Get("X-BFX-PAYLOAD")
+	if _, signature := c.signRESTPayload(nil); req.Header.Get("X-BFX-SIGNATURE") == signature {
+		t.Error("Expected signature of the request payload, not of an empty body")
+	}
+	if req.Header.Get("X-BFX-SIGNATURE") != c.signPayload(payload) {
+		t.Error("Signature does not match the X-BFX-PAYLOAD header")
+	}
+	if req.Header.Get("X-BFX-APIKEY") != "api-key" {
+		t.Error("Expected", "api-key")
+		t.Error("Actual ", req.Header.Get("X-BFX-APIKEY"))
+	}
+}
+
+// Run with -race: rotating the credentials must not race with signing.
+func TestAuthConcurrentRequests(t *testing.T) {
+	c := NewClient().Auth("api-key", "api-secret")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			c.Auth("new-key", "new-secret")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		req, err := c.newAuthenticatedRequest(context.Background(), "POST", "orders", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.redact(req.Header.Get("X-BFX-PAYLOAD"))
+	}
+	<-done
+
+	if key := c.apiKey(); key != "new-key" {
+		t.Error("Expected", "new-key")
+		t.Error("Actual ", key)
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	var requested string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+		var payload map[string]interface{}
+		json.Unmarshal(raw, &payload)
+		requested, _ = payload["request"].(string)
+		rw.Write([]byte(`[]`))
+	}))
+	defer srv.Close()
+
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		return http.DefaultClient.Do(req)
+	}
+
+	c := NewClient().Auth("api-key", "api-secret")
+	c.BaseURL, _ = url.Parse(srv.URL + "/sandbox/v1/")
+
+	if _, err := c.Orders.All(); err != nil {
+		t.Fatal(err)
+	}
+	if requested != "/sandbox/v1/orders" {
+		t.Error("Expected", "/sandbox/v1/orders")
+		t.Error("Actual ", requested)
+	}
+}
+
+type testContextKey struct{}
+
+func TestRequestContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), testContextKey{}, "order")
+	var value interface{}
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		value = req.Context().Value(testContextKey{})
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`[]`)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+
+	if _, err := NewClient().Orders.AllContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if value != "order" {
+		t.Error("Expected request bound to the context")
+	}
+}
+
+func TestRequestContextRateLimit(t *testing.T) {
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`[]`)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+
+	c := NewClient()
+	c.RateLimiter = NewRateLimiter(1)
+	if _, err := c.Pairs.All(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Pairs.AllContext(ctx); err == nil {
+		t.Error("Expected rate limit error before the deadline")
+	}
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestClockNonce(t *testing.T) {
+	now := time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewClient()
+	c.Clock = fixedClock(now)
+	nonceMu.Lock()
+	saved := nonce
+	nonce = 0
+	nonceMu.Unlock()
+	// the nonces of 2100 would be ahead of the ones of the other tests
+	defer func() {
+		nonceMu.Lock()
+		nonce = saved
+		nonceMu.Unlock()
+	}()
+
+	var nonces []string
+	for i := 0; i < 2; i++ {
+		req, err := c.newAuthenticatedRequest(context.Background(), "POST", "orders", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+		var payload map[string]string
+		json.Unmarshal(raw, &payload)
+		nonces = append(nonces, payload["nonce"])
+	}
+
+	expected := []string{"4102444800000000000", "4102444800000000001"}
+	if nonces[0] != expected[0] || nonces[1] != expected[1] {
+		t.Error("Expected", expected)
+		t.Error("Actual ", nonces)
+	}
+}
+
+func TestErrorRedaction(t *testing.T) {
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		msg := `{"message":"Invalid signature ` + req.Header.Get("X-BFX-SIGNATURE") + ` for key api-key made with api-secret"}`
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+			StatusCode: 400,
+			Request:    req,
+		}
+		return &resp, nil
+	}
+
+	c := NewClient().Auth("api-key", "api-secret")
+	_, err := c.Account.Info()
+	if err == nil {
+		t.Fatal("Expected an auth error")
+	}
+	msg := err.Error()
+	for _, secret := range []string{"api-key", "api-secret"} {
+		if strings.Contains(msg, secret) {
+			t.Error("Expected no", secret, "in", msg)
+		}
+	}
+	if !strings.HasSuffix(msg, "Invalid signature *** for key *** made with ***") {
+		t.Error("Unexpected error", msg)
+	}
+}
@@ -1,5 +1,12 @@
 package bitfinex
 
+import (
+    "context"
+    "encoding/json"
+    "strconv"
+    "time"
+)
+
 type CreditsService struct {
     client *Client
 }
@@ -8,15 +15,51 @@ type Credit struct {
     Id        int
     Currency  string
     Status    string
-    Rate      float64
+    Rate      float64 `json:",string"`
     Period    float64
-    Amount    float64
-    Timestamp string
+    Amount    float64 `json:",string"`
+    Timestamp float64 `json:",string"`
+
+    // Decimals holds the exact rate, amount and timestamp
+    Decimals CreditDecimals `json:"-"`
+}
+
+// CreditDecimals are the rate, amount and timestamp of a Credit as sent by
+// Bitfinex
+type CreditDecimals struct {
+    Rate   Decimal `json:"rate"`
+    Amount Decimal `json:"amount"`
+    // Timestamp keeps the digits float64 rounds away
+    Timestamp Decimal `json:"timestamp"`
+}
+
+func (c *Credit) UnmarshalJSON(data []byte) error {
+    type credit Credit
+    if err := json.Unmarshal(data, (*credit)(c)); err != nil {
+        return err
+    }
+    return json.Unmarshal(data, &c.Decimals)
+}
+
+// Time returns the time the credit was opened
+func (c *Credit) Time() *time.Time {
+    ts := string(c.Decimals.Timestamp)
+    if ts == "" {
+        // built rather than decoded
+        ts = strconv.FormatFloat(c.Timestamp, 'f', -1, 64)
+    }
+    t, _ := parseTimestamp(ts)
+    return &t
 }
 
 // Returns an array of Credit
 func (c *CreditsService) All() ([]Credit, error) {
-    req, err := c.client.newAuthenticatedRequest("GET", "credits", nil)
+    return c.AllContext(context.Background())
+}
+
+// AllContext is like All with a context for the request
+func (c *CreditsService) AllContext(ctx context.Context) ([]Credit, error) {
+    req, err := c.client.newAuthenticatedRequest(ctx, "GET", "credits", nil)
     if err != nil {
         return nil, err
     }
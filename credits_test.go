@@ -0,0 +1,42 @@
+package bitfinex
+
+import (
+    "bytes"
+    "io/ioutil"
+    "net/http"
+    "testing"
+)
+
+func TestCreditsAll(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        msg := `[{
+          "id":216,
+          "currency":"USD",
+          "status":"ACTIVE",
+          "rate":"9.8998",
+          "period":2,
+          "amount":"100.0",
+          "timestamp":"1444280948.123456789"
+        }]`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    credits, err := NewClient().Credits.All()
+
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(credits) != 1 || credits[0].Rate != 9.8998 || credits[0].Amount != 100 {
+        t.Error("Unexpected credits", credits)
+    }
+
+    if tm := credits[0].Time(); tm.Unix() != 1444280948 || tm.Nanosecond() != 123456789 {
+        t.Error("Expected", "1444280948.123456789")
+        t.Error("Actual ", tm.Unix(), tm.Nanosecond())
+    }
+}
@@ -0,0 +1,86 @@
+package bitfinex
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+)
+
+// cancelAllTimeout bounds the request cancelling the orders once the dead
+// man's switch fires.
+const cancelAllTimeout = 10 * time.Second
+
+// dmsCancelAll is the auth flag asking Bitfinex to cancel every order of
+// the account when the authenticated connection closes.
+const dmsCancelAll = 4
+
+// SetCancelAllAfter arms a dead man's switch: every active order is
+// cancelled unless SetCancelAllAfter is called again within timeout, which
+// refreshes it, e.g. from the loop of a trading bot. A zero timeout
+// disarms it.
+//
+// The timer runs in the process, so it cannot help once the process dies.
+// The protection against that is server side and needs the private
+// connection of c.WebSocket: authenticated while the switch is armed, it
+// sets the Bitfinex dead man's switch flag and the server cancels the
+// orders itself when the connection closes. A private connection already
+// running when the switch is armed is re-authenticated for it, its TermData
+// channel gets a STATUS_CANCEL_ALL_ARMED status followed by fresh
+// snapshots. Disarming does not clear the flag of a running connection.
+func (c *Client) SetCancelAllAfter(timeout time.Duration) error {
+	if timeout < 0 {
+		return errors.New("cancel all after: negative timeout")
+	}
+	if err := c.setCancelAllAfter(timeout); err != nil || timeout == 0 {
+		return err
+	}
+	// outside deadmanMu, taken by the websocket under its own lock
+	if c.WebSocket != nil {
+		c.WebSocket.armCancelAll()
+	}
+	return nil
+}
+
+func (c *Client) setCancelAllAfter(timeout time.Duration) error {
+	c.deadmanMu.Lock()
+	defer c.deadmanMu.Unlock()
+	if c.deadman != nil {
+		c.deadman.Stop()
+		c.deadman = nil
+	}
+	if timeout == 0 {
+		return nil
+	}
+	if key, secret := c.credentials(); key == "" || secret == "" {
+		return errors.New("cancel all after: API credentials required")
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(timeout, func() {
+		c.deadmanMu.Lock()
+		fired := c.deadman == timer
+		if fired {
+			c.deadman = nil
+		}
+		c.deadmanMu.Unlock()
+		if !fired {
+			// refreshed or disarmed meanwhile
+			return
+		}
+		log.Println("Cancel all after", timeout, "elapsed, cancelling all orders")
+		ctx, cancel := context.WithTimeout(context.Background(), cancelAllTimeout)
+		defer cancel()
+		if err := c.Orders.CancelAllContext(ctx); err != nil {
+			log.Println("Error cancelling all orders", err)
+		}
+	})
+	c.deadman = timer
+	return nil
+}
+
+// cancelAllArmed reports whether the dead man's switch is armed.
+func (c *Client) cancelAllArmed() bool {
+	c.deadmanMu.Lock()
+	defer c.deadmanMu.Unlock()
+	return c.deadman != nil
+}
@@ -0,0 +1,136 @@
+package bitfinex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSetCancelAllAfter(t *testing.T) {
+	cancelled := make(chan string, 2)
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		cancelled <- req.URL.Path
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`{"result":"All orders cancelled"}`)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+
+	c := NewClient()
+	if err := c.SetCancelAllAfter(time.Second); err == nil {
+		t.Error("Expected an error without credentials")
+	}
+	c.Auth("api-key", "api-secret")
+	defer c.SetCancelAllAfter(0)
+
+	// refreshed in time, nothing is cancelled
+	for i := 0; i < 4; i++ {
+		if err := c.SetCancelAllAfter(60 * time.Millisecond); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	select {
+	case path := <-cancelled:
+		t.Fatal("Unexpected request", path)
+	default:
+	}
+
+	select {
+	case path := <-cancelled:
+		if path != "/v1/order/cancel/all" {
+			t.Error("Expected", "/v1/order/cancel/all")
+			t.Error("Actual ", path)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the orders to be cancelled")
+	}
+	if c.cancelAllArmed() {
+		t.Error("Expected the switch to be disarmed once fired")
+	}
+
+	// disarmed
+	c.SetCancelAllAfter(20 * time.Millisecond)
+	c.SetCancelAllAfter(0)
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case path := <-cancelled:
+		t.Error("Unexpected request after disarming", path)
+	default:
+	}
+}
+
+func TestCancelAllAfterAuthFlag(t *testing.T) {
+	auth := make(chan privateConnect, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		auth <- readAuth(t, ws)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.Auth("api-key", "api-secret")
+	c.SetCancelAllAfter(time.Minute)
+	defer c.SetCancelAllAfter(0)
+	go c.WebSocket.ConnectPrivate(make(chan TermData, 10))
+	defer c.WebSocket.ClosePrivate()
+
+	select {
+	case msg := <-auth:
+		if msg.Dms != dmsCancelAll {
+			t.Error("Expected", dmsCancelAll)
+			t.Error("Actual ", msg.Dms)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the auth message")
+	}
+}
+
+func TestCancelAllAfterReauth(t *testing.T) {
+	auth := make(chan privateConnect, 2)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		auth <- readAuth(t, ws)
+		writeFrames(ws, `[0,"os",[]]`)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.Auth("api-key", "api-secret")
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+	receiveTerm(t, terms)
+	if msg := <-auth; msg.Dms != 0 {
+		t.Error("Expected", 0)
+		t.Error("Actual ", msg.Dms)
+	}
+
+	c.SetCancelAllAfter(time.Minute)
+	defer c.SetCancelAllAfter(0)
+	if v := receiveTerm(t, terms); v.Status != STATUS_CANCEL_ALL_ARMED {
+		t.Error("Expected", STATUS_CANCEL_ALL_ARMED)
+		t.Error("Actual ", v)
+	}
+	select {
+	case msg := <-auth:
+		if msg.Dms != dmsCancelAll {
+			t.Error("Expected", dmsCancelAll)
+			t.Error("Actual ", msg.Dms)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the auth message")
+	}
+	receiveTerm(t, terms)
+
+	// refreshing an armed switch keeps the connection
+	c.SetCancelAllAfter(time.Minute)
+	select {
+	case v := <-terms:
+		t.Error("Unexpected term data", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,32 @@
+package bitfinex
+
+import (
+    "encoding/json"
+    "strconv"
+)
+
+// Decimal is a number in the exact decimal representation Bitfinex sent,
+// for callers that can't afford float64 rounding. Pass it to a decimal
+// library of your choice, e.g. decimal.NewFromString(string(d))
+type Decimal string
+
+// Float64 returns the number as a float64, or 0 if it is empty or invalid
+func (d Decimal) Float64() float64 {
+    f, _ := strconv.ParseFloat(string(d), 64)
+    return f
+}
+
+// UnmarshalJSON accepts both quoted and bare JSON numbers
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+    var s string
+    if err := json.Unmarshal(data, &s); err == nil {
+        *d = Decimal(s)
+        return nil
+    }
+    var n json.Number
+    if err := json.Unmarshal(data, &n); err != nil {
+        return err
+    }
+    *d = Decimal(n)
+    return nil
+}
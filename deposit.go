@@ -1,6 +1,9 @@
 package bitfinex
 
-import "errors"
+import (
+    "context"
+    "errors"
+)
 
 type DepositService struct {
     client *Client
@@ -23,6 +26,11 @@ func (d *DepositResponse) Success() (bool, error) {
 }
 
 func (s *DepositService) New(method, walletName string, renew int) (DepositResponse, error) {
+    return s.NewContext(context.Background(), method, walletName, renew)
+}
+
+// NewContext is like New with a context for the request
+func (s *DepositService) NewContext(ctx context.Context, method, walletName string, renew int) (DepositResponse, error) {
 
     payload := map[string]interface{}{
         "method":      method,
@@ -30,7 +38,7 @@ func (s *DepositService) New(method, walletName string, renew int) (DepositRespo
         "renew":       renew,
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "deposit/new", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "deposit/new", payload)
 
     if err != nil {
         return DepositResponse{}, err
@@ -1,6 +1,10 @@
 package bitfinex
 
-import "time"
+import (
+    "context"
+    "strings"
+    "time"
+)
 
 type HistoryService struct {
     client *Client
@@ -15,6 +19,11 @@ type Balance struct {
 }
 
 func (s *HistoryService) Balance(currency, wallet string, since, until time.Time, limit int) ([]Balance, error) {
+    return s.BalanceContext(context.Background(), currency, wallet, since, until, limit)
+}
+
+// BalanceContext is like Balance with a context for the request
+func (s *HistoryService) BalanceContext(ctx context.Context, currency, wallet string, since, until time.Time, limit int) ([]Balance, error) {
 
     payload := map[string]interface{}{"currency": currency}
 
@@ -28,7 +37,7 @@ func (s *HistoryService) Balance(currency, wallet string, since, until time.Time
         payload["limit"] = limit
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "history", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "history", payload)
 
     if err != nil {
         return nil, err
@@ -57,6 +66,11 @@ type Movement struct {
 }
 
 func (s *HistoryService) Movements(currency, method string, since, until time.Time, limit int) ([]Movement, error) {
+    return s.MovementsContext(context.Background(), currency, method, since, until, limit)
+}
+
+// MovementsContext is like Movements with a context for the request
+func (s *HistoryService) MovementsContext(ctx context.Context, currency, method string, since, until time.Time, limit int) ([]Movement, error) {
 
     payload := map[string]interface{}{"currency": currency, "method": method}
 
@@ -70,7 +84,7 @@ func (s *HistoryService) Movements(currency, method string, since, until time.Ti
         payload["limit"] = limit
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "history/movements", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "history/movements", payload)
 
     if err != nil {
         return nil, err
@@ -100,7 +114,12 @@ type PastTrade struct {
 }
 
 func (s *HistoryService) Trades(pair string, since, until time.Time, limit int, reverse bool) ([]PastTrade, error) {
-    payload := map[string]interface{}{"symbol": pair}
+    return s.TradesContext(context.Background(), pair, since, until, limit, reverse)
+}
+
+// TradesContext is like Trades with a context for the request
+func (s *HistoryService) TradesContext(ctx context.Context, pair string, since, until time.Time, limit int, reverse bool) ([]PastTrade, error) {
+    payload := map[string]interface{}{"symbol": NormalizeSymbol(pair, SYMBOL_REST)}
 
     if !since.IsZero() {
         payload["timestamp"] = since.Unix()
@@ -115,7 +134,7 @@ func (s *HistoryService) Trades(pair string, since, until time.Time, limit int,
         payload["reverse"] = 1
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "mytrades", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "mytrades", payload)
 
     if err != nil {
         return nil, err
@@ -131,3 +150,177 @@ func (s *HistoryService) Trades(pair string, since, until time.Time, limit int,
 
     return v, nil
 }
+
+// FundingTrade is a fill of one of the account's funding offers or bids.
+type FundingTrade struct {
+    Rate      string
+    Period    int
+    Amount    string
+    Timestamp string
+    Type      string
+    TID       int64
+    OfferId   int64 `json:"offer_id"`
+}
+
+// FundingTrades returns the funding trades of currency, the funding
+// equivalent of Trades.
+func (s *HistoryService) FundingTrades(currency string, since time.Time, limit int) ([]FundingTrade, error) {
+    return s.FundingTradesContext(context.Background(), currency, since, limit)
+}
+
+// FundingTradesContext is like FundingTrades with a context for the request
+func (s *HistoryService) FundingTradesContext(ctx context.Context, currency string, since time.Time, limit int) ([]FundingTrade, error) {
+    payload := map[string]interface{}{"symbol": currency}
+
+    if !since.IsZero() {
+        payload["timestamp"] = since.Unix()
+    }
+    if limit != 0 {
+        payload["limit_trades"] = limit
+    }
+
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "mytrades_funding", payload)
+
+    if err != nil {
+        return nil, err
+    }
+
+    var v []FundingTrade
+
+    _, err = s.client.do(req, &v)
+
+    if err != nil {
+        return nil, err
+    }
+
+    return v, nil
+}
+
+// LedgerCategory is the category of a ledger entry, used to filter Ledger
+type LedgerCategory int
+
+const (
+    // Every category, no filter
+    LEDGER_CATEGORY_ALL                  LedgerCategory = 0
+    LEDGER_CATEGORY_EXCHANGE             LedgerCategory = 5
+    LEDGER_CATEGORY_POSITION_MODIFIED    LedgerCategory = 22
+    LEDGER_CATEGORY_POSITION_CLAIM       LedgerCategory = 23
+    LEDGER_CATEGORY_POSITION_TRANSFER    LedgerCategory = 25
+    LEDGER_CATEGORY_POSITION_SWAP        LedgerCategory = 26
+    LEDGER_CATEGORY_POSITION_FUNDING     LedgerCategory = 27
+    LEDGER_CATEGORY_MARGIN_INTEREST      LedgerCategory = 28
+    LEDGER_CATEGORY_DERIVATIVES_FUNDING  LedgerCategory = 29
+    LEDGER_CATEGORY_SETTLEMENT           LedgerCategory = 31
+    LEDGER_CATEGORY_TRANSFER             LedgerCategory = 51
+    LEDGER_CATEGORY_DEPOSIT              LedgerCategory = 101
+    LEDGER_CATEGORY_WITHDRAWAL           LedgerCategory = 104
+    LEDGER_CATEGORY_CANCELED_WITHDRAWAL  LedgerCategory = 105
+    LEDGER_CATEGORY_TRADING_FEE          LedgerCategory = 201
+    LEDGER_CATEGORY_TRADING_REBATE       LedgerCategory = 202
+    LEDGER_CATEGORY_HIDDEN_ORDER_FEE     LedgerCategory = 204
+    LEDGER_CATEGORY_OTC_TRADE_FEE        LedgerCategory = 207
+    LEDGER_CATEGORY_SWAP_FEE             LedgerCategory = 222
+    LEDGER_CATEGORY_CLAIMING_FEE         LedgerCategory = 224
+    LEDGER_CATEGORY_MARGIN_FUNDING_FEE   LedgerCategory = 226
+    LEDGER_CATEGORY_AFFILIATE_REBATE     LedgerCategory = 228
+    LEDGER_CATEGORY_DEPOSIT_FEE          LedgerCategory = 241
+    LEDGER_CATEGORY_WITHDRAWAL_FEE       LedgerCategory = 243
+    LEDGER_CATEGORY_WITHDRAWAL_EXPRESS   LedgerCategory = 251
+    LEDGER_CATEGORY_MINER_FEE            LedgerCategory = 254
+    LEDGER_CATEGORY_STAKING_PAYMENT      LedgerCategory = 255
+    LEDGER_CATEGORY_ADJUSTMENT           LedgerCategory = 262
+    LEDGER_CATEGORY_CURRENCY_CONVERSION  LedgerCategory = 501
+)
+
+// LedgerEntry is a movement of the account ledger.
+type LedgerEntry struct {
+    ID       int64
+    Currency string
+    // Wallet is e.g. exchange, margin or funding
+    Wallet      string
+    Time        time.Time
+    Amount      float64
+    Balance     float64
+    Description string
+    // Category is the one the entries were filtered by, LEDGER_CATEGORY_ALL
+    // without a filter: Bitfinex doesn't send it with each entry.
+    Category LedgerCategory
+}
+
+// Ledger returns up to limit ledger entries of currency, of every currency
+// when empty, newest first. A category other than LEDGER_CATEGORY_ALL only
+// returns the entries of that category, e.g. LEDGER_CATEGORY_TRADING_FEE.
+// Zero since, until or limit use the defaults of Bitfinex. It is served by
+// the v2 API.
+func (s *HistoryService) Ledger(currency string, category LedgerCategory, since, until time.Time, limit int) ([]LedgerEntry, error) {
+    return s.LedgerContext(context.Background(), currency, category, since, until, limit)
+}
+
+// LedgerContext is like Ledger with a context for the request
+func (s *HistoryService) LedgerContext(ctx context.Context, currency string, category LedgerCategory, since, until time.Time, limit int) ([]LedgerEntry, error) {
+    payload := map[string]interface{}{}
+
+    if category != LEDGER_CATEGORY_ALL {
+        payload["category"] = int(category)
+    }
+    if !since.IsZero() {
+        payload["start"] = since.UnixNano() / int64(time.Millisecond)
+    }
+    if !until.IsZero() {
+        payload["end"] = until.UnixNano() / int64(time.Millisecond)
+    }
+    if limit != 0 {
+        payload["limit"] = limit
+    }
+
+    path := "auth/r/ledgers/hist"
+    if currency != "" {
+        path = "auth/r/ledgers/" + strings.ToUpper(currency) + "/hist"
+    }
+    req, err := s.client.newAuthenticatedV2Request(ctx, path, payload)
+
+    if err != nil {
+        return nil, err
+    }
+
+    var v [][]interface{}
+
+    _, err = s.client.do(req, &v)
+
+    if err != nil {
+        return nil, err
+    }
+
+    entries := make([]LedgerEntry, 0, len(v))
+    for _, row := range v {
+        if e, ok := ledgerEntry(row, category); ok {
+            entries = append(entries, e)
+        }
+    }
+    return entries, nil
+}
+
+// ledgerEntry decodes [ID, CURRENCY, WALLET, MTS, _, AMOUNT, BALANCE, _,
+// DESCRIPTION].
+func ledgerEntry(row []interface{}, category LedgerCategory) (LedgerEntry, bool) {
+    if len(row) < 9 {
+        return LedgerEntry{}, false
+    }
+    id, _ := row[0].(float64)
+    currency, _ := row[1].(string)
+    wallet, _ := row[2].(string)
+    mts, _ := row[3].(float64)
+    amount, _ := row[5].(float64)
+    balance, _ := row[6].(float64)
+    description, _ := row[8].(string)
+    return LedgerEntry{
+        ID:          int64(id),
+        Currency:    currency,
+        Wallet:      wallet,
+        Time:        time.Unix(0, int64(mts)*int64(time.Millisecond)),
+        Amount:      amount,
+        Balance:     balance,
+        Description: description,
+        Category:    category,
+    }, true
+}
@@ -2,8 +2,10 @@ package bitfinex
 
 import (
     "bytes"
+    "encoding/json"
     "io/ioutil"
     "net/http"
+    "reflect"
     "testing"
     "time"
 )
@@ -105,3 +107,100 @@ func TestHistoryTrades(t *testing.T) {
     }
 
 }
+
+func TestHistoryFundingTrades(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        if req.URL.Path != "/v1/mytrades_funding" {
+            t.Error("Expected", "/v1/mytrades_funding")
+            t.Error("Actual ", req.URL.Path)
+        }
+        msg := `[{
+            "rate":"0.01",
+            "period":30,
+            "amount":"0.01",
+            "timestamp":"1444141857.0",
+            "type":"Buy",
+            "tid":11970839,
+            "offer_id":446913929
+        }]`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    trades, err := NewClient().History.FundingTrades("USD", time.Unix(1444141000, 0), 10)
+
+    if err != nil {
+        t.Error(err)
+    }
+
+    if len(trades) != 1 {
+        t.Fatal("Expected 1 trade, got", len(trades))
+    }
+    if trades[0].Rate != "0.01" || trades[0].Period != 30 || trades[0].OfferId != 446913929 {
+        t.Error("Unexpected trade", trades[0])
+    }
+}
+
+func TestHistoryLedger(t *testing.T) {
+    var path, signature, expectedSignature string
+    var body map[string]interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        path = req.URL.Path
+        raw, _ := ioutil.ReadAll(req.Body)
+        json.Unmarshal(raw, &body)
+        signature = req.Header.Get("bfx-signature")
+        expectedSignature = NewClient().Auth("api-key", "api-secret").signPayload("/api" + path + req.Header.Get("bfx-nonce") + string(raw))
+        msg := `[
+            [2531822314,"USD","exchange",1574840151000,null,-0.29,873.5,null,"Trading fees for 0.1 BTC (BTCUSD) @ 7320.0 on BFX (0.2%) on wallet exchange"],
+            [2531822100,"USD"]
+        ]`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    c := NewClient().Auth("api-key", "api-secret")
+    since := time.Unix(1574800000, 0)
+    entries, err := c.History.Ledger("usd", LEDGER_CATEGORY_TRADING_FEE, since, time.Time{}, 25)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if path != "/v2/auth/r/ledgers/USD/hist" {
+        t.Error("Expected", "/v2/auth/r/ledgers/USD/hist")
+        t.Error("Actual ", path)
+    }
+    if body["category"] != 201.0 || body["start"] != 1574800000000.0 || body["limit"] != 25.0 || body["end"] != nil {
+        t.Error("Unexpected body", body)
+    }
+    if signature == "" || signature != expectedSignature {
+        t.Error("Expected", expectedSignature)
+        t.Error("Actual ", signature)
+    }
+
+    expected := LedgerEntry{
+        ID:          2531822314,
+        Currency:    "USD",
+        Wallet:      "exchange",
+        Time:        time.Unix(1574840151, 0),
+        Amount:      -0.29,
+        Balance:     873.5,
+        Description: "Trading fees for 0.1 BTC (BTCUSD) @ 7320.0 on BFX (0.2%) on wallet exchange",
+        Category:    LEDGER_CATEGORY_TRADING_FEE,
+    }
+    if len(entries) != 1 || !reflect.DeepEqual(entries[0], expected) {
+        t.Error("Expected", []LedgerEntry{expected})
+        t.Error("Actual ", entries)
+    }
+
+    body = nil
+    c.History.Ledger("", LEDGER_CATEGORY_ALL, time.Time{}, time.Time{}, 0)
+    if path != "/v2/auth/r/ledgers/hist" || len(body) != 0 {
+        t.Error("Expected every currency and category, got", path, body)
+    }
+}
@@ -1,6 +1,8 @@
 package bitfinex
 
 import (
+    "context"
+    "fmt"
     "net/url"
     "strconv"
     "strings"
@@ -35,6 +37,11 @@ type Lendbook struct {
 
 // GET /lendbook/:currency
 func (s *LendbookService) Get(currency string, limitBids, limitAsks int) (Lendbook, error) {
+    return s.GetContext(context.Background(), currency, limitBids, limitAsks)
+}
+
+// GetContext is like Get with a context for the request
+func (s *LendbookService) GetContext(ctx context.Context, currency string, limitBids, limitAsks int) (Lendbook, error) {
     currency = strings.ToUpper(currency)
 
     params := url.Values{}
@@ -45,7 +52,7 @@ func (s *LendbookService) Get(currency string, limitBids, limitAsks int) (Lendbo
         params.Add("limit_asks", strconv.Itoa(limitAsks))
     }
 
-    req, err := s.client.newRequest("GET", "lendbook/"+currency, params)
+    req, err := s.client.newRequest(ctx, "GET", "lendbook/"+currency, params)
     if err != nil {
         return Lendbook{}, err
     }
@@ -59,6 +66,33 @@ func (s *LendbookService) Get(currency string, limitBids, limitAsks int) (Lendbo
     return v, nil
 }
 
+// FRR returns the current flash return rate of currency, the daily rate
+// of the v2 funding ticker, e.g. 0.0002 for 0.02% a day.
+func (s *LendbookService) FRR(currency string) (float64, error) {
+    return s.FRRContext(context.Background(), currency)
+}
+
+// FRRContext is like FRR with a context for the request
+func (s *LendbookService) FRRContext(ctx context.Context, currency string) (float64, error) {
+    symbol := "f" + strings.ToUpper(currency)
+    req, err := s.client.newV2Request(ctx, "GET", "ticker/"+symbol, nil)
+    if err != nil {
+        return 0, err
+    }
+
+    // [FRR, BID, BID_PERIOD, BID_SIZE, ASK, ASK_PERIOD, ASK_SIZE, ...]
+    var v []float64
+    _, err = s.client.do(req, &v)
+    if err != nil {
+        return 0, err
+    }
+    if len(v) == 0 {
+        return 0, fmt.Errorf("no funding ticker for %s", symbol)
+    }
+
+    return v[0], nil
+}
+
 type Lends struct {
     Rate       string
     AmountLent string `json:"amount_lent"`
@@ -73,8 +107,13 @@ func (el *Lends) Time() *time.Time {
 
 // GET /lends/:currency
 func (s *LendbookService) Lends(currency string) ([]Lends, error) {
+    return s.LendsContext(context.Background(), currency)
+}
+
+// LendsContext is like Lends with a context for the request
+func (s *LendbookService) LendsContext(ctx context.Context, currency string) ([]Lends, error) {
     currency = strings.ToUpper(currency)
-    req, err := s.client.newRequest("GET", "lends/"+currency, nil)
+    req, err := s.client.newRequest(ctx, "GET", "lends/"+currency, nil)
     if err != nil {
         return nil, err
     }
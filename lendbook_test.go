@@ -77,3 +77,30 @@ func TestLendbookLends(t *testing.T) {
         t.Error("Actual ", len(lends))
     }
 }
+
+func TestLendbookFRR(t *testing.T) {
+    var requested string
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        requested = req.URL.String()
+        msg := `[0.00021,0.0002,30,1000,0.00019,2,500,0.00001,0.05,0.0002,1000000,0.0003,0.0001,null,null,250000]`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    frr, err := NewClient().Lendbook.FRR("usd")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if requested != DefaultBaseV2URL+"ticker/fUSD" {
+        t.Error("Expected", DefaultBaseV2URL+"ticker/fUSD")
+        t.Error("Actual ", requested)
+    }
+    if frr != 0.00021 {
+        t.Error("Expected", 0.00021)
+        t.Error("Actual ", frr)
+    }
+}
@@ -1,6 +1,9 @@
 package bitfinex
 
-import "strconv"
+import (
+    "context"
+    "strconv"
+)
 
 type MarginFundingService struct {
     client *Client
@@ -21,7 +24,7 @@ type MarginOffer struct {
     OfferId         int
 }
 
-func (s *MarginFundingService) new(currency, direction string, amount, rate float64, period int) (MarginOffer, error) {
+func (s *MarginFundingService) new(ctx context.Context, currency, direction string, amount, rate float64, period int) (MarginOffer, error) {
     payload := map[string]interface{}{
         "currency":  currency,
         "amount":    strconv.FormatFloat(amount, 'f', -1, 32),
@@ -30,7 +33,7 @@ func (s *MarginFundingService) new(currency, direction string, amount, rate floa
         "direction": direction,
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "offer/new", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "offer/new", payload)
 
     if err != nil {
         return MarginOffer{}, err
@@ -47,16 +50,32 @@ func (s *MarginFundingService) new(currency, direction string, amount, rate floa
 }
 
 func (s *MarginFundingService) NewLend(currency string, amount, rate float64, period int) (MarginOffer, error) {
-    return s.new(currency, "lend", amount, rate, period)
+    return s.NewLendContext(context.Background(), currency, amount, rate, period)
 }
+
+// NewLendContext is like NewLend with a context for the request
+func (s *MarginFundingService) NewLendContext(ctx context.Context, currency string, amount, rate float64, period int) (MarginOffer, error) {
+    return s.new(ctx, currency, "lend", amount, rate, period)
+}
+
 func (s *MarginFundingService) NewLoan(currency string, amount, rate float64, period int) (MarginOffer, error) {
-    return s.new(currency, "loan", amount, rate, period)
+    return s.NewLoanContext(context.Background(), currency, amount, rate, period)
+}
+
+// NewLoanContext is like NewLoan with a context for the request
+func (s *MarginFundingService) NewLoanContext(ctx context.Context, currency string, amount, rate float64, period int) (MarginOffer, error) {
+    return s.new(ctx, currency, "loan", amount, rate, period)
 }
 
 func (s *MarginFundingService) Cancel(offerId int64) (MarginOffer, error) {
+    return s.CancelContext(context.Background(), offerId)
+}
+
+// CancelContext is like Cancel with a context for the request
+func (s *MarginFundingService) CancelContext(ctx context.Context, offerId int64) (MarginOffer, error) {
     payload := map[string]interface{}{"offer_id": offerId}
 
-    req, err := s.client.newAuthenticatedRequest("POST", "offer/cancel", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "offer/cancel", payload)
 
     if err != nil {
         return MarginOffer{}, err
@@ -72,9 +91,14 @@ func (s *MarginFundingService) Cancel(offerId int64) (MarginOffer, error) {
 }
 
 func (s *MarginFundingService) Status(offerId int64) (MarginOffer, error) {
+    return s.StatusContext(context.Background(), offerId)
+}
+
+// StatusContext is like Status with a context for the request
+func (s *MarginFundingService) StatusContext(ctx context.Context, offerId int64) (MarginOffer, error) {
     payload := map[string]interface{}{"offer_id": offerId}
 
-    req, err := s.client.newAuthenticatedRequest("POST", "offer/status", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "offer/status", payload)
 
     if err != nil {
         return MarginOffer{}, err
@@ -104,8 +128,13 @@ type ActiveOffer struct {
 }
 
 func (s *MarginFundingService) Credits() ([]ActiveOffer, error) {
+    return s.CreditsContext(context.Background())
+}
 
-    req, err := s.client.newAuthenticatedRequest("POST", "credits", nil)
+// CreditsContext is like Credits with a context for the request
+func (s *MarginFundingService) CreditsContext(ctx context.Context) ([]ActiveOffer, error) {
+
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "credits", nil)
 
     if err != nil {
         return nil, err
@@ -121,8 +150,13 @@ func (s *MarginFundingService) Credits() ([]ActiveOffer, error) {
 }
 
 func (s *MarginFundingService) Offers() ([]ActiveOffer, error) {
+    return s.OffersContext(context.Background())
+}
+
+// OffersContext is like Offers with a context for the request
+func (s *MarginFundingService) OffersContext(ctx context.Context) ([]ActiveOffer, error) {
 
-    req, err := s.client.newAuthenticatedRequest("POST", "offers", nil)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "offers", nil)
 
     if err != nil {
         return nil, err
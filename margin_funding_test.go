@@ -2,6 +2,7 @@ package bitfinex
 
 import (
     "bytes"
+    "context"
     "io/ioutil"
     "net/http"
     "testing"
@@ -30,7 +31,7 @@ func TestMarginFundingNew(t *testing.T) {
         return &resp, nil
     }
 
-    offer, err := NewClient().MarginFunding.new("BTC", "loan", 10.0, 0.01, 10)
+    offer, err := NewClient().MarginFunding.new(context.Background(), "BTC", "loan", 10.0, 0.01, 10)
 
     if err != nil {
         t.Error(err)
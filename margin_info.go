@@ -1,5 +1,7 @@
 package bitfinex
 
+import "context"
+
 type MarginInfoService struct {
     client *Client
 }
@@ -26,7 +28,12 @@ type MarginLimit struct {
 
 // GET /margin_infos
 func (s *MarginInfoService) All() ([]MarginInfo, error) {
-    req, err := s.client.newAuthenticatedRequest("GET", "margin_infos", nil)
+    return s.AllContext(context.Background())
+}
+
+// AllContext is like All with a context for the request
+func (s *MarginInfoService) AllContext(ctx context.Context) ([]MarginInfo, error) {
+    req, err := s.client.newAuthenticatedRequest(ctx, "GET", "margin_infos", nil)
     if err != nil {
         return nil, err
     }
@@ -1,6 +1,11 @@
 package bitfinex
 
-import "strconv"
+import (
+    "context"
+    "encoding/json"
+    "strconv"
+    "time"
+)
 
 type OffersService struct {
     client *Client
@@ -14,30 +19,89 @@ const (
 type Offer struct {
     Id              int64
     Currency        string
-    Rate            string
+    Rate            float64 `json:",string"`
     Period          int64
     Direction       string
-    Timestamp       string
-    IsLive          bool   `json:"is_live"`
-    IsCancelled     bool   `json:"is_cancelled"`
-    OriginalAmount  string `json:"original_amount:string"`
-    RemainingAmount string `json:"remaining_amount:string"`
-    ExecutedAmount  string `json:"executed_amount:string"`
-    OfferId         int64  `json:"offer_id"`
+    Timestamp       float64 `json:",string"`
+    IsLive          bool    `json:"is_live"`
+    IsCancelled     bool    `json:"is_cancelled"`
+    OriginalAmount  float64 `json:"original_amount,string"`
+    RemainingAmount float64 `json:"remaining_amount,string"`
+    ExecutedAmount  float64 `json:"executed_amount,string"`
+    OfferId         int64   `json:"offer_id"`
+
+    // Decimals holds the exact rate, amounts and timestamp
+    Decimals OfferDecimals `json:"-"`
+}
+
+// OfferDecimals are the rate, amounts and timestamp of an Offer as sent by
+// Bitfinex
+type OfferDecimals struct {
+    Rate            Decimal `json:"rate"`
+    OriginalAmount  Decimal `json:"original_amount"`
+    RemainingAmount Decimal `json:"remaining_amount"`
+    ExecutedAmount  Decimal `json:"executed_amount"`
+    // Timestamp keeps the digits float64 rounds away
+    Timestamp Decimal `json:"timestamp"`
+}
+
+func (o *Offer) UnmarshalJSON(data []byte) error {
+    type offer Offer
+    if err := json.Unmarshal(data, (*offer)(o)); err != nil {
+        return err
+    }
+    return json.Unmarshal(data, &o.Decimals)
+}
+
+// Time returns the time the offer was placed
+func (o *Offer) Time() *time.Time {
+    ts := string(o.Decimals.Timestamp)
+    if ts == "" {
+        // built rather than decoded
+        ts = strconv.FormatFloat(o.Timestamp, 'f', -1, 64)
+    }
+    t, _ := parseTimestamp(ts)
+    return &t
+}
+
+// Returns an array of active offers
+func (s *OffersService) All() ([]Offer, error) {
+    return s.AllContext(context.Background())
+}
+
+// AllContext is like All with a context for the request
+func (s *OffersService) AllContext(ctx context.Context) ([]Offer, error) {
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "offers", nil)
+    if err != nil {
+        return nil, err
+    }
+
+    offers := make([]Offer, 0)
+    _, err = s.client.do(req, &offers)
+    if err != nil {
+        return nil, err
+    }
+
+    return offers, nil
 }
 
 // Create new offer for LEND or LOAN a currency, use LEND or LOAN constants as direction
 func (s *OffersService) New(currency string, amount, rate float64, period int64, direction string) (Offer, error) {
+    return s.NewContext(context.Background(), currency, amount, rate, period, direction)
+}
+
+// NewContext is like New with a context for the request
+func (s *OffersService) NewContext(ctx context.Context, currency string, amount, rate float64, period int64, direction string) (Offer, error) {
 
     payload := map[string]interface{}{
         "currency":  currency,
-        "amount":    strconv.FormatFloat(amount, 'f', -1, 32),
-        "rate":      strconv.FormatFloat(rate, 'f', -1, 32),
+        "amount":    strconv.FormatFloat(amount, 'f', -1, 64),
+        "rate":      strconv.FormatFloat(rate, 'f', -1, 64),
         "period":    strconv.FormatInt(period, 10),
         "direction": direction,
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "offers/new", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "offer/new", payload)
 
     if err != nil {
         return Offer{}, err
@@ -54,13 +118,19 @@ func (s *OffersService) New(currency string, amount, rate float64, period int64,
 
 }
 
+// Cancel the offer with id `offerId`
 func (s *OffersService) Cancel(offerId int64) (Offer, error) {
+    return s.CancelContext(context.Background(), offerId)
+}
+
+// CancelContext is like Cancel with a context for the request
+func (s *OffersService) CancelContext(ctx context.Context, offerId int64) (Offer, error) {
 
     payload := map[string]interface{}{
         "offer_id": strconv.FormatInt(offerId, 10),
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "offers/cancel", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "offer/cancel", payload)
 
     if err != nil {
         return Offer{}, err
@@ -77,13 +147,19 @@ func (s *OffersService) Cancel(offerId int64) (Offer, error) {
     return *offer, nil
 }
 
+// Retrieve the status of an offer
 func (s *OffersService) Status(offerId int64) (Offer, error) {
+    return s.StatusContext(context.Background(), offerId)
+}
+
+// StatusContext is like Status with a context for the request
+func (s *OffersService) StatusContext(ctx context.Context, offerId int64) (Offer, error) {
 
     payload := map[string]interface{}{
         "offer_id": strconv.FormatInt(offerId, 10),
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "offers/status", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "offer/status", payload)
 
     if err != nil {
         return Offer{}, err
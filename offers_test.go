@@ -2,13 +2,17 @@ package bitfinex
 
 import (
     "bytes"
+    "encoding/base64"
+    "encoding/json"
     "io/ioutil"
     "net/http"
     "testing"
 )
 
 func TestOfferNew(t *testing.T) {
+    var paths []string
     httpDo = func(req *http.Request) (*http.Response, error) {
+        paths = append(paths, req.URL.Path)
         msg := `{
           "id":13800585,
           "currency":"USD",
@@ -56,6 +60,15 @@ func TestOfferNew(t *testing.T) {
         t.Error("Actual ", offer.IsLive)
     }
 
+    if offer.Rate != 20 || offer.OriginalAmount != 50 || offer.RemainingAmount != 50 {
+        t.Error("Unexpected rate or amounts", offer)
+    }
+
+    if tm := offer.Time(); tm.Unix() != 1444279698 || tm.Nanosecond() != 211759710 {
+        t.Error("Expected", "1444279698.21175971")
+        t.Error("Actual ", tm.Unix(), tm.Nanosecond())
+    }
+
     newOffer, err := NewClient().Offers.Cancel(offer.Id)
 
     if err != nil {
@@ -72,4 +85,65 @@ func TestOfferNew(t *testing.T) {
         t.Error("Actual ", newOffer.IsCancelled)
     }
 
+    if len(paths) != 2 || paths[0] != "/v1/offer/new" || paths[1] != "/v1/offer/cancel" {
+        t.Error("Expected", []string{"/v1/offer/new", "/v1/offer/cancel"})
+        t.Error("Actual ", paths)
+    }
+
+}
+
+func TestOfferNewPayload(t *testing.T) {
+    var payload map[string]interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id":1}`)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    if _, err := NewClient().Offers.New("USD", 1234.5678, 0.0123456789, 2, LEND); err != nil {
+        t.Fatal(err)
+    }
+
+    // formatted with 64 bits, 32 would round both
+    if payload["amount"] != "1234.5678" || payload["rate"] != "0.0123456789" {
+        t.Error("Expected", "1234.5678", "0.0123456789")
+        t.Error("Actual ", payload["amount"], payload["rate"])
+    }
+}
+
+func TestOffersAll(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        msg := `[{
+          "id":13800719,
+          "currency":"USD",
+          "rate":"31.39",
+          "period":2,
+          "direction":"lend",
+          "timestamp":"1444280237.0",
+          "is_live":true,
+          "is_cancelled":false,
+          "original_amount":"50.0",
+          "remaining_amount":"50.0",
+          "executed_amount":"0.0"
+        }]`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    offers, err := NewClient().Offers.All()
+
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if len(offers) != 1 || offers[0].Id != 13800719 || offers[0].Rate != 31.39 {
+        t.Error("Unexpected offers", offers)
+    }
 }
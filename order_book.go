@@ -1,9 +1,12 @@
 package bitfinex
 
 import (
+    "context"
+    "errors"
+    "fmt"
+    "math"
     "net/url"
     "strconv"
-    "strings"
     "time"
 )
 
@@ -18,11 +21,89 @@ type OrderBookEntry struct {
     Period    int
     Timestamp string
     Frr       string
+
+    // Side is BUY for bids and SELL for asks. Amount is always positive
+    Side Side `json:"-"`
 }
 
+// OrderBook levels are sorted best price first on both sides
 type OrderBook struct {
     Bids []OrderBookEntry
     Asks []OrderBookEntry
+
+    // ServerTime is the time of the last update on websocket books, set with
+    // CONF_TIMESTAMP
+    ServerTime time.Time `json:"-"`
+
+    // Reset is set on the empty book a websocket book subscription delivers
+    // after reconnecting: the previous book is stale and must be discarded
+    Reset bool `json:"-"`
+
+    // IsSnapshot is set on the websocket book built from a snapshot, the
+    // first complete book after subscribing or reconnecting, as opposed to
+    // one changed by an update. Books are only delivered once the whole
+    // snapshot was applied, never half-built
+    IsSnapshot bool `json:"-"`
+}
+
+// MidPrice returns the price halfway between the best bid and best ask,
+// or 0 when either side is empty
+func (b *OrderBook) MidPrice() float64 {
+    if len(b.Bids) == 0 || len(b.Asks) == 0 {
+        return 0
+    }
+    bid, _ := strconv.ParseFloat(b.Bids[0].Price, 64)
+    ask, _ := strconv.ParseFloat(b.Asks[0].Price, 64)
+    return (bid + ask) / 2
+}
+
+// Spread returns the difference between the best ask and best bid,
+// or 0 when either side is empty
+func (b *OrderBook) Spread() float64 {
+    if len(b.Bids) == 0 || len(b.Asks) == 0 {
+        return 0
+    }
+    bid, _ := strconv.ParseFloat(b.Bids[0].Price, 64)
+    ask, _ := strconv.ParseFloat(b.Asks[0].Price, 64)
+    return ask - bid
+}
+
+// VWAP returns the volume weighted average price of filling amount on side:
+// a BUY walks the asks and a SELL walks the bids
+func (b *OrderBook) VWAP(side Side, amount float64) (float64, error) {
+    if amount <= 0 {
+        return 0, errors.New("VWAP amount must be positive")
+    }
+
+    var levels []OrderBookEntry
+    switch side {
+    case BUY:
+        levels = b.Asks
+    case SELL:
+        levels = b.Bids
+    default:
+        return 0, fmt.Errorf("VWAP side must be %s or %s, got %q", BUY, SELL, side)
+    }
+
+    var filled, cost float64
+    for _, level := range levels {
+        price, err := strconv.ParseFloat(level.Price, 64)
+        if err != nil {
+            return 0, err
+        }
+        size, err := strconv.ParseFloat(level.Amount, 64)
+        if err != nil {
+            return 0, err
+        }
+        size = math.Min(math.Abs(size), amount-filled)
+        filled += size
+        cost += size * price
+        if filled >= amount {
+            return cost / filled, nil
+        }
+    }
+
+    return 0, errors.New("order book is too thin to fill the amount")
 }
 
 func (el *OrderBookEntry) ParseTime() (*time.Time, error) {
@@ -36,7 +117,12 @@ func (el *OrderBookEntry) ParseTime() (*time.Time, error) {
 
 // GET /book
 func (s *OrderBookService) Get(pair string, limitBids, limitAsks int, noGroup bool) (OrderBook, error) {
-    pair = strings.ToUpper(pair)
+    return s.GetContext(context.Background(), pair, limitBids, limitAsks, noGroup)
+}
+
+// GetContext is like Get with a context for the request
+func (s *OrderBookService) GetContext(ctx context.Context, pair string, limitBids, limitAsks int, noGroup bool) (OrderBook, error) {
+    pair = NormalizeSymbol(pair, SYMBOL_REST)
 
     params := url.Values{}
     if limitBids != 0 {
@@ -49,7 +135,7 @@ func (s *OrderBookService) Get(pair string, limitBids, limitAsks int, noGroup bo
         params.Add("group", "0")
     }
 
-    req, err := s.client.newRequest("GET", "book/"+pair, params)
+    req, err := s.client.newRequest(ctx, "GET", "book/"+pair, params)
 
     if err != nil {
         return OrderBook{}, err
@@ -62,5 +148,12 @@ func (s *OrderBookService) Get(pair string, limitBids, limitAsks int, noGroup bo
         return OrderBook{}, err
     }
 
+    for i := range v.Bids {
+        v.Bids[i].Side = BUY
+    }
+    for i := range v.Asks {
+        v.Asks[i].Side = SELL
+    }
+
     return v, nil
 }
@@ -45,3 +45,47 @@ func TestOrderBookGet(t *testing.T) {
     }
 
 }
+
+func TestOrderBookPrices(t *testing.T) {
+    book := OrderBook{
+        Bids: []OrderBookEntry{{Price: "449", Amount: "1"}, {Price: "448", Amount: "2"}},
+        Asks: []OrderBookEntry{{Price: "451", Amount: "1"}, {Price: "452", Amount: "3"}},
+    }
+
+    if book.MidPrice() != 450 {
+        t.Error("Expected", 450)
+        t.Error("Actual ", book.MidPrice())
+    }
+    if book.Spread() != 2 {
+        t.Error("Expected", 2)
+        t.Error("Actual ", book.Spread())
+    }
+
+    vwap, err := book.VWAP(BUY, 2)
+    if err != nil {
+        t.Error(err)
+    }
+    if vwap != 451.5 {
+        t.Error("Expected", 451.5)
+        t.Error("Actual ", vwap)
+    }
+
+    vwap, err = book.VWAP(SELL, 3)
+    if err != nil {
+        t.Error(err)
+    }
+    if expected := (449.0 + 2*448.0) / 3; vwap != expected {
+        t.Error("Expected", expected)
+        t.Error("Actual ", vwap)
+    }
+
+    if _, err = book.VWAP(BUY, 5); err == nil {
+        t.Error("Expected error for thin book")
+    }
+    if _, err = book.VWAP("", 1); err == nil {
+        t.Error("Expected error for an invalid side")
+    }
+    if (&OrderBook{}).MidPrice() != 0 {
+        t.Error("Expected 0 mid price for empty book")
+    }
+}
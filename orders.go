@@ -1,49 +1,185 @@
 package bitfinex
 
 import (
+    "context"
+    "encoding/json"
+    "errors"
     "fmt"
     "math"
+    "sort"
     "strconv"
+    "strings"
+    "sync"
+    "time"
 )
 
+// OrderType is the order type string Bitfinex expects
+type OrderType string
+
 const (
-    ORDER_TYPE_MARKET                 = "market"
-    ORDER_TYPE_LIMIT                  = "limit"
-    ORDER_TYPE_STOP                   = "stop"
-    ORDER_TYPE_TRAILING_STOP          = "trailing-stop"
-    ORDER_TYPE_FILL_OR_KILL           = "fill-or-kill"
-    ORDER_TYPE_EXCHANGE_MARKET        = "exchange market"
-    ORDER_TYPE_EXCHANGE_LIMIT         = "exchange limit"
-    ORDER_TYPE_EXCHANGE_STOP          = "exchange stop"
-    ORDER_TYPE_EXCHANGE_TRAILING_STOP = "exchange trailing-stop"
-    ORDER_TYPE_EXCHANGE_FILL_OR_KILL  = "exchange fill-or-kill"
+    ORDER_TYPE_MARKET                 OrderType = "market"
+    ORDER_TYPE_LIMIT                  OrderType = "limit"
+    ORDER_TYPE_STOP                   OrderType = "stop"
+    ORDER_TYPE_TRAILING_STOP          OrderType = "trailing-stop"
+    ORDER_TYPE_FILL_OR_KILL           OrderType = "fill-or-kill"
+    ORDER_TYPE_EXCHANGE_MARKET        OrderType = "exchange market"
+    ORDER_TYPE_EXCHANGE_LIMIT         OrderType = "exchange limit"
+    ORDER_TYPE_EXCHANGE_STOP          OrderType = "exchange stop"
+    ORDER_TYPE_EXCHANGE_TRAILING_STOP OrderType = "exchange trailing-stop"
+    ORDER_TYPE_EXCHANGE_FILL_OR_KILL  OrderType = "exchange fill-or-kill"
 )
 
+// validate checks the fields the order type requires. Stop orders use
+// Price as the stop price and trailing stops as the trailing distance.
+func (t OrderType) validate(price float64) error {
+    switch t {
+    case ORDER_TYPE_MARKET, ORDER_TYPE_EXCHANGE_MARKET:
+        return nil
+    case ORDER_TYPE_LIMIT, ORDER_TYPE_EXCHANGE_LIMIT,
+        ORDER_TYPE_FILL_OR_KILL, ORDER_TYPE_EXCHANGE_FILL_OR_KILL:
+        if price <= 0 {
+            return fmt.Errorf("%q orders require a price", t)
+        }
+    case ORDER_TYPE_STOP, ORDER_TYPE_EXCHANGE_STOP:
+        if price <= 0 {
+            return fmt.Errorf("%q orders require a stop price", t)
+        }
+    case ORDER_TYPE_TRAILING_STOP, ORDER_TYPE_EXCHANGE_TRAILING_STOP:
+        if price <= 0 {
+            return fmt.Errorf("%q orders require a trailing distance", t)
+        }
+    default:
+        return fmt.Errorf("unknown order type %q", t)
+    }
+    return nil
+}
+
+const exchangePrefix = "exchange "
+
+// margin returns t without the exchange prefix, the margin order type
+func (t OrderType) margin() OrderType {
+    return OrderType(strings.TrimPrefix(string(t), exchangePrefix))
+}
+
+// Side of an order, a trade or a book level. Bitfinex encodes it in the
+// sign of amounts: positive amounts buy (bids), negative amounts sell (asks)
+type Side string
+
+const (
+    BUY  Side = "buy"
+    SELL Side = "sell"
+)
+
+// SideOf returns the side of a signed Bitfinex amount
+func SideOf(amount float64) Side {
+    if amount < 0 {
+        return SELL
+    }
+    return BUY
+}
+
+// Signed returns amount with the sign Bitfinex uses for the side
+func (s Side) Signed(amount float64) float64 {
+    if s == SELL {
+        return -math.Abs(amount)
+    }
+    return math.Abs(amount)
+}
+
 type OrderService struct {
     client *Client
+
+    // pairs that can be traded on margin, loaded with the first margin order
+    marginMu    sync.Mutex
+    marginPairs map[string]bool
 }
 
+// Order as returned by order/new, order/status and orders
 type Order struct {
     Id                int
+    OrderId           int `json:"order_id"`
     Symbol            string
     Exchange          string
-    Price             string
-    AvgExecutionPrice string `json:"avg_execution_price"`
-    Side              string
+    Price             float64 `json:",string"`
+    AvgExecutionPrice float64 `json:"avg_execution_price,string"`
+    Side              Side
     Type              string
-    Timestamp         string
-    IsLive            bool   `json:"is_live"`
-    IsCanceled        bool   `json:"is_cancelled"`
-    IsHidden          bool   `json:"is_hidden"`
-    WasForced         bool   `json:"was_forced"`
-    OriginalAmount    string `json:"original_amount"`
-    RemainingAmount   string `json:"remaining_amount"`
-    ExecutedAmount    string `json:"executed_amount"`
+    Timestamp         float64 `json:",string"`
+    IsLive            bool    `json:"is_live"`
+    IsCanceled        bool    `json:"is_cancelled"`
+    IsHidden          bool    `json:"is_hidden"`
+    WasForced         bool    `json:"was_forced"`
+    OriginalAmount    float64 `json:"original_amount,string"`
+    RemainingAmount   float64 `json:"remaining_amount,string"`
+    ExecutedAmount    float64 `json:"executed_amount,string"`
+
+    // Decimals holds the exact prices, amounts and timestamp
+    Decimals OrderDecimals `json:"-"`
+}
+
+// OrderDecimals are the prices, amounts and timestamp of an Order as sent
+// by Bitfinex
+type OrderDecimals struct {
+    Price             Decimal `json:"price"`
+    AvgExecutionPrice Decimal `json:"avg_execution_price"`
+    OriginalAmount    Decimal `json:"original_amount"`
+    RemainingAmount   Decimal `json:"remaining_amount"`
+    ExecutedAmount    Decimal `json:"executed_amount"`
+    // Timestamp keeps the digits float64 rounds away
+    Timestamp Decimal `json:"timestamp"`
+}
+
+func (o *Order) UnmarshalJSON(data []byte) error {
+    type order Order
+    if err := json.Unmarshal(data, (*order)(o)); err != nil {
+        return err
+    }
+    return json.Unmarshal(data, &o.Decimals)
+}
+
+// Time - return Timestamp in time.Time format
+func (o *Order) Time() *time.Time {
+    ts := string(o.Decimals.Timestamp)
+    if ts == "" {
+        // built rather than decoded
+        ts = strconv.FormatFloat(o.Timestamp, 'f', -1, 64)
+    }
+    t, _ := parseTimestamp(ts)
+    return &t
+}
+
+// parseTimestamp parses a Bitfinex timestamp, seconds since the epoch with
+// a fraction, e.g. 1444274013.621701916
+func parseTimestamp(ts string) (time.Time, error) {
+    sec, frac := ts, ""
+    if i := strings.IndexByte(ts, '.'); i >= 0 {
+        sec, frac = ts[:i], ts[i+1:]
+    }
+    s, err := strconv.ParseInt(sec, 10, 64)
+    if err != nil {
+        return time.Time{}, err
+    }
+    var ns int64
+    if frac != "" {
+        if len(frac) > 9 {
+            frac = frac[:9]
+        }
+        ns, err = strconv.ParseInt(frac+strings.Repeat("0", 9-len(frac)), 10, 64)
+        if err != nil || ns < 0 {
+            return time.Time{}, fmt.Errorf("invalid timestamp %q", ts)
+        }
+    }
+    return time.Unix(s, ns), nil
 }
 
 // get all active orders
 func (s *OrderService) All() ([]Order, error) {
-    req, err := s.client.newAuthenticatedRequest("GET", "orders", nil)
+    return s.AllContext(context.Background())
+}
+
+// AllContext is like All with a context for the request
+func (s *OrderService) AllContext(ctx context.Context) ([]Order, error) {
+    req, err := s.client.newAuthenticatedRequest(ctx, "GET", "orders", nil)
     if err != nil {
         return nil, err
     }
@@ -59,7 +195,12 @@ func (s *OrderService) All() ([]Order, error) {
 
 // Cancel all active orders
 func (s *OrderService) CancelAll() error {
-    req, err := s.client.newAuthenticatedRequest("POST", "order/cancel/all", nil)
+    return s.CancelAllContext(context.Background())
+}
+
+// CancelAllContext is like CancelAll with a context for the request
+func (s *OrderService) CancelAllContext(ctx context.Context) error {
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "order/cancel/all", nil)
     if err != nil {
         return err
     }
@@ -73,113 +214,428 @@ func (s *OrderService) CancelAll() error {
 }
 
 // Create a new order
-func (s *OrderService) Create(symbol string, amount float64, price float64, orderType string) (*Order, error) {
-    var side string
-    if amount < 0 {
-        amount = math.Abs(amount)
-        side = "sell"
-    } else {
-        side = "buy"
+func (s *OrderService) Create(symbol string, amount float64, price float64, orderType OrderType) (*Order, error) {
+    return s.CreateContext(context.Background(), symbol, amount, price, orderType)
+}
+
+// CreateContext is like Create with a context for the request
+func (s *OrderService) CreateContext(ctx context.Context, symbol string, amount float64, price float64, orderType OrderType) (*Order, error) {
+    return s.SubmitContext(ctx, SubmitOrder{
+        Symbol: symbol,
+        Amount: amount,
+        Price:  price,
+        Type:   orderType,
+        // the type given is sent as is
+        Margin: !strings.HasPrefix(string(orderType), exchangePrefix),
+    })
+}
+
+// Submit a new order, including any order flags set on it
+func (s *OrderService) Submit(order SubmitOrder) (*Order, error) {
+    return s.SubmitContext(context.Background(), order)
+}
+
+// SubmitContext is like Submit with a context for the request
+func (s *OrderService) SubmitContext(ctx context.Context, order SubmitOrder) (*Order, error) {
+    payload, err := order.payload()
+    if err != nil {
+        return nil, err
+    }
+    if err := s.checkMargin(ctx, order); err != nil {
+        return nil, err
+    }
+
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "order/new", payload)
+    if err != nil {
+        return nil, err
+    }
+
+    o := new(Order)
+    _, err = s.client.do(req, o)
+    if err != nil {
+        return nil, err
     }
 
+    return o, nil
+}
+
+// Cancel the order with id `orderId`. The order is returned as of its
+// cancellation, with the amount executed until then
+func (s *OrderService) Cancel(orderId int) (*Order, error) {
+    return s.CancelContext(context.Background(), orderId)
+}
+
+// CancelContext is like Cancel with a context for the request
+func (s *OrderService) CancelContext(ctx context.Context, orderId int) (*Order, error) {
     payload := map[string]interface{}{
-        "symbol":   symbol,
-        "amount":   fmt.Sprintf("%f", amount),
-        "price":    fmt.Sprintf("%f", price),
-        "side":     side,
-        "type":     orderType,
-        "exchange": "bitfinex",
+        "order_id": orderId,
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "order/new", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "order/cancel", payload)
     if err != nil {
         return nil, err
     }
 
-    order := new(Order)
-    _, err = s.client.do(req, order)
+    o := new(Order)
+    _, err = s.client.do(req, o)
     if err != nil {
         return nil, err
     }
 
-    return order, nil
+    return o, nil
+}
+
+// CancelByCID cancels the order with the client order id `cid` set on
+// SubmitOrder. cidDate is the UTC day the order was created, see CIDDate
+func (s *OrderService) CancelByCID(cid int64, cidDate string) error {
+    return s.CancelByCIDContext(context.Background(), cid, cidDate)
 }
 
-// Cancel the order with id `orderId`
-func (s *OrderService) Cancel(orderId int) error {
+// CancelByCIDContext is like CancelByCID with a context for the request
+func (s *OrderService) CancelByCIDContext(ctx context.Context, cid int64, cidDate string) error {
     payload := map[string]interface{}{
-        "order_id": orderId,
+        "cid":      cid,
+        "cid_date": cidDate,
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "order/cancel", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "order/cancel", payload)
     if err != nil {
         return err
     }
 
     _, err = s.client.do(req, nil)
+    return err
+}
+
+// orderTrade is a fill as returned by order/trades
+type orderTrade struct {
+    TID         int64
+    OrderId     int64   `json:"order_id"`
+    Symbol      string
+    Price       float64 `json:",string"`
+    Amount      float64 `json:",string"`
+    Timestamp   float64 `json:",string"`
+    Type        string
+    FeeCurrency string  `json:"fee_currency"`
+    FeeAmount   float64 `json:"fee_amount,string"`
+}
+
+func (t orderTrade) fill() Fill {
+    amount := t.Amount
+    if strings.EqualFold(t.Type, string(SELL)) {
+        amount = -math.Abs(amount)
+    }
+    return Fill{
+        TradeId:     t.TID,
+        Pair:        NormalizeSymbol(t.Symbol, SYMBOL_WEBSOCKET),
+        Timestamp:   int64(t.Timestamp),
+        OrderId:     t.OrderId,
+        Amount:      amount,
+        Price:       t.Price,
+        Fee:         t.FeeAmount,
+        FeeCurrency: t.FeeCurrency,
+    }
+}
+
+// Trades returns the fills of the order with id `orderId`, the same
+// fills the private websocket feed delivers as they happen
+func (s *OrderService) Trades(orderId int64) ([]Fill, error) {
+    return s.TradesContext(context.Background(), orderId)
+}
+
+// TradesContext is like Trades with a context for the request
+func (s *OrderService) TradesContext(ctx context.Context, orderId int64) ([]Fill, error) {
+    payload := map[string]interface{}{
+        "order_id": orderId,
+    }
+
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "order/trades", payload)
     if err != nil {
-        return err
+        return nil, err
     }
 
-    return nil
+    var v []orderTrade
+    _, err = s.client.do(req, &v)
+    if err != nil {
+        return nil, err
+    }
+
+    fills := make([]Fill, 0, len(v))
+    for _, t := range v {
+        fills = append(fills, t.fill())
+    }
+    return fills, nil
+}
+
+// CIDDate formats the day of t as expected for cid_date, e.g. 2016-01-02
+func CIDDate(t time.Time) string {
+    return t.UTC().Format("2006-01-02")
 }
 
 type SubmitOrder struct {
     Symbol string
+    // Positive amount to buy, negative to sell
     Amount float64
-    Price  float64
-    Type   string
+    // Side, when set, gives the direction instead of the sign of Amount
+    Side Side
+    // Limit price, stop price for stop orders, trailing distance for
+    // trailing stops. Ignored for market orders.
+    Price float64
+    Type  OrderType
+
+    // Hidden orders are not shown in the public order book
+    Hidden bool
+    // PostOnly orders are cancelled instead of taking liquidity
+    PostOnly bool
+    // OCO attaches a linked stop order, one cancels the other. The stop price
+    // is BuyPriceOCO for buy orders and SellPriceOCO for sell orders.
+    OCO          bool
+    BuyPriceOCO  float64
+    SellPriceOCO float64
+
+    // CID is an optional client order id. Unique per day, it lets the
+    // order be cancelled with CancelByCID without knowing its order id
+    CID int64
+
+    // Margin places a margin order: Type must not be an exchange type and
+    // the symbol must support margin trading. Otherwise the order is an
+    // exchange order: Type is sent with the "exchange " prefix, so
+    // ORDER_TYPE_LIMIT and ORDER_TYPE_EXCHANGE_LIMIT both place an exchange
+    // limit order.
+    Margin bool
 }
 
-type MultipleOrderResponse struct {
-    Orders []Order `json:"order_ids"`
+// orderType returns the type sent for the order, prefixed unless Margin
+func (o SubmitOrder) orderType() (OrderType, error) {
+    if !o.Margin {
+        return exchangePrefix + o.Type.margin(), nil
+    }
+    if o.Type != o.Type.margin() {
+        return "", fmt.Errorf("%q is not a margin order type", o.Type)
+    }
+    return o.Type, nil
+}
+
+// checkMargin verifies that the symbols of margin orders can be traded on
+// margin, as listed by symbols_details
+func (s *OrderService) checkMargin(ctx context.Context, orders ...SubmitOrder) error {
+    for _, o := range orders {
+        if !o.Margin {
+            continue
+        }
+        pairs, err := s.loadMarginPairs(ctx)
+        if err != nil {
+            return err
+        }
+        if symbol := NormalizeSymbol(o.Symbol, SYMBOL_REST); !pairs[symbol] {
+            return fmt.Errorf("%s does not support margin trading", symbol)
+        }
+    }
+    return nil
+}
+
+func (s *OrderService) loadMarginPairs(ctx context.Context) (map[string]bool, error) {
+    s.marginMu.Lock()
+    defer s.marginMu.Unlock()
+    if s.marginPairs != nil {
+        return s.marginPairs, nil
+    }
+
+    details, err := s.client.Pairs.AllDetailedContext(ctx)
+    if err != nil {
+        return nil, err
+    }
+    pairs := make(map[string]bool, len(details))
+    for _, p := range details {
+        pairs[NormalizeSymbol(p.Pair, SYMBOL_REST)] = p.Margin
+    }
+    s.marginPairs = pairs
+    return pairs, nil
+}
+
+// supportsOCO reports whether an OCO stop can be attached to orderType
+func supportsOCO(orderType OrderType) bool {
+    return orderType == ORDER_TYPE_LIMIT || orderType == ORDER_TYPE_EXCHANGE_LIMIT
+}
+
+// payload converts the order into the request fields Bitfinex expects
+func (o SubmitOrder) payload() (map[string]interface{}, error) {
+    if o.Amount == 0 {
+        return nil, errors.New("order amount must not be zero")
+    }
+    orderType, err := o.orderType()
+    if err != nil {
+        return nil, err
+    }
+    if err := orderType.validate(o.Price); err != nil {
+        return nil, err
+    }
+    if (o.OCO || o.BuyPriceOCO != 0 || o.SellPriceOCO != 0) && !supportsOCO(orderType) {
+        return nil, fmt.Errorf("OCO is not supported for %q orders", orderType)
+    }
+
+    price := o.Price
+    if orderType == ORDER_TYPE_MARKET || orderType == ORDER_TYPE_EXCHANGE_MARKET {
+        // the price is ignored, but Bitfinex requires a positive one
+        price = 1
+    }
+
+    amount := o.Amount
+    if o.Side != "" {
+        if o.Side != BUY && o.Side != SELL {
+            return nil, fmt.Errorf("unknown order side %q", o.Side)
+        }
+        amount = o.Side.Signed(amount)
+    }
+    side := SideOf(amount)
+    amount = math.Abs(amount)
+
+    payload := map[string]interface{}{
+        "symbol":   NormalizeSymbol(o.Symbol, SYMBOL_REST),
+        "amount":   strconv.FormatFloat(amount, 'f', -1, 64),
+        "price":    strconv.FormatFloat(price, 'f', -1, 64),
+        "exchange": "bitfinex",
+        "side":     side,
+        "type":     orderType,
+    }
+
+    if o.CID != 0 {
+        payload["cid"] = o.CID
+    }
+
+    if o.Hidden {
+        payload["is_hidden"] = true
+    }
+    if o.PostOnly {
+        payload["is_postonly"] = true
+    }
+    if o.OCO {
+        if side == BUY && o.BuyPriceOCO == 0 {
+            return nil, errors.New("OCO buy order requires BuyPriceOCO")
+        }
+        if side == SELL && o.SellPriceOCO == 0 {
+            return nil, errors.New("OCO sell order requires SellPriceOCO")
+        }
+        payload["ocoorder"] = true
+        payload["buy_price_oco"] = strconv.FormatFloat(o.BuyPriceOCO, 'f', -1, 64)
+        payload["sell_price_oco"] = strconv.FormatFloat(o.SellPriceOCO, 'f', -1, 64)
+    }
+
+    return payload, nil
+}
+
+// multipleOrderResponse is the response of order/new/multi, an entry per
+// order sent, in order. Rejected orders have a message instead of an id.
+type multipleOrderResponse struct {
+    Orders []json.RawMessage `json:"order_ids"`
     Status string
 }
 
-// Create Multiple Orders
-func (s *OrderService) CreateMulti(orders []SubmitOrder) (MultipleOrderResponse, error) {
+// MultiOrderError reports, by index, the orders of a batch that are invalid
+// or were rejected
+type MultiOrderError map[int]error
+
+func (e MultiOrderError) Error() string {
+    indexes := make([]int, 0, len(e))
+    for i := range e {
+        indexes = append(indexes, i)
+    }
+    sort.Ints(indexes)
+
+    msgs := make([]string, len(indexes))
+    for n, i := range indexes {
+        msgs[n] = fmt.Sprintf("order %d: %v", i, e[i])
+    }
+    return strings.Join(msgs, "; ")
+}
+
+// Create Multiple Orders. Every order is validated before the batch is
+// sent; invalid orders are reported together as a MultiOrderError. The
+// created orders are returned in the order they were given, along with a
+// MultiOrderError for the ones Bitfinex rejected.
+func (s *OrderService) CreateMulti(orders []SubmitOrder) ([]Order, error) {
+    return s.CreateMultiContext(context.Background(), orders)
+}
+
+// CreateMultiContext is like CreateMulti with a context for the request
+func (s *OrderService) CreateMultiContext(ctx context.Context, orders []SubmitOrder) ([]Order, error) {
 
     ordersMap := make([]interface{}, 0)
-    for _, order := range orders {
-        var side string
-        if order.Amount < 0 {
-            order.Amount = math.Abs(order.Amount)
-            side = "sell"
-        } else {
-            side = "buy"
-        }
-        ordersMap = append(ordersMap, map[string]interface{}{
-            "symbol":   order.Symbol,
-            "amount":   strconv.FormatFloat(order.Amount, 'f', -1, 32),
-            "price":    strconv.FormatFloat(order.Price, 'f', -1, 32),
-            "exchange": "bitfinex",
-            "side":     side,
-            "type":     order.Type,
-        })
+    invalid := MultiOrderError{}
+    for i, order := range orders {
+        o, err := order.payload()
+        if err != nil {
+            invalid[i] = err
+            continue
+        }
+        ordersMap = append(ordersMap, o)
+    }
+    if len(invalid) > 0 {
+        return nil, invalid
+    }
+    if err := s.checkMargin(ctx, orders...); err != nil {
+        return nil, err
     }
     payload := map[string]interface{}{
         "orders": ordersMap,
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "order/new/multi", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "order/new/multi", payload)
     if err != nil {
-        return MultipleOrderResponse{}, err
+        return nil, err
     }
 
-    response := new(MultipleOrderResponse)
+    response := new(multipleOrderResponse)
     _, err = s.client.do(req, response)
+    if err != nil {
+        return nil, err
+    }
 
-    return *response, err
-
+    created := make([]Order, 0, len(response.Orders))
+    rejected := MultiOrderError{}
+    for i, raw := range response.Orders {
+        var result struct {
+            Id      int
+            Message string
+        }
+        json.Unmarshal(raw, &result)
+        if result.Id == 0 {
+            if result.Message == "" {
+                result.Message = "order not created"
+            }
+            rejected[i] = errors.New(result.Message)
+            continue
+        }
+        var order Order
+        if err := json.Unmarshal(raw, &order); err != nil {
+            return created, err
+        }
+        created = append(created, order)
+    }
+    for i := len(response.Orders); i < len(orders); i++ {
+        rejected[i] = errors.New("order not created")
+    }
+    if len(rejected) > 0 {
+        return created, rejected
+    }
+    if response.Status != "success" {
+        return created, fmt.Errorf("order/new/multi returned status %q", response.Status)
+    }
+    return created, nil
 }
 
 // Cancel multiple orders
 func (s *OrderService) CancelMulti(orderIDS []int64) (string, error) {
+    return s.CancelMultiContext(context.Background(), orderIDS)
+}
+
+// CancelMultiContext is like CancelMulti with a context for the request
+func (s *OrderService) CancelMultiContext(ctx context.Context, orderIDS []int64) (string, error) {
     payload := map[string]interface{}{
         "order_ids": orderIDS,
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "order/cancel/multi", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "order/cancel/multi", payload)
 
     if err != nil {
         return "", err
@@ -191,29 +647,59 @@ func (s *OrderService) CancelMulti(orderIDS []int64) (string, error) {
     return response["result"], err
 }
 
+// CancelOrdersForSymbol cancels the active orders of symbol with a single
+// multi-cancel request, leaving the orders of other symbols alone. It
+// returns the number of orders cancelled, 0 without a request when there
+// are none.
+func (s *OrderService) CancelOrdersForSymbol(symbol string) (int, error) {
+    return s.CancelOrdersForSymbolContext(context.Background(), symbol)
+}
+
+// CancelOrdersForSymbolContext is like CancelOrdersForSymbol with a context
+// for the requests
+func (s *OrderService) CancelOrdersForSymbolContext(ctx context.Context, symbol string) (int, error) {
+    orders, err := s.AllContext(ctx)
+    if err != nil {
+        return 0, err
+    }
+
+    symbol = NormalizeSymbol(symbol, SYMBOL_REST)
+    ids := []int64{}
+    for _, o := range orders {
+        if NormalizeSymbol(o.Symbol, SYMBOL_REST) == symbol {
+            ids = append(ids, int64(o.Id))
+        }
+    }
+    if len(ids) == 0 {
+        return 0, nil
+    }
+
+    if _, err := s.CancelMultiContext(ctx, ids); err != nil {
+        return 0, err
+    }
+
+    return len(ids), nil
+}
+
 // Replace an Order
 func (s *OrderService) Replace(orderId int64, useRemaining bool, newOrder SubmitOrder) (Order, error) {
+    return s.ReplaceContext(context.Background(), orderId, useRemaining, newOrder)
+}
 
-    var side string
-    if newOrder.Amount < 0 {
-        newOrder.Amount = math.Abs(newOrder.Amount)
-        side = "sell"
-    } else {
-        side = "buy"
-    }
+// ReplaceContext is like Replace with a context for the request
+func (s *OrderService) ReplaceContext(ctx context.Context, orderId int64, useRemaining bool, newOrder SubmitOrder) (Order, error) {
 
-    payload := map[string]interface{}{
-        "order_id":      strconv.FormatInt(orderId, 10),
-        "symbol":        newOrder.Symbol,
-        "amount":        strconv.FormatFloat(newOrder.Amount, 'f', -1, 32),
-        "price":         strconv.FormatFloat(newOrder.Price, 'f', -1, 32),
-        "exchange":      "bitfinex",
-        "side":          side,
-        "type":          newOrder.Type,
-        "use_remaining": useRemaining,
+    payload, err := newOrder.payload()
+    if err != nil {
+        return Order{}, err
+    }
+    if err := s.checkMargin(ctx, newOrder); err != nil {
+        return Order{}, err
     }
+    payload["order_id"] = strconv.FormatInt(orderId, 10)
+    payload["use_remaining"] = useRemaining
 
-    req, err := s.client.newAuthenticatedRequest("POST", "order/cancel/replace", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "order/cancel/replace", payload)
     if err != nil {
         return Order{}, err
     }
@@ -229,12 +715,17 @@ func (s *OrderService) Replace(orderId int64, useRemaining bool, newOrder Submit
 
 // Retrieve the status of an order
 func (s *OrderService) Status(orderId int64) (Order, error) {
+    return s.StatusContext(context.Background(), orderId)
+}
+
+// StatusContext is like Status with a context for the request
+func (s *OrderService) StatusContext(ctx context.Context, orderId int64) (Order, error) {
 
     payload := map[string]interface{}{
         "order_id": orderId,
     }
 
-    req, err := s.client.newAuthenticatedRequest("POST", "order/status", payload)
+    req, err := s.client.newAuthenticatedRequest(ctx, "POST", "order/status", payload)
 
     if err != nil {
         return Order{}, err
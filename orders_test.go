@@ -2,9 +2,12 @@ package bitfinex
 
 import (
     "bytes"
+    "encoding/base64"
+    "encoding/json"
     "io/ioutil"
     "net/http"
     "testing"
+    "time"
 )
 
 func TestOrdersAll(t *testing.T) {
@@ -46,10 +49,25 @@ func TestOrdersAll(t *testing.T) {
         t.Error("Actual ", orders[0].Id)
     }
 
+    if orders[0].Price != 0.02 || orders[0].RemainingAmount != 0.02 || !orders[0].IsLive {
+        t.Error("Expected price and remaining amount 0.02 on live order")
+        t.Error("Actual ", orders[0])
+    }
+
+    if orders[0].Time().Unix() != 1444276597 {
+        t.Error("Expected", 1444276597)
+        t.Error("Actual ", orders[0].Time().Unix())
+    }
+
 }
 
 func TestCreateMulti(t *testing.T) {
+    var payload struct {
+        Orders []map[string]interface{} `json:"orders"`
+    }
     httpDo = func(req *http.Request) (*http.Response, error) {
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
         msg := `{
             "order_ids":[{
             "id":448383727,
@@ -102,17 +120,75 @@ func TestCreateMulti(t *testing.T) {
         Symbol: "BTCUSD",
         Amount: 10.0,
         Price:  450.0,
-        Type:   ORDER_TYPE_LIMIT,
+        Type:   ORDER_TYPE_EXCHANGE_LIMIT,
     }}
-    response, err := NewClient().Orders.CreateMulti(reqOrders)
+    created, err := NewClient().Orders.CreateMulti(reqOrders)
 
     if err != nil {
         t.Error(err)
     }
 
-    if len(response.Orders) != 2 {
-        t.Error("Expected", 2)
-        t.Error("Actual ", len(response.Orders))
+    if len(payload.Orders) != 2 {
+        t.Fatal("Expected 2 orders sent, got", len(payload.Orders))
+    }
+    // orders without Margin are exchange orders
+    if payload.Orders[0]["type"] != "exchange limit" {
+        t.Error("Expected", "exchange limit")
+        t.Error("Actual ", payload.Orders[0]["type"])
+    }
+    if payload.Orders[1]["type"] != "exchange limit" {
+        t.Error("Expected", "exchange limit")
+        t.Error("Actual ", payload.Orders[1]["type"])
+    }
+
+    if len(created) != 2 {
+        t.Fatal("Expected 2 orders, got", len(created))
+    }
+    if created[0].Id != 448383727 || created[1].Id != 448383729 || created[1].OriginalAmount != 0.02 {
+        t.Error("Unexpected orders", created)
+    }
+}
+
+func TestCreateMultiRejected(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        msg := `{
+            "order_ids":[{
+            "id":448383727,"symbol":"btcusd","exchange":"bitfinex","price":"0.01","avg_execution_price":"0.0",
+            "side":"buy","type":"limit","timestamp":"1444274013.621701916","is_live":true,"is_cancelled":false,
+            "is_hidden":false,"was_forced":false,"original_amount":"0.01","remaining_amount":"0.01","executed_amount":"0.0"
+          },{
+            "message":"Invalid order: not enough tradable balance"
+          },{
+            "id":448383731,"symbol":"btcusd","exchange":"bitfinex","price":"0.03","avg_execution_price":"0.0",
+            "side":"buy","type":"limit","timestamp":"1444274013.661297306","is_live":true,"is_cancelled":false,
+            "is_hidden":false,"was_forced":false,"original_amount":"0.03","remaining_amount":"0.03","executed_amount":"0.0"
+          }],
+          "status":"success"
+       }`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    order := SubmitOrder{Symbol: "BTCUSD", Amount: 0.01, Price: 0.01, Type: ORDER_TYPE_LIMIT}
+    created, err := NewClient().Orders.CreateMulti([]SubmitOrder{order, order, order, order})
+
+    rejected, ok := err.(MultiOrderError)
+    if !ok {
+        t.Fatal("Expected MultiOrderError, got", err)
+    }
+    if len(rejected) != 2 || rejected[1] == nil || rejected[3] == nil {
+        t.Error("Expected orders 1 and 3 to be reported")
+        t.Error("Actual ", rejected)
+    }
+    if rejected[1] != nil && rejected[1].Error() != "Invalid order: not enough tradable balance" {
+        t.Error("Expected", "Invalid order: not enough tradable balance")
+        t.Error("Actual ", rejected[1])
+    }
+    if len(created) != 2 || created[0].Id != 448383727 || created[1].Id != 448383731 {
+        t.Error("Unexpected orders", created)
     }
 }
 
@@ -139,3 +215,518 @@ func TestCancelMulti(t *testing.T) {
     }
 
 }
+
+func TestCancelOrdersForSymbol(t *testing.T) {
+    var cancelled []interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        msg := `[
+            {"id":1,"symbol":"btcusd","price":"100.0"},
+            {"id":2,"symbol":"ethusd","price":"10.0"},
+            {"id":3,"symbol":"btcusd","price":"101.0"}
+        ]`
+        if req.URL.Path == "/v1/order/cancel/multi" {
+            raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+            var payload map[string]interface{}
+            json.Unmarshal(raw, &payload)
+            cancelled, _ = payload["order_ids"].([]interface{})
+            msg = `{"result":"Orders cancelled"}`
+        }
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    n, err := NewClient().Orders.CancelOrdersForSymbol("BTCUSD")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if n != 2 || len(cancelled) != 2 || cancelled[0] != 1.0 || cancelled[1] != 3.0 {
+        t.Error("Expected", 2, []int64{1, 3})
+        t.Error("Actual ", n, cancelled)
+    }
+
+    cancelled = nil
+    n, err = NewClient().Orders.CancelOrdersForSymbol("LTCUSD")
+    if err != nil || n != 0 || cancelled != nil {
+        t.Error("Expected no cancel request for LTCUSD, got", n, cancelled, err)
+    }
+}
+
+func TestSubmitOrderFlags(t *testing.T) {
+    var payload map[string]interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id":448411365}`)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    _, err := NewClient().Orders.Submit(SubmitOrder{
+        Symbol:       "BTCUSD",
+        Amount:       -0.5,
+        Price:        450.0,
+        Type:         ORDER_TYPE_EXCHANGE_LIMIT,
+        Hidden:       true,
+        PostOnly:     true,
+        OCO:          true,
+        SellPriceOCO: 400.0,
+    })
+
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    for _, key := range []string{"is_hidden", "is_postonly", "ocoorder"} {
+        if payload[key] != true {
+            t.Error("Expected", key, "to be set")
+        }
+    }
+    if payload["side"] != "sell" || payload["amount"] != "0.5" {
+        t.Error("Expected sell 0.5")
+        t.Error("Actual ", payload["side"], payload["amount"])
+    }
+    if payload["sell_price_oco"] != "400" {
+        t.Error("Expected", "400")
+        t.Error("Actual ", payload["sell_price_oco"])
+    }
+}
+
+func TestSubmitOrderOCOValidation(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        t.Fatal("request should not be sent")
+        return nil, nil
+    }
+
+    _, err := NewClient().Orders.Submit(SubmitOrder{
+        Symbol:      "BTCUSD",
+        Amount:      1,
+        Type:        ORDER_TYPE_MARKET,
+        OCO:         true,
+        BuyPriceOCO: 400.0,
+    })
+    if err == nil {
+        t.Error("Expected error for OCO on market order")
+    }
+
+    _, err = NewClient().Orders.Submit(SubmitOrder{
+        Symbol: "BTCUSD",
+        Amount: 1,
+        Price:  450.0,
+        Type:   ORDER_TYPE_LIMIT,
+        OCO:    true,
+    })
+    if err == nil {
+        t.Error("Expected error for OCO without stop price")
+    }
+}
+
+func TestOrderCreate(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        msg := `{
+           "id":448364249,
+           "symbol":"btcusd",
+           "exchange":"bitfinex",
+           "price":"0.01",
+           "avg_execution_price":"0.0",
+           "side":"buy",
+           "type":"exchange limit",
+           "timestamp":"1444272165.252370982",
+           "is_live":true,
+           "is_cancelled":false,
+           "is_hidden":false,
+           "was_forced":false,
+           "original_amount":"0.01",
+           "remaining_amount":"0.01",
+           "executed_amount":"0.0",
+           "order_id":448364249
+        }`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    order, err := NewClient().Orders.Create(BTCUSD, 0.01, 0.01, ORDER_TYPE_EXCHANGE_LIMIT)
+
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    if order.OrderId != 448364249 || order.OriginalAmount != 0.01 || order.ExecutedAmount != 0 {
+        t.Error("Unexpected order", order)
+    }
+    if tm := order.Time(); tm.Unix() != 1444272165 || tm.Nanosecond() != 252370982 {
+        t.Error("Expected", "1444272165.252370982")
+        t.Error("Actual ", tm.Unix(), tm.Nanosecond())
+    }
+}
+
+func TestParseTimestamp(t *testing.T) {
+    cases := []struct {
+        ts  string
+        ok  bool
+        sec int64
+        ns  int
+    }{
+        {"1444274013", true, 1444274013, 0},
+        {"1444274013.0", true, 1444274013, 0},
+        {"1444274013.621701916", true, 1444274013, 621701916},
+        {"1444274013.5", true, 1444274013, 500000000},
+        {"1444274013.6217019161", true, 1444274013, 621701916},
+        {"", false, 0, 0},
+        {"abc", false, 0, 0},
+        {"1444274013.-5", false, 0, 0},
+    }
+    for _, c := range cases {
+        tm, err := parseTimestamp(c.ts)
+        if (err == nil) != c.ok {
+            t.Error("Expected ok", c.ok, "for", c.ts)
+            t.Error("Actual ", err)
+            continue
+        }
+        if c.ok && (tm.Unix() != c.sec || tm.Nanosecond() != c.ns) {
+            t.Error("Expected", c.sec, c.ns)
+            t.Error("Actual ", tm.Unix(), tm.Nanosecond())
+        }
+    }
+}
+
+func TestCreateMultiInvalid(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        t.Fatal("request should not be sent")
+        return nil, nil
+    }
+
+    reqOrders := []SubmitOrder{{
+        Symbol: "BTCUSD",
+        Amount: 10.0,
+        Price:  450.0,
+        Type:   ORDER_TYPE_LIMIT,
+    }, {
+        Symbol:      "BTCUSD",
+        Amount:      10.0,
+        Type:        ORDER_TYPE_MARKET,
+        OCO:         true,
+        BuyPriceOCO: 400.0,
+    }}
+    _, err := NewClient().Orders.CreateMulti(reqOrders)
+
+    invalid, ok := err.(MultiOrderError)
+    if !ok {
+        t.Fatal("Expected MultiOrderError, got", err)
+    }
+    if len(invalid) != 1 || invalid[1] == nil {
+        t.Error("Expected order 1 to be reported")
+        t.Error("Actual ", invalid)
+    }
+}
+
+func TestSubmitOrderTypeValidation(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        t.Fatal("request should not be sent")
+        return nil, nil
+    }
+
+    invalid := []SubmitOrder{
+        {Symbol: "BTCUSD", Amount: 1, Type: ORDER_TYPE_EXCHANGE_LIMIT},
+        {Symbol: "BTCUSD", Amount: 1, Type: ORDER_TYPE_STOP},
+        {Symbol: "BTCUSD", Amount: 1, Price: 10, Type: "iceberg"},
+        {Symbol: "BTCUSD", Price: 10, Type: ORDER_TYPE_LIMIT},
+    }
+    for _, o := range invalid {
+        if _, err := NewClient().Orders.Submit(o); err == nil {
+            t.Error("Expected validation error for", o)
+        }
+    }
+}
+
+func TestSubmitMarketOrder(t *testing.T) {
+    var payload map[string]interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id":1}`)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    _, err := NewClient().Orders.Submit(SubmitOrder{Symbol: "BTCUSD", Amount: 1, Type: ORDER_TYPE_EXCHANGE_MARKET})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if payload["type"] != "exchange market" || payload["price"] != "1" {
+        t.Error("Expected exchange market order with placeholder price")
+        t.Error("Actual ", payload)
+    }
+}
+
+func TestSubmitOrderSide(t *testing.T) {
+    var payload map[string]interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id":1,"side":"sell"}`)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    order, err := NewClient().Orders.Submit(SubmitOrder{Symbol: "BTCUSD", Amount: 2, Side: SELL, Price: 450, Type: ORDER_TYPE_LIMIT})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if payload["side"] != "sell" || payload["amount"] != "2" {
+        t.Error("Expected sell order of 2")
+        t.Error("Actual ", payload)
+    }
+    if order.Side != SELL {
+        t.Error("Expected", SELL)
+        t.Error("Actual ", order.Side)
+    }
+
+    if _, err := NewClient().Orders.Submit(SubmitOrder{Symbol: "BTCUSD", Amount: 2, Side: "short", Price: 450, Type: ORDER_TYPE_LIMIT}); err == nil {
+        t.Error("Expected error for unknown side")
+    }
+
+    if SideOf(-1) != SELL || SideOf(1) != BUY || SELL.Signed(2) != -2 || BUY.Signed(-2) != 2 {
+        t.Error("Unexpected signed amount conversion")
+    }
+}
+
+func TestOrderDecimals(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        msg := `{"id":448411153,"symbol":"btcusd","price":"0.00001234","avg_execution_price":"0.0",
+          "side":"buy","type":"exchange limit","timestamp":"1444276597.0","original_amount":"0.30000001",
+          "remaining_amount":"0.30000001","executed_amount":"0.0"}`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    order, err := NewClient().Orders.Status(448411153)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if order.Decimals.Price != "0.00001234" || order.Decimals.OriginalAmount != "0.30000001" {
+        t.Error("Expected exact decimals")
+        t.Error("Actual ", order.Decimals)
+    }
+    if order.Price != 0.00001234 || order.Decimals.Price.Float64() != order.Price {
+        t.Error("Expected", 0.00001234)
+        t.Error("Actual ", order.Price)
+    }
+}
+
+func TestCancelByCID(t *testing.T) {
+    var payload map[string]interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        if req.URL.Path != "/v1/order/cancel" {
+            t.Error("Expected", "/v1/order/cancel")
+            t.Error("Actual ", req.URL.Path)
+        }
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(`{"id":1}`)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    created := time.Date(2016, 1, 2, 23, 0, 0, 0, time.UTC)
+    if err := NewClient().Orders.CancelByCID(12345, CIDDate(created)); err != nil {
+        t.Fatal(err)
+    }
+    if payload["cid"] != float64(12345) || payload["cid_date"] != "2016-01-02" {
+        t.Error("Unexpected payload", payload)
+    }
+}
+
+func TestOrderTrades(t *testing.T) {
+    var payload map[string]interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        if req.URL.Path != "/v1/order/trades" {
+            t.Error("Expected", "/v1/order/trades")
+            t.Error("Actual ", req.URL.Path)
+        }
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
+        msg := `[{
+            "tid":11970839,
+            "order_id":448364249,
+            "symbol":"btcusd",
+            "price":"246.94",
+            "amount":"0.5",
+            "timestamp":"1444141857.0",
+            "exchange":"bitfinex",
+            "type":"Sell",
+            "fee_currency":"USD",
+            "fee_amount":"-0.24694"
+        }]`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    fills, err := NewClient().Orders.Trades(448364249)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if payload["order_id"] != float64(448364249) {
+        t.Error("Unexpected payload", payload)
+    }
+
+    expected := Fill{
+        TradeId:     11970839,
+        Pair:        BTCUSD,
+        Timestamp:   1444141857,
+        OrderId:     448364249,
+        Amount:      -0.5,
+        Price:       246.94,
+        Fee:         -0.24694,
+        FeeCurrency: "USD",
+    }
+    if len(fills) != 1 || fills[0] != expected {
+        t.Error("Expected", expected)
+        t.Error("Actual ", fills)
+    }
+}
+
+func TestSubmitOrderMargin(t *testing.T) {
+    var payload map[string]interface{}
+    details := 0
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        body := `{"id":1}`
+        if req.URL.Path == "/v1/symbols_details" {
+            details++
+            body = `[{"pair":"btcusd","margin":true},{"pair":"xrpbtc","margin":false}]`
+        } else {
+            raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+            json.Unmarshal(raw, &payload)
+        }
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(body)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    c := NewClient()
+    order := SubmitOrder{Symbol: "BTCUSD", Amount: 1, Price: 450, Type: ORDER_TYPE_LIMIT}
+    if _, err := c.Orders.Submit(order); err != nil {
+        t.Fatal(err)
+    }
+    if payload["type"] != "exchange limit" {
+        t.Error("Expected", "exchange limit")
+        t.Error("Actual ", payload["type"])
+    }
+    if details != 0 {
+        t.Error("Expected symbols_details not to be requested, got", details)
+    }
+
+    order.Margin = true
+    for i := 0; i < 2; i++ {
+        if _, err := c.Orders.Submit(order); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if payload["type"] != "limit" {
+        t.Error("Expected", "limit")
+        t.Error("Actual ", payload["type"])
+    }
+    if details != 1 {
+        t.Error("Expected symbols_details to be requested once, got", details)
+    }
+
+    if _, err := c.Orders.Submit(SubmitOrder{Symbol: "XRPBTC", Amount: 1, Price: 1, Type: ORDER_TYPE_LIMIT, Margin: true}); err == nil {
+        t.Error("Expected error for a pair without margin trading")
+    }
+    order.Type = ORDER_TYPE_EXCHANGE_LIMIT
+    if _, err := c.Orders.Submit(order); err == nil {
+        t.Error("Expected error for an exchange type on a margin order")
+    }
+}
+
+func TestOrderReplace(t *testing.T) {
+    var payload map[string]interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        if req.URL.Path != "/v1/order/cancel/replace" {
+            t.Error("Expected", "/v1/order/cancel/replace")
+            t.Error("Actual ", req.URL.Path)
+        }
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
+        msg := `{
+          "id":448411153,"symbol":"btcusd","exchange":null,"price":"0.02","avg_execution_price":"0.0",
+          "side":"buy","type":"limit","timestamp":"1444276597.0","is_live":true,"is_cancelled":false,
+          "is_hidden":false,"was_forced":false,"original_amount":"0.02","remaining_amount":"0.02","executed_amount":"0.0"
+        }`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    order, err := NewClient().Orders.Replace(448411152, true, SubmitOrder{Symbol: "BTCUSD", Amount: 0.02, Price: 0.02, Type: ORDER_TYPE_LIMIT})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if payload["type"] != "exchange limit" {
+        t.Error("Expected", "exchange limit")
+        t.Error("Actual ", payload["type"])
+    }
+    if payload["order_id"] != "448411152" || payload["use_remaining"] != true {
+        t.Error("Unexpected payload", payload)
+    }
+    if order.Id != 448411153 {
+        t.Error("Expected", 448411153)
+        t.Error("Actual ", order.Id)
+    }
+}
+
+func TestOrderCancel(t *testing.T) {
+    var payload map[string]interface{}
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        if req.URL.Path != "/v1/order/cancel" {
+            t.Error("Expected", "/v1/order/cancel")
+            t.Error("Actual ", req.URL.Path)
+        }
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
+        msg := `{
+          "id":446915287,"symbol":"btcusd","exchange":null,"price":"239.0","avg_execution_price":"238.5",
+          "side":"sell","type":"trailing stop","timestamp":"1444141982.0","is_live":false,"is_cancelled":true,
+          "is_hidden":false,"was_forced":false,"original_amount":"1.0","remaining_amount":"0.25","executed_amount":"0.75"
+        }`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    order, err := NewClient().Orders.Cancel(446915287)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if payload["order_id"] != float64(446915287) {
+        t.Error("Unexpected payload", payload)
+    }
+    if order.Id != 446915287 || !order.IsCanceled || order.IsLive || order.RemainingAmount != 0.25 || order.ExecutedAmount != 0.75 {
+        t.Error("Unexpected cancelled order", order)
+    }
+}
@@ -1,12 +1,19 @@
 package bitfinex
 
+import "context"
+
 type PairsService struct {
     client *Client
 }
 
 // Get all Pair names as array of strings
 func (p *PairsService) All() ([]string, error) {
-    req, err := p.client.newRequest("GET", "symbols", nil)
+    return p.AllContext(context.Background())
+}
+
+// AllContext is like All with a context for the request
+func (p *PairsService) AllContext(ctx context.Context) ([]string, error) {
+    req, err := p.client.newRequest(ctx, "GET", "symbols", nil)
     if err != nil {
         return nil, err
     }
@@ -30,11 +37,18 @@ type Pair struct {
     MaximumOrderSize float64 `json:"maximum_order_size,string"`
     MinimumOrderSize float64 `json:"minimum_order_size,string"`
     Espiration       string
+    // Margin is set for pairs that can be traded on margin
+    Margin bool
 }
 
 // Return a list of detailed pairs
 func (p *PairsService) AllDetailed() ([]Pair, error) {
-    req, err := p.client.newRequest("GET", "symbols_details", nil)
+    return p.AllDetailedContext(context.Background())
+}
+
+// AllDetailedContext is like AllDetailed with a context for the request
+func (p *PairsService) AllDetailedContext(ctx context.Context) ([]Pair, error) {
+    req, err := p.client.newRequest(ctx, "GET", "symbols_details", nil)
     if err != nil {
         return nil, err
     }
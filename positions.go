@@ -1,6 +1,7 @@
 package bitfinex
 
 import (
+    "context"
     "strconv"
     "time"
 )
@@ -33,7 +34,12 @@ func (p *Position) ParseTime() (*time.Time, error) {
 
 // All - gets all positions
 func (b *PositionsService) All() ([]Position, error) {
-    req, err := b.client.newAuthenticatedRequest("GET", "positions", nil)
+    return b.AllContext(context.Background())
+}
+
+// AllContext is like All with a context for the request
+func (b *PositionsService) AllContext(ctx context.Context) ([]Position, error) {
+    req, err := b.client.newAuthenticatedRequest(ctx, "GET", "positions", nil)
     if err != nil {
         return nil, err
     }
@@ -49,13 +55,18 @@ func (b *PositionsService) All() ([]Position, error) {
 
 // Claim a position
 func (b *PositionsService) Claim(positionId, amount string) (Position, error) {
+    return b.ClaimContext(context.Background(), positionId, amount)
+}
+
+// ClaimContext is like Claim with a context for the request
+func (b *PositionsService) ClaimContext(ctx context.Context, positionId, amount string) (Position, error) {
 
     request := map[string]interface{}{
         "position_id": positionId,
         "amount":      amount,
     }
 
-    req, err := b.client.newAuthenticatedRequest("POST", "position/claim", request)
+    req, err := b.client.newAuthenticatedRequest(ctx, "POST", "position/claim", request)
 
     if err != nil {
         return Position{}, err
@@ -0,0 +1,55 @@
+package bitfinex
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RateLimiter spaces out REST requests so the client stays under the
+// Bitfinex request limits. It is safe for concurrent use and can be shared
+// between several clients using the same API key.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter creates a limiter allowing requestsPerMinute requests.
+func NewRateLimiter(requestsPerMinute int) *RateLimiter {
+	l := &RateLimiter{}
+	if requestsPerMinute > 0 {
+		l.interval = time.Minute / time.Duration(requestsPerMinute)
+	}
+	return l
+}
+
+// Wait blocks until the next request is allowed. If the wait would end
+// after the context deadline it returns an error straight away. A wait
+// ended by the context doesn't use up a request.
+func (l *RateLimiter) Wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		now := time.Now()
+		if !l.next.After(now) {
+			// the slot is only taken once it is due
+			l.next = now.Add(l.interval)
+			l.mu.Unlock()
+			return nil
+		}
+		at := l.next
+		l.mu.Unlock()
+		if deadline, ok := ctx.Deadline(); ok && at.After(deadline) {
+			return fmt.Errorf("rate limit: next request allowed in %v, after the context deadline", at.Sub(now))
+		}
+
+		t := time.NewTimer(at.Sub(now))
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("rate limit: %w", ctx.Err())
+		}
+	}
+}
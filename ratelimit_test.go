@@ -0,0 +1,104 @@
+package bitfinex
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterSpacing(t *testing.T) {
+	l := NewRateLimiter(600) // one request every 100ms
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Error("Expected at least", 200*time.Millisecond)
+		t.Error("Actual ", elapsed)
+	}
+}
+
+func TestRateLimiterDeadline(t *testing.T) {
+	l := NewRateLimiter(1)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	start := time.Now()
+	if err := l.Wait(ctx); err == nil {
+		t.Error("Expected deadline error")
+	}
+	// the next request is a minute away, well after the deadline
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Error("Expected to return before the deadline, took", elapsed)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	l := NewRateLimiter(600)
+	if err := l.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	l.mu.Lock()
+	next := l.next
+	l.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+	if err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Error("Expected", context.Canceled)
+		t.Error("Actual ", err)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.next.Equal(next) {
+		t.Error("Expected the cancelled wait to leave the next request at", next)
+		t.Error("Actual ", l.next)
+	}
+}
+
+func TestClientRateLimiter(t *testing.T) {
+	calls := 0
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		calls++
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`["btcusd"]`)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+
+	c := NewClient()
+	c.RateLimiter = NewRateLimiter(600)
+
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		if _, err := c.Pairs.All(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if calls != 2 {
+		t.Error("Expected", 2)
+		t.Error("Actual ", calls)
+	}
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Error("Expected requests to be spaced by", 100*time.Millisecond)
+		t.Error("Actual ", elapsed)
+	}
+}
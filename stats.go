@@ -1,8 +1,8 @@
 package bitfinex
 
 import (
+    "context"
     "net/url"
-    "strings"
 )
 
 type StatsService struct {
@@ -16,7 +16,12 @@ type Stats struct {
 
 // All(pair) - Volume stats for specified pair
 func (s *StatsService) All(pair string, period, volume string) ([]Stats, error) {
-    pair = strings.ToUpper(pair)
+    return s.AllContext(context.Background(), pair, period, volume)
+}
+
+// AllContext is like All with a context for the request
+func (s *StatsService) AllContext(ctx context.Context, pair string, period, volume string) ([]Stats, error) {
+    pair = NormalizeSymbol(pair, SYMBOL_REST)
 
     params := url.Values{}
     if period != "" {
@@ -25,7 +30,7 @@ func (s *StatsService) All(pair string, period, volume string) ([]Stats, error)
     if volume != "" {
         params.Add("volume", volume)
     }
-    req, err := s.client.newRequest("GET", "stats/"+strings.ToUpper(pair), params)
+    req, err := s.client.newRequest(ctx, "GET", "stats/"+pair, params)
 
     if err != nil {
         return nil, err
@@ -0,0 +1,38 @@
+package bitfinex
+
+import (
+    "strings"
+)
+
+// SymbolStyle is the representation of a trading pair an endpoint expects.
+type SymbolStyle int
+
+const (
+    // v1 REST, e.g. btcusd
+    SYMBOL_REST SymbolStyle = iota
+    // v1 websocket, e.g. BTCUSD
+    SYMBOL_WEBSOCKET
+    // v2 trading pairs, e.g. tBTCUSD
+    SYMBOL_V2
+)
+
+// NormalizeSymbol converts a trading pair to the representation of target.
+// The canonical form is the base and quote currencies without separator in
+// any case, like the BTCUSD constants, but any form used by an endpoint is
+// accepted as well: btcusd, tBTCUSD, BTC/USD, BTC-USD or BTC_USD. Every
+// method taking a pair normalizes it, so pairs can be passed in any of
+// those forms.
+func NormalizeSymbol(s string, target SymbolStyle) string {
+    if len(s) > 1 && s[0] == 't' && strings.ToUpper(s[1:]) == s[1:] {
+        s = s[1:]
+    }
+    s = strings.NewReplacer("/", "", "-", "", "_", "").Replace(s)
+
+    switch target {
+    case SYMBOL_REST:
+        return strings.ToLower(s)
+    case SYMBOL_V2:
+        return "t" + strings.ToUpper(s)
+    }
+    return strings.ToUpper(s)
+}
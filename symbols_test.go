@@ -0,0 +1,29 @@
+package bitfinex
+
+import (
+    "testing"
+)
+
+func TestNormalizeSymbol(t *testing.T) {
+    cases := []struct {
+        in       string
+        target   SymbolStyle
+        expected string
+    }{
+        {"BTCUSD", SYMBOL_REST, "btcusd"},
+        {"btcusd", SYMBOL_WEBSOCKET, "BTCUSD"},
+        {"tBTCUSD", SYMBOL_WEBSOCKET, "BTCUSD"},
+        {"BTC/USD", SYMBOL_V2, "tBTCUSD"},
+        {"btc-usd", SYMBOL_V2, "tBTCUSD"},
+        {"tBTCUSD", SYMBOL_V2, "tBTCUSD"},
+        // a pair starting with t is not a v2 symbol
+        {"trxusd", SYMBOL_WEBSOCKET, "TRXUSD"},
+        {"TRXUSD", SYMBOL_REST, "trxusd"},
+    }
+    for _, c := range cases {
+        if actual := NormalizeSymbol(c.in, c.target); actual != c.expected {
+            t.Error("Expected", c.expected)
+            t.Error("Actual ", actual)
+        }
+    }
+}
@@ -1,6 +1,8 @@
 package bitfinex
 
 import (
+    "context"
+    "fmt"
     "strconv"
     "strings"
     "time"
@@ -33,8 +35,13 @@ func (el *Tick) ParseTime() (*time.Time, error) {
 
 // Get(pair) - return last Tick for specified pair
 func (s *TickerService) Get(pair string) (Tick, error) {
-    pair = strings.ToUpper(pair)
-    req, err := s.client.newRequest("GET", "pubticker/"+pair, nil)
+    return s.GetContext(context.Background(), pair)
+}
+
+// GetContext is like Get with a context for the request
+func (s *TickerService) GetContext(ctx context.Context, pair string) (Tick, error) {
+    pair = NormalizeSymbol(pair, SYMBOL_REST)
+    req, err := s.client.newRequest(ctx, "GET", "pubticker/"+pair, nil)
 
     if err != nil {
         return Tick{}, err
@@ -49,3 +56,37 @@ func (s *TickerService) Get(pair string) (Tick, error) {
 
     return *v, nil
 }
+
+// ExchangeRate returns the amount of quote one unit of base is worth, from
+// the last price of the pair. v1 has no conversion endpoint, so the
+// ticker of base+quote is used, or the inverse of the quote+base one when
+// only that pair is listed, e.g. ExchangeRate("usd", "btc").
+func (s *TickerService) ExchangeRate(base, quote string) (float64, error) {
+    return s.ExchangeRateContext(context.Background(), base, quote)
+}
+
+// ExchangeRateContext is like ExchangeRate with a context for the request
+func (s *TickerService) ExchangeRateContext(ctx context.Context, base, quote string) (float64, error) {
+    base, quote = strings.ToUpper(base), strings.ToUpper(quote)
+    if base == quote {
+        return 1, nil
+    }
+
+    tick, err := s.GetContext(ctx, base+quote)
+    if err == nil {
+        return strconv.ParseFloat(tick.LastPrice, 64)
+    }
+
+    inverse, ierr := s.GetContext(ctx, quote+base)
+    if ierr != nil {
+        return 0, err
+    }
+    price, ierr := strconv.ParseFloat(inverse.LastPrice, 64)
+    if ierr != nil {
+        return 0, ierr
+    }
+    if price == 0 {
+        return 0, fmt.Errorf("no last price for %s%s", quote, base)
+    }
+    return 1 / price, nil
+}
@@ -41,3 +41,36 @@ func TestTickerGet(t *testing.T) {
         t.Error("Actual ", tick.LastPrice)
     }
 }
+
+func TestTickerExchangeRate(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        if req.URL.Path != "/v1/pubticker/btcusd" {
+            resp := http.Response{
+                Body:       ioutil.NopCloser(bytes.NewBufferString(`{"message":"Unknown symbol"}`)),
+                StatusCode: 400,
+            }
+            return &resp, nil
+        }
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(`{"last_price":"250.0"}`)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    rate, err := NewClient().Ticker.ExchangeRate("btc", "usd")
+    if err != nil || rate != 250 {
+        t.Error("Expected", 250)
+        t.Error("Actual ", rate, err)
+    }
+
+    rate, err = NewClient().Ticker.ExchangeRate("usd", "btc")
+    if err != nil || rate != 0.004 {
+        t.Error("Expected", 0.004)
+        t.Error("Actual ", rate, err)
+    }
+
+    if _, err = NewClient().Ticker.ExchangeRate("usd", "eur"); err == nil {
+        t.Error("Expected an error for an unknown pair")
+    }
+}
@@ -1,9 +1,9 @@
 package bitfinex
 
 import (
+    "context"
     "net/url"
     "strconv"
-    "strings"
     "time"
 )
 
@@ -26,7 +26,12 @@ func (el *Trade) Time() *time.Time {
 }
 
 func (s *TradesService) All(pair string, timestamp time.Time, limitTrades int) ([]Trade, error) {
-    pair = strings.ToUpper(pair)
+    return s.AllContext(context.Background(), pair, timestamp, limitTrades)
+}
+
+// AllContext is like All with a context for the request
+func (s *TradesService) AllContext(ctx context.Context, pair string, timestamp time.Time, limitTrades int) ([]Trade, error) {
+    pair = NormalizeSymbol(pair, SYMBOL_REST)
 
     params := url.Values{}
     if !time.Time.IsZero(timestamp) {
@@ -35,7 +40,7 @@ func (s *TradesService) All(pair string, timestamp time.Time, limitTrades int) (
     if limitTrades != 0 {
         params.Add("limit_trades", strconv.Itoa(limitTrades))
     }
-    req, err := s.client.newRequest("GET", "trades/"+pair, params)
+    req, err := s.client.newRequest(ctx, "GET", "trades/"+pair, params)
     if err != nil {
         return nil, err
     }
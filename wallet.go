@@ -1,10 +1,16 @@
 package bitfinex
 
-import "strconv"
+import (
+    "context"
+    "fmt"
+    "strconv"
+)
 
 const (
+    // Margin wallet
     WALLET_TRADING  = "trading"
     WALLET_EXCHANGE = "exchange"
+    // Funding wallet
     WALLET_DEPOSIT  = "deposit"
 )
 
@@ -17,17 +23,23 @@ type TransferStatus struct {
     Message string
 }
 
-// Transfer funds between wallets
+// Transfer funds between wallets, e.g. from WALLET_EXCHANGE to WALLET_TRADING.
+// A transfer Bitfinex rejects is returned with an error holding its message
 func (c *WalletService) Transfer(amount float64, currency, from, to string) ([]TransferStatus, error) {
+    return c.TransferContext(context.Background(), amount, currency, from, to)
+}
+
+// TransferContext is like Transfer with a context for the request
+func (c *WalletService) TransferContext(ctx context.Context, amount float64, currency, from, to string) ([]TransferStatus, error) {
 
     payload := map[string]interface{}{
-        "amount":     strconv.FormatFloat(amount, 'f', -1, 32),
+        "amount":     strconv.FormatFloat(amount, 'f', -1, 64),
         "currency":   currency,
         "walletfrom": from,
         "walletto":   to,
     }
 
-    req, err := c.client.newAuthenticatedRequest("GET", "transfer", payload)
+    req, err := c.client.newAuthenticatedRequest(ctx, "POST", "transfer", payload)
 
     if err != nil {
         return nil, err
@@ -37,7 +49,17 @@ func (c *WalletService) Transfer(amount float64, currency, from, to string) ([]T
 
     _, err = c.client.do(req, &status)
 
-    return status, err
+    if err != nil {
+        return status, err
+    }
+
+    for _, s := range status {
+        if s.Status != "success" {
+            return status, fmt.Errorf("transfer failed: %s", s.Message)
+        }
+    }
+
+    return status, nil
 }
 
 type WithdrawStatus struct {
@@ -48,6 +70,11 @@ type WithdrawStatus struct {
 
 // Withdraw a cryptocurrency to a digital wallet
 func (c *WalletService) WithdrawCrypto(amount float64, currency, wallet, destinationAddress string) ([]WithdrawStatus, error) {
+    return c.WithdrawCryptoContext(context.Background(), amount, currency, wallet, destinationAddress)
+}
+
+// WithdrawCryptoContext is like WithdrawCrypto with a context for the request
+func (c *WalletService) WithdrawCryptoContext(ctx context.Context, amount float64, currency, wallet, destinationAddress string) ([]WithdrawStatus, error) {
 
     payload := map[string]interface{}{
         "amount":         strconv.FormatFloat(amount, 'f', -1, 32),
@@ -56,7 +83,7 @@ func (c *WalletService) WithdrawCrypto(amount float64, currency, wallet, destina
         "address":        destinationAddress,
     }
 
-    req, err := c.client.newAuthenticatedRequest("GET", "withdraw", payload)
+    req, err := c.client.newAuthenticatedRequest(ctx, "GET", "withdraw", payload)
 
     if err != nil {
         return nil, err
@@ -81,6 +108,11 @@ type BankAccount struct {
 }
 
 func (c *WalletService) WithdrawWire(amount float64, expressWire bool, wallet string, beneficiaryBank, intermediaryBank BankAccount, message string) ([]WithdrawStatus, error) {
+    return c.WithdrawWireContext(context.Background(), amount, expressWire, wallet, beneficiaryBank, intermediaryBank, message)
+}
+
+// WithdrawWireContext is like WithdrawWire with a context for the request
+func (c *WalletService) WithdrawWireContext(ctx context.Context, amount float64, expressWire bool, wallet string, beneficiaryBank, intermediaryBank BankAccount, message string) ([]WithdrawStatus, error) {
 
     var express int
     if expressWire {
@@ -109,7 +141,7 @@ func (c *WalletService) WithdrawWire(amount float64, expressWire bool, wallet st
         "intermediary_bank_swift":   intermediaryBank.SwiftCode,
     }
 
-    req, err := c.client.newAuthenticatedRequest("GET", "withdraw", payload)
+    req, err := c.client.newAuthenticatedRequest(ctx, "GET", "withdraw", payload)
 
     if err != nil {
         return nil, err
@@ -2,13 +2,22 @@ package bitfinex
 
 import (
     "bytes"
+    "encoding/base64"
+    "encoding/json"
     "io/ioutil"
     "net/http"
     "testing"
 )
 
 func TestWalletTransfer(t *testing.T) {
+    var payload map[string]interface{}
     httpDo = func(req *http.Request) (*http.Response, error) {
+        if req.Method != "POST" {
+            t.Error("Expected", "POST")
+            t.Error("Actual ", req.Method)
+        }
+        raw, _ := base64.StdEncoding.DecodeString(req.Header.Get("X-BFX-PAYLOAD"))
+        json.Unmarshal(raw, &payload)
         msg := `[{
           "status":"success",
           "message":"1.0 USD transfered from Exchange to Deposit"
@@ -20,7 +29,7 @@ func TestWalletTransfer(t *testing.T) {
         return &resp, nil
     }
 
-    response, err := NewClient().Wallet.Transfer(10.0, "BTC", "1WalletA", "1WalletB")
+    response, err := NewClient().Wallet.Transfer(0.12345678, "BTC", WALLET_EXCHANGE, WALLET_TRADING)
 
     if err != nil {
         t.Error(err)
@@ -30,6 +39,28 @@ func TestWalletTransfer(t *testing.T) {
         t.Error("Expected", "success")
         t.Error("Actual ", response[0].Status)
     }
+
+    if payload["amount"] != "0.12345678" || payload["walletfrom"] != "exchange" || payload["walletto"] != "trading" {
+        t.Error("Unexpected payload", payload)
+    }
+}
+
+func TestWalletTransferError(t *testing.T) {
+    httpDo = func(req *http.Request) (*http.Response, error) {
+        msg := `[{"status":"error","message":"Insufficient balance."}]`
+        resp := http.Response{
+            Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+            StatusCode: 200,
+        }
+        return &resp, nil
+    }
+
+    _, err := NewClient().Wallet.Transfer(1, "BTC", WALLET_EXCHANGE, WALLET_TRADING)
+
+    if err == nil || err.Error() != "transfer failed: Insufficient balance." {
+        t.Error("Expected", "transfer failed: Insufficient balance.")
+        t.Error("Actual ", err)
+    }
 }
 
 func TestWithdrawCrypto(t *testing.T) {
@@ -1,14 +1,24 @@
 package bitfinex
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
+	"net/url"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -37,185 +47,1676 @@ const (
 	CHAN_BOOK   = "book"
 	CHAN_TRADE  = "trades"
 	CHAN_TICKER = "ticker"
+	// Candles are only served by the v2 endpoint, DefaultWebSocketV2URL
+	CHAN_CANDLES = "candles"
+	// Derivatives and liquidation status, v2 only as well
+	CHAN_STATUS = "status"
+)
+
+const (
+	// DefaultMaxMessageSize is the default limit for a single inbound frame.
+	DefaultMaxMessageSize = 1 << 20
+	// DefaultReconnectInterval is the default delay between reconnect attempts.
+	DefaultReconnectInterval = 5 * time.Second
+	// MaxChannelsPerConnection is the number of channels Bitfinex allows
+	// to subscribe on a single connection.
+	MaxChannelsPerConnection = 30
+	// ChannelDefaultLen as a subscription length requests the channel
+	// default: DefaultBookLen price levels for books, and no length at all
+	// for the channels that don't take one.
+	ChannelDefaultLen = -1
+	// DefaultBookLen is the number of book levels Bitfinex sends by default.
+	DefaultBookLen = 25
 )
 
 // WebSocketService allow to connect and receive stream data
 // from bitfinex.com ws service.
 type WebSocketService struct {
+	// MaxMessageSize is the largest inbound frame in bytes accepted on both
+	// the public and the private connection. Larger frames fail the read
+	// loop with websocket.ErrReadLimit. Zero disables the limit.
+	MaxMessageSize int64
+
+	// AutoReconnect makes Subscribe and ConnectPrivate re-establish a broken
+	// connection, replaying the subscriptions or the authentication,
+	// instead of returning the read error.
+	//
+	// Frames are delivered in the order they were received and a single
+	// goroutine reads one connection at a time, so every frame of the old
+	// connection, including those still buffered in a consumer channel, is
+	// received before anything of the new one, and frames of the old
+	// connection held for a subscription not confirmed yet are discarded.
+	// For the channels sending snapshots the boundary is marked by the
+	// SnapshotReset row of raw subscriptions, an OrderBook with Reset set or a
+	// frame with Snapshot set.
+	AutoReconnect bool
+	// ReconnectInterval is the delay before each reconnect attempt.
+	ReconnectInterval time.Duration
+	// MaxReconnectAttempts bounds the attempts to reconnect after a failure,
+	// and those of Connect, zero means no limit (see ConnectContext). Once
+	// exhausted, Connect, Subscribe and ConnectPrivate fail with a
+	// *ReconnectError.
+	MaxReconnectAttempts int
+	// MaxInFlightSubscribes, when positive, limits the subscribe messages
+	// sent and not confirmed yet. The others are sent as the confirmations
+	// arrive, so that Bitfinex does not drop them when subscribing to many
+	// channels at once.
+	MaxInFlightSubscribes int
+	// CommandQueueSize bounds the messages queued for the read loop by
+	// AddSubscribe, the typed Subscribe* methods and Unsubscribe while
+	// Subscribe runs, DefaultCommandQueueSize when zero. The read loop sends
+	// them between two frames, so that only it writes subscription changes.
+	// Once the queue is full, they block.
+	CommandQueueSize int
+	// SubscribeAckTimeout, when positive, sends a subscribe message again
+	// when its subscribed event did not arrive in time, up to 5 times.
+	SubscribeAckTimeout time.Duration
+	// IdleTimeout, when positive, fails Subscribe with ErrIdleTimeout when
+	// no frame, heartbeats included, arrives on the public connection for
+	// that long, closing it. Bitfinex sends a heartbeat on every channel
+	// every 15 seconds.
+	IdleTimeout time.Duration
+
+	// IsRetryable, when set, replaces the default classification of the
+	// errors that break a connection or a reconnect attempt: AutoReconnect
+	// only reconnects after errors it reports as retryable. By default
+	// network errors, timeouts and unexpected closes are retried, while a
+	// normal closure, a cancelled context, an auth failure, a rejected
+	// handshake or a *DecodeError are not.
+	IsRetryable func(err error) bool
+
+	// OnBeforeResubscribe, when set, is called after a reconnect with the
+	// current subscriptions and returns the ones to replay. Entries can be
+	// dropped, reordered or have their Len changed; new entries subscribe
+	// the raw frames of a channel to their Chan.
+	OnBeforeResubscribe func(current []SubscriptionInfo) []SubscriptionInfo
+
+	// OnBlockedSend, when set, is called instead of logging a warning when
+	// a frame arrives for a subscription whose buffered channel is full, so
+	// that delivering it blocks the read loop. backlog is the number of
+	// queued values. It is called once until the consumer catches up.
+	OnBlockedSend func(chanId float64, backlog int)
+
+	// PrivateBufferSize, when positive, queues up to that many TermData of
+	// the private connection between reading and the channel passed to
+	// ConnectPrivate, so that a slow consumer doesn't stall reading.
+	// PrivateOverflow applies when the queue, or a buffered channel without
+	// it, is full.
+	PrivateBufferSize int
+	PrivateOverflow   OverflowPolicy
+
+	// OnSequenceGap, when set, is called instead of logging when a missing
+	// message is detected with CONF_SEQ_ALL, before every channel is
+	// resubscribed.
+	OnSequenceGap func(gap *SequenceGapError)
+
+	// CloseChannelsOnError makes Subscribe close every consumer channel
+	// before it returns, so that range loops over them terminate. Channels
+	// shared by several subscriptions are closed once. The subscriptions are
+	// cleared as well and must be added again, with new channels, before
+	// calling Subscribe again.
+	CloseChannelsOnError bool
+
+	// OnMaintenance, when set, is called when a Bitfinex maintenance
+	// starts (active is true) and when it ends.
+	OnMaintenance func(active bool)
+
+	// OnDisconnect, when set, is called on the read goroutine when the
+	// public or the private connection breaks, with the reason given by
+	// the server's close frame, before any reconnect.
+	OnDisconnect func(d Disconnect)
+
+	// StrictDecoding makes Subscribe and ConnectPrivate fail with a
+	// *DecodeError on any frame that doesn't have the expected shape,
+	// instead of dropping it or decoding it as well as possible. It is
+	// meant for development.
+	StrictDecoding bool
+
+	// CacheLastPrices records the last price seen on every ticker and
+	// trades subscription, see LastPrice.
+	CacheLastPrices bool
+
+	// Version selects the protocol spoken, see Protocol. It is derived
+	// from the client WebSocketURL when zero.
+	Version ProtocolVersion
+
+	// Unmarshal decodes the frames of both connections, json.Unmarshal when
+	// nil. Public data frames made of numbers only are scanned directly and
+	// never reach it. High volume feeds can plug in a faster implementation
+	// with the same semantics, e.g.
+	// jsoniter.ConfigCompatibleWithStandardLibrary.Unmarshal.
+	Unmarshal func(data []byte, v interface{}) error
+
+	// Subprotocols are offered in the handshake of both connections.
+	// Bitfinex does not negotiate a subprotocol, so none are sent by default.
+	Subprotocols []string
+
+	// Proxy returns the proxy used by both connections, overriding the
+	// HTTP_PROXY/HTTPS_PROXY environment variables used by default. Besides
+	// http and https proxies, socks5:// proxy URLs are supported, e.g.
+	//   w.Proxy = http.ProxyURL(&url.URL{Scheme: "socks5", Host: "localhost:1080"})
+	// Credentials in the proxy URL, e.g. url.UserPassword(user, password),
+	// are sent as basic auth in the Proxy-Authorization header of the
+	// CONNECT request, or used for the socks5 authentication.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// TLSConfig, when set, is used for the TLS handshake of both
+	// connections, e.g. with custom root CAs or a client certificate, and
+	// takes precedence over the client WebSocketTLSSkipVerify. The server
+	// name is taken from the URL when it is empty.
+	TLSConfig *tls.Config
+
+	// OnSendFrame, when set, is called with every text frame written on
+	// either connection, before it is sent: subscriptions, conf, the auth
+	// message and so on. The key and signature of the auth message are
+	// replaced by REDACTED. It must not retain or modify the frame.
+	OnSendFrame func(frame []byte)
+
+	// DebugHandshake, when set, is called with the HTTP response of every
+	// websocket handshake on both connections, successful or not.
+	DebugHandshake func(resp *http.Response)
+
 	// http client
 	client *Client
+	// guards the connections and closed flags against Close called from
+	// another goroutine
+	mu     sync.Mutex
+	closed bool
+	// set by the stop function of SubscribeWithStop
+	stopped     bool
+	maintenance bool
+	// set while the public connection is up, for Health
+	connected bool
+	// successful reconnects of the public connection, accessed atomically
+	reconnects int64
+	// last error that broke the public read loop
+	lastErr error
+	// conf flags sent on every connect
+	flags int
+	// last sequence number seen with CONF_SEQ_ALL
+	seq int64
+	// last prices by pair with CacheLastPrices
+	pricesMu sync.RWMutex
+	prices   map[string]float64
+	// ClockSkew, accessed atomically
+	skew int64
+	// serializes writes on the public connection
+	writeMu sync.Mutex
 	// websocket client
 	ws *websocket.Conn
+	// reads ws for the read loop
+	wsReader *wsReader
+	// the network connection below ws, to batch the subscribe messages
+	batch *batchConn
+	// closed when the read loop of the public connection returns, for
+	// Close to wait for the close handshake
+	readDone chan struct{}
+	// commands queued for the running read loop, nil otherwise
+	commands chan wsCommand
 	// special web socket for private messages
-	privateWs *websocket.Conn
-	// map internal channels to websocket's
-	chanMap    map[float64]chan [][]float64
-	subscribes []subscribeToChannel
+	privateWs     *websocket.Conn
+	privateClosed bool
+	// the status announcing a re-authentication of the private connection,
+	// set by RotateCredentials and by arming the dead man's switch
+	reauth string
+	// whether the private connection was authenticated with dmsCancelAll
+	privateDms bool
+	// reported by the last successful authentication
+	auth *AuthInfo
+	// serializes writes on the private connection
+	privateWriteMu sync.Mutex
+	// privateBlocked is set while the private consumer channel was last seen
+	// full, accessed by the private read goroutine only.
+	privateBlocked bool
+	// OrderResult channels by cid, written under mu
+	orderResults map[int64]chan OrderUpdate
+	// map internal channels to websocket's, written under mu so that
+	// Health can read them
+	chanMap    map[float64]*subscribeToChannel
+	subscribes []*subscribeToChannel
+	// data frames received before their chanId was linked
+	pending map[float64][]dataFrame
+	// subscriptions waiting for their unsubscribed event to subscribe again
+	resubscribing map[float64]*subscribeToChannel
+	// subscriptions left to send under MaxInFlightSubscribes and the
+	// number of subscribe messages sent for the unconfirmed ones, with the
+	// round of sendSubscribeMessages they belong to, guarded by mu
+	queued   []*subscribeToChannel
+	inflight map[*subscribeToChannel]int
+	subGen   int
+	// chanIds replaced by a new subscribed event on this connection, whose
+	// late frames are dropped
+	retired map[float64]bool
+	// subscriptions removed while their subscribe message was in flight,
+	// unsubscribed once their subscribed event arrives, guarded by mu
+	abandoned []*subscribeToChannel
+	// delivering is set while Subscribe runs, releasing holds the owned
+	// channels it is left to close, guarded by mu
+	delivering bool
+	releasing  []*subscribeToChannel
 }
 
 type SubscribeMsg struct {
-	Event   string  `json:"event"`
-	Channel string  `json:"channel"`
-	Pair    string  `json:"pair"`
-	Len     string  `json:"len"`
-	ChanId  float64 `json:"chanId,omitempty"`
+	Event   string `json:"event"`
+	Channel string `json:"channel"`
+	Pair    string `json:"pair,omitempty"`
+	// Symbol replaces Pair with PROTOCOL_V2, e.g. tBTCUSD
+	Symbol string `json:"symbol,omitempty"`
+	Len    string `json:"len,omitempty"`
+	// Prec is the book precision, e.g. P1
+	Prec string `json:"prec,omitempty"`
+	// Key identifies the channels subscribed by key rather than pair, e.g.
+	// candles
+	Key    string  `json:"key,omitempty"`
+	ChanId float64 `json:"chanId,omitempty"`
 }
 
 type subscribeToChannel struct {
+	// lastFrame is the UnixNano time of the last frame dispatched, accessed
+	// atomically. First for its 64-bit alignment.
+	lastFrame int64
+
 	Channel string
 	Pair    string
 	Len     int
 	Chan    chan [][]float64
+	// Key is sent instead of Pair and Len when set.
+	Key string
+	// Priority orders the subscribe messages, highest first.
+	Priority int
+	// Prec is the book precision, the channel default when empty.
+	Prec BookPrecision
+	// deliver replaces the raw Chan for typed subscriptions.
+	deliver func(f dataFrame)
+	// reset is called after a reconnect, before the new snapshot arrives.
+	reset func()
+	// removed is called by remove, with w.mu held.
+	removed func()
+	// chanId is the id Bitfinex assigned when it confirmed the subscription.
+	chanId float64
+	// out is the consumer channel, closed with CloseChannelsOnError.
+	out interface{}
+	// blocked is set while out was last seen full.
+	blocked bool
+	// owned is set when the service created out and closes it, released
+	// once the subscription is removed, guarded by mu. The channel is closed
+	// by the goroutine delivering to it, see release.
+	owned    bool
+	released bool
+}
+
+// dataFrame is a channel data message with the chanId and any term removed.
+type dataFrame struct {
+	// Snapshot is set for the initial state sent after subscribing.
+	Snapshot bool
+	// Term is the trades channel message type (te or tu), empty otherwise.
+	Term string
+	// Rows holds every entry of a snapshot, or the single updated entry.
+	Rows [][]float64
+	// Items holds the rows as decoded when some of their fields are not
+	// numbers, which Rows holds as zeros.
+	Items [][]interface{}
+	// ServerTime is the time the server sent the frame, with CONF_TIMESTAMP.
+	ServerTime time.Time
+}
+
+// confirmedBy reports whether a subscribed event is the one of s.
+func (s *subscribeToChannel) confirmedBy(event *SubscribeMsg) bool {
+	if event.Channel != s.Channel {
+		return false
+	}
+	if s.Key != "" {
+		return event.Key == s.Key
+	}
+	if event.Pair == "" && event.Symbol != "" {
+		return NormalizeSymbol(event.Symbol, SYMBOL_WEBSOCKET) == s.Pair
+	}
+	return event.Pair == s.Pair
+}
+
+func (s *subscribeToChannel) send(f dataFrame) {
+	if s.deliver != nil {
+		s.deliver(f)
+		return
+	}
+	s.Chan <- rawRows(f)
+}
+
+// SnapshotReset is the first row of every snapshot delivered to raw
+// subscriptions, telling the receiver that the entire book follows and the
+// old one should be discarded. Detect it with IsSnapshotReset.
+var SnapshotReset = []float64{0, 0, 0}
+
+// IsSnapshotReset reports whether row is the SnapshotReset sentinel. No
+// real row is all zeros: books and trades have a price, tickers more
+// fields.
+func IsSnapshotReset(row []float64) bool {
+	return len(row) == 3 && row[0] == 0 && row[1] == 0 && row[2] == 0
+}
+
+// rawRows returns the rows of f delivered to raw subscriptions.
+func rawRows(f dataFrame) [][]float64 {
+	if f.Snapshot {
+		// a copy, so that a receiver changing it doesn't affect the others
+		reset := append([]float64(nil), SnapshotReset...)
+		return append([][]float64{reset}, f.Rows...)
+	}
+	return f.Rows
 }
 
 func NewWebSocketService(c *Client) *WebSocketService {
 	return &WebSocketService{
-		client:     c,
-		chanMap:    make(map[float64]chan [][]float64),
-		subscribes: make([]subscribeToChannel, 0),
+		MaxMessageSize:    DefaultMaxMessageSize,
+		ReconnectInterval: DefaultReconnectInterval,
+		client:            c,
+		chanMap:           make(map[float64]*subscribeToChannel),
+		pending:           make(map[float64][]dataFrame),
+		resubscribing:     make(map[float64]*subscribeToChannel),
+		retired:           make(map[float64]bool),
+		inflight:          make(map[*subscribeToChannel]int),
+		subscribes:        make([]*subscribeToChannel, 0),
 	}
 }
 
-// Connect create new bitfinex websocket connection
+// Connect create new bitfinex websocket connection. With AutoReconnect and
+// MaxReconnectAttempts set, failed attempts are retried the way reconnects
+// are: every ReconnectInterval, as long as the error is retryable, up to
+// MaxReconnectAttempts attempts in all. Otherwise it fails on the first
+// error, as ConnectOnce does.
 func (w *WebSocketService) Connect() error {
+	return w.ConnectContext(context.Background())
+}
+
+// ConnectContext is like Connect, giving up once ctx is done. With
+// AutoReconnect and no MaxReconnectAttempts, the attempts are only bounded
+// by ctx: they go on until ctx is done, or fail on the first error when ctx
+// is never done.
+func (w *WebSocketService) ConnectContext(ctx context.Context) error {
+	for attempt := 1; ; attempt++ {
+		err := w.connect(ctx)
+		if cerr := contextErr(ctx); err != nil && w.AutoReconnect && cerr != nil {
+			return &ReconnectError{Attempts: attempt, Err: joinErrors(cerr, err)}
+		}
+		if err == nil || !w.AutoReconnect || !w.retryable(err) {
+			return err
+		}
+		if w.MaxReconnectAttempts <= 0 && ctx.Done() == nil {
+			return err
+		}
+		if w.MaxReconnectAttempts > 0 && attempt >= w.MaxReconnectAttempts {
+			return &ReconnectError{Attempts: attempt, Err: err}
+		}
+		log.Println("Error connecting to websocket", err)
+		select {
+		case <-ctx.Done():
+			return &ReconnectError{Attempts: attempt, Err: joinErrors(ctx.Err(), err)}
+		case <-time.After(w.ReconnectInterval):
+		}
+	}
+}
+
+// contextErr is ctx.Err(), reporting a deadline as soon as it passed: a
+// dial bound to ctx may time out before ctx.Err() is set.
+func contextErr(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
+// ConnectOnce is like Connect without retries, returning the error of the
+// first attempt.
+func (w *WebSocketService) ConnectOnce() error {
+	return w.connect(context.Background())
+}
+
+func (w *WebSocketService) connect(ctx context.Context) error {
+	ws, batch, err := w.dialContext(ctx)
+	if err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.ws = ws
+	w.batch = batch
+	w.closed = false
+	w.stopped = false
+	w.connected = true
+	w.mu.Unlock()
+	return nil
+}
+
+// dial opens a websocket connection using the service settings. Writes go
+// through the returned batchConn.
+func (w *WebSocketService) dial() (*websocket.Conn, *batchConn, error) {
+	return w.dialContext(context.Background())
+}
+
+func (w *WebSocketService) dialContext(ctx context.Context) (*websocket.Conn, *batchConn, error) {
+	var batch *batchConn
 	var d = websocket.Dialer{
-		Subprotocols:     []string{"p1", "p2"},
+		Subprotocols:     w.Subprotocols,
 		ReadBufferSize:   1024,
 		WriteBufferSize:  1024,
 		Proxy:            http.ProxyFromEnvironment,
 		HandshakeTimeout: 3 * time.Second,
+		NetDialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := (&net.Dialer{}).DialContext(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			batch = &batchConn{Conn: conn}
+			return batch, nil
+		},
+	}
+
+	if w.Proxy != nil {
+		d.Proxy = w.Proxy
 	}
 
-	if w.client.WebSocketTLSSkipVerify {
+	if w.TLSConfig != nil {
+		d.TLSClientConfig = w.TLSConfig
+	} else if w.client.WebSocketTLSSkipVerify {
 		d.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
 	}
 
-	ws, _, err := d.Dial(w.client.WebSocketURL, nil)
+	ws, resp, err := d.DialContext(ctx, w.client.WebSocketURL, nil)
+	if err != nil && resp != nil {
+		herr := newHandshakeError(resp, err)
+		herr.Body = w.client.redact(herr.Body)
+		err = herr
+	}
+	if resp != nil && w.DebugHandshake != nil {
+		w.DebugHandshake(resp)
+	}
 	if err != nil {
-		return err
+		return nil, nil, err
 	}
-	w.ws = ws
-	return nil
+	if w.MaxMessageSize > 0 {
+		ws.SetReadLimit(w.MaxMessageSize)
+	}
+	return ws, batch, nil
+}
+
+// HandshakeError is returned when the server rejects the websocket
+// handshake. It carries the server response, which usually explains
+// the rejection (invalid key, rate limited, ...).
+type HandshakeError struct {
+	Response *http.Response
+	// Body is the beginning of the response body
+	Body string
+	Err  error
+}
+
+func newHandshakeError(resp *http.Response, err error) *HandshakeError {
+	var body []byte
+	if resp.Body != nil {
+		body, _ = ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		// keep the body readable for DebugHandshake
+		resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	return &HandshakeError{Response: resp, Body: string(body), Err: err}
 }
 
-// Close web socket connection
+func (e *HandshakeError) Error() string {
+	return fmt.Sprintf("%v: %d %v", e.Err, e.Response.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// closeTimeout bounds the wait for the server to acknowledge a close frame.
+const closeTimeout = time.Second
+
+// Close web socket connection. A close frame is sent first, after the
+// unsubscribe messages still queued, and, while Subscribe is reading, the
+// server's acknowledgment is awaited for up to a second before the
+// connection is closed.
 func (w *WebSocketService) Close() {
+	w.mu.Lock()
+	w.closed = true
+	w.connected = false
+	ws, readDone, commands := w.ws, w.readDone, w.commands
+	w.mu.Unlock()
+	if ws == nil {
+		return
+	}
+
+	// queued while Subscribe runs, so that e.g. an Unsubscribe just before
+	// Close goes out first
+	sent, err := w.queueClose(commands, readDone)
+	if !sent {
+		err = writeClose(ws)
+	}
+	if err == nil && readDone != nil {
+		select {
+		case <-readDone:
+		case <-time.After(closeTimeout):
+		}
+	}
+	ws.Close()
+}
+
+func (w *WebSocketService) isClosed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closed
+}
+
+// ReconnectError is returned once MaxReconnectAttempts attempts to
+// reconnect failed in a row.
+type ReconnectError struct {
+	Attempts int
+	// Err is the error of the last attempt
+	Err error
+}
+
+func (e *ReconnectError) Error() string {
+	return fmt.Sprintf("giving up after %d reconnect attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *ReconnectError) Unwrap() error {
+	return e.Err
+}
+
+// isRetryable is the default IsRetryable.
+func isRetryable(err error) bool {
+	switch e := err.(type) {
+	case nil, *DecodeError:
+		return false
+	case *HandshakeError:
+		// the server answered, only retry when it may answer differently
+		code := e.Response.StatusCode
+		return code == http.StatusTooManyRequests || code >= 500
+	}
+	if errors.Is(err, errPrivateAuth) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	return !websocket.IsCloseError(err, websocket.CloseNormalClosure)
+}
+
+func (w *WebSocketService) retryable(err error) bool {
+	if w.IsRetryable != nil {
+		return w.IsRetryable(err)
+	}
+	return isRetryable(err)
+}
+
+// errReconnectClosed stops reconnecting when the connection is closed.
+var errReconnectClosed = errors.New("connection closed")
+
+// reconnect replaces the broken connection, retrying every
+// ReconnectInterval until it succeeds, Close is called or
+// MaxReconnectAttempts is reached, and prepares the subscriptions to be
+// replayed.
+func (w *WebSocketService) reconnect(ctx context.Context) error {
+	w.mu.Lock()
+	w.connected = false
+	w.mu.Unlock()
 	w.ws.Close()
+	for attempt := 1; !w.isClosed(); attempt++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.ReconnectInterval):
+		}
+		if w.isClosed() {
+			break
+		}
+		ws, batch, err := w.dial()
+		if err != nil {
+			if !w.retryable(err) || (w.MaxReconnectAttempts > 0 && attempt >= w.MaxReconnectAttempts) {
+				return &ReconnectError{Attempts: attempt, Err: err}
+			}
+			log.Println("Error reconnecting to websocket", err)
+			continue
+		}
+
+		w.mu.Lock()
+		if w.closed {
+			w.mu.Unlock()
+			ws.Close()
+			return errReconnectClosed
+		}
+		w.ws = ws
+		w.batch = batch
+		w.connected = true
+		// chanIds are assigned again when the subscriptions are replayed
+		w.chanMap = make(map[float64]*subscribeToChannel)
+		w.pending = make(map[float64][]dataFrame)
+		w.resubscribing = make(map[float64]*subscribeToChannel)
+		w.retired = make(map[float64]bool)
+		w.abandoned = nil
+		w.mu.Unlock()
+		atomic.AddInt64(&w.reconnects, 1)
+
+		w.beforeResubscribe()
+		for _, s := range w.subscriptions() {
+			w.resetSub(s)
+		}
+		return nil
+	}
+	return errReconnectClosed
 }
 
-func (w *WebSocketService) AddSubscribe(channel string, pair string, length int, c chan [][]float64) {
-	s := subscribeToChannel{
+// SubscriptionInfo describes a subscription for OnBeforeResubscribe.
+type SubscriptionInfo struct {
+	Channel string
+	Pair    string
+	Len     int
+	// Chan receives the raw frames. It is nil for typed subscriptions.
+	Chan chan [][]float64
+	// Priority is set with SetPriority
+	Priority int
+
+	sub *subscribeToChannel
+}
+
+// beforeResubscribe replaces the subscriptions with the ones returned by
+// OnBeforeResubscribe.
+func (w *WebSocketService) beforeResubscribe() {
+	if w.OnBeforeResubscribe == nil {
+		return
+	}
+	subscribes := w.subscriptions()
+	current := make([]SubscriptionInfo, len(subscribes))
+	for i, s := range subscribes {
+		current[i] = SubscriptionInfo{Channel: s.Channel, Pair: s.Pair, Len: s.Len, Chan: s.Chan, Priority: s.Priority, sub: s}
+	}
+
+	w.ClearSubscriptions()
+	for _, info := range w.OnBeforeResubscribe(current) {
+		s := info.sub
+		if s == nil {
+			if info.Chan == nil {
+				log.Println("Ignoring subscription without a channel", info.Channel, info.Pair)
+				continue
+			}
+			s = &subscribeToChannel{Channel: info.Channel, Pair: info.Pair, Chan: info.Chan, out: info.Chan}
+		}
+		s.Len = info.Len
+		s.Priority = info.Priority
+		if err := w.addSubscribe(s); err != nil {
+			log.Println("Ignoring subscription", info.Channel, info.Pair, err)
+		}
+	}
+}
+
+// ErrAlreadySubscribed is returned when adding a subscription for a
+// channel and pair that already has one. Bitfinex only allows a single
+// subscription per channel and pair on a connection.
+var ErrAlreadySubscribed = errors.New("already subscribed to this channel and pair")
+
+// AddSubscribe adds a subscription delivering raw frames to c once
+// Subscribe is called. Like the typed Subscribe* methods it returns
+// ErrAlreadySubscribed for a channel and pair added before. Pass
+// ChannelDefaultLen as length when the channel default will do.
+//
+// It can be called from any goroutine, also while Subscribe runs: the
+// subscribe message is then sent by the read loop, see CommandQueueSize.
+func (w *WebSocketService) AddSubscribe(channel string, pair string, length int, c chan [][]float64) error {
+	return w.addSubscribe(&subscribeToChannel{
 		Channel: channel,
 		Pair:    pair,
 		Chan:    c,
 		Len:     length,
+		out:     c,
+	})
+}
+
+// AddSubscribeFunc is like AddSubscribe, calling fn with the raw frames
+// instead of sending them to a channel. fn runs on the read goroutine:
+// it must return quickly and never block, as no other frame of the
+// connection is handled meanwhile. The typed Subscribe*Func variants have
+// the same contract.
+func (w *WebSocketService) AddSubscribeFunc(channel string, pair string, length int, fn func([][]float64)) error {
+	return w.addSubscribe(&subscribeToChannel{
+		Channel: channel,
+		Pair:    pair,
+		Len:     length,
+		deliver: func(f dataFrame) {
+			fn(rawRows(f))
+		},
+	})
+}
+
+func (w *WebSocketService) addSubscribe(s *subscribeToChannel) error {
+	if s.Pair != "" {
+		s.Pair = NormalizeSymbol(s.Pair, SYMBOL_WEBSOCKET)
+	}
+	w.mu.Lock()
+	for _, k := range w.subscribes {
+		if k.Channel == s.Channel && k.Pair == s.Pair && k.Key == s.Key {
+			w.mu.Unlock()
+			return ErrAlreadySubscribed
+		}
 	}
 	w.subscribes = append(w.subscribes, s)
+	queue, done := w.commands, w.readDone
+	w.mu.Unlock()
+
+	if queue == nil {
+		// sent with the others once Subscribe runs
+		return nil
+	}
+	return w.enqueue(queue, done, wsCommand{subscribe: s})
 }
 
-func (w *WebSocketService) ClearSubscriptions() {
-	w.subscribes = make([]subscribeToChannel, 0)
+// subscriptions returns the current subscriptions. The slice is replaced
+// rather than modified when one is removed, so it can be iterated once
+// w.mu is released.
+func (w *WebSocketService) subscriptions() []*subscribeToChannel {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.subscribes
 }
 
-func (w *WebSocketService) sendSubscribeMessages() error {
+// ErrNotSubscribed is returned for a channel and pair without a
+// subscription.
+var ErrNotSubscribed = errors.New("not subscribed to this channel and pair")
+
+// lookup returns the subscription of channel and key, or of channel and
+// pair among those without a key when key is empty, nil without one. w.mu
+// must be held.
+func (w *WebSocketService) lookup(channel, pair, key string) *subscribeToChannel {
 	for _, s := range w.subscribes {
-		msg, _ := json.Marshal(SubscribeMsg{
-			Event:   "subscribe",
-			Channel: s.Channel,
-			Pair:    s.Pair,
-			Len:     strconv.Itoa(s.Len),
-		})
-		err := w.ws.WriteMessage(websocket.TextMessage, msg)
+		if s.Channel == channel && s.Key == key && (key != "" || s.Pair == pair) {
+			return s
+		}
+	}
+	return nil
+}
+
+// SetPriority sets the priority of the subscription of channel and pair.
+// Subscriptions are sent in descending priority, then in the order they
+// were added, both by Subscribe and after a reconnect, so that e.g. the
+// book of the main pair gets its fresh snapshot first. The default is 0.
+// The channels subscribed by key, e.g. candles, use SetPriorityKey.
+func (w *WebSocketService) SetPriority(channel, pair string, priority int) error {
+	return w.setPriority(channel, NormalizeSymbol(pair, SYMBOL_WEBSOCKET), "", priority)
+}
+
+// SetPriorityKey is SetPriority for the subscription of channel and key,
+// e.g. CHAN_CANDLES and CandleKey(BTCUSD, "1m").
+func (w *WebSocketService) SetPriorityKey(channel, key string, priority int) error {
+	return w.setPriority(channel, "", key, priority)
+}
+
+func (w *WebSocketService) setPriority(channel, pair, key string, priority int) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.lookup(channel, pair, key)
+	if s == nil {
+		return ErrNotSubscribed
+	}
+	s.Priority = priority
+	return nil
+}
+
+// Unsubscribe removes the subscription of channel and pair, unsubscribing
+// its channel when it is linked on the current connection, or once its
+// subscribed event arrives when its subscribe message is in flight. Frames
+// still arriving for it are dropped. While Subscribe runs the unsubscribe message
+// is sent by the read loop, see CommandQueueSize, and Unsubscribe returns
+// once it is queued. The channels subscribed by key, e.g. candles, are
+// removed with UnsubscribeKey.
+func (w *WebSocketService) Unsubscribe(channel, pair string) error {
+	return w.unsubscribe(channel, NormalizeSymbol(pair, SYMBOL_WEBSOCKET), "")
+}
+
+// UnsubscribeKey is Unsubscribe for the subscription of channel and key,
+// e.g. CHAN_CANDLES and CandleKey(BTCUSD, "1m"), leaving the other
+// timeframes of the pair subscribed.
+func (w *WebSocketService) UnsubscribeKey(channel, key string) error {
+	return w.unsubscribe(channel, "", key)
+}
+
+func (w *WebSocketService) unsubscribe(channel, pair, key string) error {
+	w.mu.Lock()
+	s := w.lookup(channel, pair, key)
+	if s == nil {
+		w.mu.Unlock()
+		return ErrNotSubscribed
+	}
+	linked := w.chanMap[s.chanId] == s
+	chanId := s.chanId
+	w.abandon(s)
+	w.remove(s)
+	queue, done := w.commands, w.readDone
+	w.mu.Unlock()
+
+	if !linked {
+		return nil
+	}
+	return w.enqueue(queue, done, wsCommand{unsubscribe: chanId})
+}
+
+// ClearSubscriptions removes every subscription. The channels linked on the
+// current connection are forgotten as well, their frames are dropped rather
+// than delivered, and no queued subscription is sent anymore.
+func (w *WebSocketService) ClearSubscriptions() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, s := range w.subscribes {
+		w.abandon(s)
+		w.release(s)
+		if s.removed != nil {
+			s.removed()
+		}
+	}
+	w.subscribes = make([]*subscribeToChannel, 0)
+	for chanId := range w.chanMap {
+		w.retire(chanId)
+	}
+	w.chanMap = make(map[float64]*subscribeToChannel)
+	w.pending = make(map[float64][]dataFrame)
+	// stops the pending acknowledgment timeouts
+	w.subGen++
+	w.inflight = make(map[*subscribeToChannel]int)
+	w.queued = nil
+}
+
+// sendSubscribeMessages sends the subscribe messages of subscribes, a copy
+// of the subscriptions taken under w.mu.
+func (w *WebSocketService) sendSubscribeMessages(subscribes []*subscribeToChannel) error {
+	sort.SliceStable(subscribes, func(i, j int) bool {
+		return subscribes[i].Priority > subscribes[j].Priority
+	})
+	subscribes = w.sendWindow(subscribes)
+	// the frames go out in a single write instead of one per subscription
+	w.mu.Lock()
+	batch := w.batch
+	w.mu.Unlock()
+	batch.begin()
+	var errs []error
+	buffered := make([]*subscribeToChannel, 0, len(subscribes))
+	for _, s := range subscribes {
+		if err := w.sendSubscribe(s); err != nil {
+			// Can't send message to web socket, try the others anyway.
+			errs = append(errs, &SubscribeSendError{Channel: s.Channel, Pair: s.Pair, Key: s.Key, Err: err})
+			continue
+		}
+		buffered = append(buffered, s)
+		w.sent(s)
+	}
+	if err := batch.flush(); err != nil {
+		for _, s := range buffered {
+			errs = append(errs, &SubscribeSendError{Channel: s.Channel, Pair: s.Pair, Key: s.Key, Err: err})
+		}
+	}
+	return joinErrors(errs...)
+}
+
+// joinedError holds several errors, like errors.Join does from Go 1.20.
+// Is and As look through them on earlier versions as well.
+type joinedError []error
+
+// joinErrors returns the non nil errs as one error, nil if there is none.
+func joinErrors(errs ...error) error {
+	var joined joinedError
+	for _, err := range errs {
+		if err != nil {
+			joined = append(joined, err)
+		}
+	}
+	if len(joined) == 0 {
+		return nil
+	}
+	return joined
+}
+
+func (e joinedError) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e joinedError) Unwrap() []error {
+	return e
+}
+
+func (e joinedError) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e joinedError) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscribeSendError reports a subscribe message that could not be sent.
+// Subscribe returns one for every failed subscription, joined into one
+// error; errors.As finds the first one.
+type SubscribeSendError struct {
+	Channel string
+	Pair    string
+	Key     string
+	Err     error
+}
+
+func (e *SubscribeSendError) Error() string {
+	name := e.Pair
+	if e.Key != "" {
+		name = e.Key
+	}
+	return fmt.Sprintf("subscribe %s %s: %v", e.Channel, name, e.Err)
+}
+
+func (e *SubscribeSendError) Unwrap() error {
+	return e.Err
+}
+
+func (w *WebSocketService) sendSubscribe(s *subscribeToChannel) error {
+	sub := SubscribeMsg{Event: "subscribe", Channel: s.Channel, Key: s.Key}
+	if s.Key == "" {
+		if w.Protocol() == PROTOCOL_V2 {
+			sub.Symbol = NormalizeSymbol(s.Pair, SYMBOL_V2)
+		} else {
+			sub.Pair = s.Pair
+		}
+		if length, ok := s.length(); ok {
+			sub.Len = strconv.Itoa(length)
+		}
+		prec, err := precision(s.Prec, w.Protocol())
 		if err != nil {
-			// Can't send message to web socket.
 			return err
 		}
+		sub.Prec = string(prec)
 	}
-	return nil
+	msg, _ := json.Marshal(sub)
+	return w.write(msg)
+}
+
+// length returns the length sent for s, resolving ChannelDefaultLen.
+func (s *subscribeToChannel) length() (int, bool) {
+	if s.Len != ChannelDefaultLen {
+		return s.Len, true
+	}
+	if s.Channel == CHAN_BOOK {
+		return DefaultBookLen, true
+	}
+	return 0, false
+}
+
+// write sends a text frame on the public connection.
+func (w *WebSocketService) write(msg []byte) error {
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+	w.mu.Lock()
+	ws := w.ws
+	w.mu.Unlock()
+	if w.OnSendFrame != nil {
+		w.OnSendFrame(msg)
+	}
+	return ws.WriteMessage(websocket.TextMessage, msg)
 }
 
 // Watch allows to subsribe to channels and watch for new updates.
 // This method supports next channels: book, trade, ticker.
+// With AutoReconnect set it only returns once Close is called.
+//
+// Bitfinex maintenance info events are handled as documented: on
+// INFO_MAINTENANCE_START dispatch is paused until INFO_MAINTENANCE_END,
+// and on INFO_RECONNECT or INFO_MAINTENANCE_END the service reconnects
+// and resubscribes, whether or not AutoReconnect is set.
 func (w *WebSocketService) Subscribe() error {
-	// Subscribe to each channel
-	if err := w.sendSubscribeMessages(); err != nil {
+	return w.SubscribeContext(context.Background())
+}
+
+// SubscribeContext is like Subscribe, returning ctx.Err() once ctx is done,
+// also while reconnecting. The connection is closed then, as with Close.
+func (w *WebSocketService) SubscribeContext(ctx context.Context) error {
+	w.mu.Lock()
+	w.delivering = true
+	w.mu.Unlock()
+	defer w.releaseOwned()
+	defer w.stopDelivering()
+	for {
+		err := w.subscribe(ctx)
+		if cerr := ctx.Err(); cerr != nil {
+			return w.cancelled(cerr)
+		}
+		if w.isStopped() {
+			err = ErrStopped
+			if w.CloseChannelsOnError {
+				w.closeChannels()
+			}
+			return err
+		}
+		w.setLastErr(err)
+		if err == errInfoReconnect || (w.AutoReconnect && w.retryable(err)) {
+			rerr := w.reconnect(ctx)
+			if rerr == nil {
+				continue
+			}
+			if cerr := ctx.Err(); cerr != nil {
+				return w.cancelled(cerr)
+			}
+			if w.isStopped() {
+				err = ErrStopped
+			} else if rerr != errReconnectClosed {
+				err = rerr
+				w.setLastErr(err)
+			}
+		}
+		if w.CloseChannelsOnError {
+			w.closeChannels()
+		}
 		return err
 	}
+}
+
+// cancelled closes the connection once the context of SubscribeContext is
+// done and returns err.
+func (w *WebSocketService) cancelled(err error) error {
+	w.Close()
+	if w.CloseChannelsOnError {
+		w.closeChannels()
+	}
+	return err
+}
 
-	var msg string
+// ErrStopped is returned by Subscribe once the stop function returned by
+// SubscribeWithStop is called.
+var ErrStopped = errors.New("subscribe loop stopped")
 
-	for {
-		_, p, err := w.ws.ReadMessage()
-		msg = string(p)
-		if err != nil {
+// SubscribeWithStop runs Subscribe in a new goroutine. Calling stop closes
+// the connection, making Subscribe return ErrStopped rather than the read
+// error, which done receives. stop can be called any number of times and
+// does not wait for the loop to return.
+func (w *WebSocketService) SubscribeWithStop() (stop func(), done <-chan error) {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- w.Subscribe()
+	}()
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			w.mu.Lock()
+			w.stopped = true
+			w.mu.Unlock()
+			w.Close()
+		})
+	}
+	return stop, errc
+}
+
+func (w *WebSocketService) isStopped() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stopped
+}
+
+// closeChannels closes the consumer channels and drops the subscriptions.
+func (w *WebSocketService) closeChannels() {
+	closed := make(map[interface{}]bool)
+	for _, s := range w.subscriptions() {
+		// owned channels are closed by ClearSubscriptions
+		if s.out == nil || s.owned || closed[s.out] {
+			continue
+		}
+		closed[s.out] = true
+		reflect.ValueOf(s.out).Close()
+	}
+	w.ClearSubscriptions()
+	w.mu.Lock()
+	w.chanMap = make(map[float64]*subscribeToChannel)
+	w.pending = make(map[float64][]dataFrame)
+	w.resubscribing = make(map[float64]*subscribeToChannel)
+	w.retired = make(map[float64]bool)
+	w.abandoned = nil
+	w.mu.Unlock()
+}
+
+func (w *WebSocketService) subscribe(ctx context.Context) error {
+	w.seq = 0
+	size := w.CommandQueueSize
+	if size <= 0 {
+		size = DefaultCommandQueueSize
+	}
+	readDone := make(chan struct{})
+	commands := make(chan wsCommand, size)
+	defer func() {
+		w.mu.Lock()
+		w.commands = nil
+		w.mu.Unlock()
+		close(readDone)
+		// left by callers that took the queue before it was removed
+		w.flushCommands(commands)
+	}()
+	// the subscriptions added from now on are queued
+	w.mu.Lock()
+	w.readDone = readDone
+	w.commands = commands
+	subscribes := append([]*subscribeToChannel(nil), w.subscribes...)
+	w.mu.Unlock()
+
+	if w.confFlags() != 0 {
+		if err := w.sendConf(); err != nil {
 			return err
 		}
-		if strings.Contains(msg, "event") {
-			w.handleEventMessage(msg)
-		} else {
-			w.handleDataMessage(msg)
+	}
+	// Subscribe to each channel
+	if err := w.sendSubscribeMessages(subscribes); err != nil {
+		return err
+	}
+
+	r := w.reader()
+	idle := newIdleTimer(w.IdleTimeout)
+	defer idle.stop()
+	for {
+		w.closeReleased()
+		var frame wsFrame
+		select {
+		case f, ok := <-r.frames:
+			if !ok {
+				w.disconnected(false, r.err)
+				return r.err
+			}
+			frame = f
+		case c := <-commands:
+			w.runQueued(c)
+			continue
+		case <-idle.C:
+			w.ws.Close()
+			w.disconnected(false, ErrIdleTimeout)
+			return ErrIdleTimeout
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		idle.reset(w.IdleTimeout)
+		if skip, err := w.skipFrame(frame.mt, frame.p); skip {
+			if err != nil {
+				return err
+			}
+			continue
 		}
+		if err := w.handleMessage(frame.p); err != nil {
+			return err
+		}
+	}
+}
+
+// handleMessage dispatches a frame of the public connection.
+func (w *WebSocketService) handleMessage(p []byte) error {
+	if bytes.Contains(p, eventKey) {
+		return w.handleEventMessage(p)
+	}
+	return w.handleDataMessage(p)
+}
+
+var eventKey = []byte("event")
+
+// skipFrame reports whether a frame is dropped before decoding: Bitfinex
+// only sends JSON text, so binary or empty frames come from a proxy or a
+// broken peer. They are logged, or fail with StrictDecoding.
+func (w *WebSocketService) skipFrame(messageType int, p []byte) (bool, error) {
+	var reason string
+	switch {
+	case messageType != websocket.TextMessage:
+		reason = fmt.Sprintf("binary message of %d bytes", len(p))
+	case len(bytes.TrimSpace(p)) == 0:
+		reason = "empty message"
+	default:
+		return false, nil
+	}
+	if w.StrictDecoding {
+		return true, &DecodeError{Path: "frame", Err: reason}
+	}
+	log.Println("Skipping frame:", reason)
+	return true, nil
+}
+
+// unmarshal decodes a frame with Unmarshal, or encoding/json by default.
+func (w *WebSocketService) unmarshal(data []byte, v interface{}) error {
+	if w.Unmarshal != nil {
+		return w.Unmarshal(data, v)
 	}
+	return json.Unmarshal(data, v)
+}
+
+// Info event codes
+const (
+	// The server asks clients to reconnect
+	INFO_RECONNECT = 20051
+	// Maintenance started, data is paused
+	INFO_MAINTENANCE_START = 20060
+	// Maintenance ended, clients should resubscribe
+	INFO_MAINTENANCE_END = 20061
+)
 
+// errInfoReconnect ends the read loop when the server asks to reconnect.
+var errInfoReconnect = errors.New("server requested reconnect")
+
+type infoMsg struct {
+	Event string `json:"event"`
+	Code  int    `json:"code"`
+	Msg   string `json:"msg"`
+}
+
+// InMaintenance reports whether Bitfinex announced a maintenance that has
+// not ended yet. Data is not dispatched during maintenance.
+func (w *WebSocketService) InMaintenance() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.maintenance
+}
+
+func (w *WebSocketService) setMaintenance(active bool) {
+	w.mu.Lock()
+	w.maintenance = active
+	w.mu.Unlock()
+	if w.OnMaintenance != nil {
+		w.OnMaintenance(active)
+	}
+}
+
+// handleInfo reacts to maintenance info codes.
+func (w *WebSocketService) handleInfo(info *infoMsg) error {
+	switch info.Code {
+	case INFO_RECONNECT:
+		return errInfoReconnect
+	case INFO_MAINTENANCE_START:
+		w.setMaintenance(true)
+	case INFO_MAINTENANCE_END:
+		w.setMaintenance(false)
+		return errInfoReconnect
+	}
 	return nil
 }
 
-func (w *WebSocketService) handleEventMessage(msg string) {
+func (w *WebSocketService) handleEventMessage(msg []byte) error {
+	info := &infoMsg{}
+	if err := w.unmarshal(msg, info); err == nil {
+		switch info.Event {
+		case "info":
+			return w.handleInfo(info)
+		case "conf":
+			return handleConfEvent(msg, w.unmarshal)
+		case "unsubscribed":
+			return w.handleUnsubscribed(msg)
+		}
+	}
+
 	// Check for first message(event:subscribed)
 	event := &SubscribeMsg{}
-	err := json.Unmarshal([]byte(msg), &event)
+	err := w.unmarshal(msg, &event)
+
+	if err == nil && event.Event == "error" {
+		for _, k := range w.subscriptions() {
+			if k.confirmedBy(event) {
+				log.Println("Subscribing failed", k.Channel, k.Pair, string(msg))
+				w.acked(k)
+			}
+		}
+		w.takeAbandoned(event)
+		return nil
+	}
 
 	// Received "subscribed" resposne. Link channels.
-	if err == nil {
-		for _, k := range w.subscribes {
-			if event.Event == "subscribed" && event.Pair == k.Pair && event.Channel == k.Channel {
-				w.chanMap[event.ChanId] = k.Chan
+	if err == nil && event.Event == "subscribed" {
+		linked := false
+		for _, k := range w.subscriptions() {
+			if k.confirmedBy(event) {
+				linked = true
+				w.acked(k)
+				w.mu.Lock()
+				if w.chanMap[event.ChanId] == k {
+					// a duplicate of the event that linked k, relinking
+					// would flush nothing and could only disturb the map
+					w.mu.Unlock()
+					log.Println("Duplicate subscribed event", k.Channel, k.Pair, string(msg))
+					continue
+				}
+				if old := k.chanId; old != event.ChanId && w.chanMap[old] == k {
+					// subscribed again on this connection, e.g. after a
+					// sequence gap, late frames of the old chanId are stale
+					delete(w.chanMap, old)
+					w.retire(old)
+				}
+				delete(w.retired, event.ChanId)
+				k.chanId = event.ChanId
+				w.chanMap[event.ChanId] = k
+				w.mu.Unlock()
+				if err := w.flushPending(k); err != nil {
+					return err
+				}
 			}
 		}
+		if !linked && w.takeAbandoned(event) != nil {
+			return w.unsubscribeAbandoned(event.ChanId)
+		}
 	}
+	return nil
 }
 
-func (w *WebSocketService) handleDataMessage(msg string) {
+// abandon remembers s when it is removed before the subscribed event of
+// its subscribe message, so that the channel is unsubscribed once the
+// event arrives. w.mu must be held.
+func (w *WebSocketService) abandon(s *subscribeToChannel) {
+	if _, sent := w.inflight[s]; sent && w.chanMap[s.chanId] != s {
+		w.abandoned = append(w.abandoned, s)
+	}
+}
 
-	// Received payload or data update
-	var dataUpdate []float64
-	err := json.Unmarshal([]byte(msg), &dataUpdate)
-	if err == nil {
-		chanId := dataUpdate[0]
-		// Remove chanId from data update
-		// and send message to internal chan
-		w.chanMap[chanId] <- [][]float64{dataUpdate[1:]}
+// takeAbandoned returns and forgets the abandoned subscription event
+// confirms or refuses, if any.
+func (w *WebSocketService) takeAbandoned(event *SubscribeMsg) *subscribeToChannel {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for i, k := range w.abandoned {
+		if k.confirmedBy(event) {
+			w.abandoned = append(w.abandoned[:i:i], w.abandoned[i+1:]...)
+			return k
+		}
 	}
+	return nil
+}
 
-	// Payload received
-	var fullPayload []interface{}
-	err = json.Unmarshal([]byte(msg), &fullPayload)
+// unsubscribeAbandoned unsubscribes the chanId subscribed for an abandoned
+// subscription, dropping its frames meanwhile.
+func (w *WebSocketService) unsubscribeAbandoned(chanId float64) error {
+	w.mu.Lock()
+	w.retire(chanId)
+	w.mu.Unlock()
+	msg, _ := json.Marshal(unsubscribeMsg{Event: "unsubscribe", ChanId: chanId})
+	return w.write(msg)
+}
 
-	if err != nil {
-		log.Println("Error decoding fullPayload", err)
-	} else {
-		if len(fullPayload) > 3 {
-			itemsSlice := fullPayload[3:]
-			i, _ := json.Marshal(itemsSlice)
-			var item []float64
-			err = json.Unmarshal(i, &item)
-			if err == nil {
-				chanID := fullPayload[0].(float64)
-				w.chanMap[chanID] <- [][]float64{item}
+// retire drops the frames of a chanId no longer linked to its subscription,
+// whether already held or still to come.
+func (w *WebSocketService) retire(chanId float64) {
+	w.retired[chanId] = true
+	delete(w.pending, chanId)
+}
+
+// maxPendingFrames bounds the frames kept for a chanId that is not linked
+// to a subscription yet, maxPendingChanIds the chanIds they are kept for:
+// the frames of a chanId that is never linked are only dropped with the
+// connection.
+const (
+	maxPendingFrames  = 256
+	maxPendingChanIds = 64
+)
+
+// holdPending keeps a frame that arrived before the subscribed event
+// linking its chanId.
+func (w *WebSocketService) holdPending(chanId float64, f dataFrame) {
+	held, ok := w.pending[chanId]
+	if len(held) >= maxPendingFrames || (!ok && len(w.pending) >= maxPendingChanIds) {
+		return
+	}
+	w.pending[chanId] = append(w.pending[chanId], f)
+}
+
+// flushPending delivers the frames held for the chanId of s.
+func (w *WebSocketService) flushPending(s *subscribeToChannel) error {
+	w.mu.Lock()
+	frames := w.pending[s.chanId]
+	delete(w.pending, s.chanId)
+	w.mu.Unlock()
+	for _, f := range frames {
+		if err := w.dispatch(s, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *WebSocketService) handleDataMessage(msg []byte) error {
+	if w.InMaintenance() {
+		return nil
+	}
+	var (
+		chanId float64
+		f      dataFrame
+		ok     bool
+	)
+	if w.confFlags() == 0 {
+		chanId, f, ok = scanNumericFrame(msg)
+	}
+	if !ok {
+		var payload []interface{}
+		if err := w.unmarshal(msg, &payload); err != nil {
+			if w.StrictDecoding {
+				return &DecodeError{Path: "frame", Err: err.Error(), Raw: string(msg)}
+			}
+			log.Println("Error decoding fullPayload", err)
+			return nil
+		}
+		payload, err := w.trailer(payload, &f)
+		if gap, ok := err.(*SequenceGapError); ok {
+			// the message that revealed the gap belongs to the old snapshot
+			return w.resnapshot(gap)
+		}
+		if w.StrictDecoding && !w.isStatus(payload) {
+			if err := checkPayload(payload); err != nil {
+				err.(*DecodeError).Raw = string(msg)
+				return err
 			}
+		}
+		if len(payload) < 2 {
+			return nil
+		}
+		chanId, _ = payload[0].(float64)
+		serverTime := f.ServerTime
+		if f, ok = decodeDataFrame(payload[1:]); !ok {
+			return nil
+		}
+		f.ServerTime = serverTime
+	}
+	// locked against ClearSubscriptions
+	w.mu.Lock()
+	_, resubscribing := w.resubscribing[chanId]
+	stale := resubscribing || w.retired[chanId]
+	sub, ok := w.chanMap[chanId]
+	if !stale && !ok {
+		// the subscribed event may still be on its way
+		w.holdPending(chanId, f)
+	}
+	w.mu.Unlock()
+	if stale || !ok {
+		// stale data of a channel being resubscribed or cleared
+		return nil
+	}
+	if err := w.dispatch(sub, f); err != nil {
+		err.(*DecodeError).Raw = string(msg)
+		return err
+	}
+	return nil
+}
+
+// dispatch delivers a frame to its subscription.
+func (w *WebSocketService) dispatch(s *subscribeToChannel, f dataFrame) error {
+	if w.isReleased(s) {
+		return nil
+	}
+	if w.Protocol() == PROTOCOL_V2 {
+		f = v1Frame(s, f)
+	}
+	if w.StrictDecoding {
+		if err := checkFrame(s.Channel, s.Pair, f); err != nil {
+			return err
+		}
+	}
+	atomic.StoreInt64(&s.lastFrame, time.Now().UnixNano())
+	if w.CacheLastPrices {
+		w.recordPrice(s, f)
+	}
+	w.checkBacklog(s)
+	if !sendRecover(s, f) {
+		w.dropClosed(s)
+	}
+	return nil
+}
+
+// sendRecover sends f to s and reports false if the consumer closed its
+// channel.
+func sendRecover(s *subscribeToChannel, f dataFrame) (sent bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if err, ok := r.(error); !ok || err.Error() != "send on closed channel" {
+				panic(r)
+			}
+			sent = false
+		}
+	}()
+	s.send(f)
+	return true
+}
+
+// dropClosed unsubscribes a subscription whose consumer channel was closed,
+// so that it is not subscribed again after a reconnect either.
+func (w *WebSocketService) dropClosed(s *subscribeToChannel) {
+	w.mu.Lock()
+	removed := !w.isSubscribed(s)
+	chanId := s.chanId
+	w.mu.Unlock()
+	if removed {
+		// closed by the service while delivering
+		return
+	}
+	log.Println("Consumer channel is closed, unsubscribing", s.Channel, s.Pair, chanId)
+	msg, _ := json.Marshal(unsubscribeMsg{Event: "unsubscribe", ChanId: chanId})
+	if err := w.write(msg); err != nil {
+		log.Println("Error unsubscribing", s.Channel, s.Pair, err)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.remove(s)
+}
+
+// isSubscribed reports whether s is still one of the subscriptions. w.mu
+// must be held.
+func (w *WebSocketService) isSubscribed(s *subscribeToChannel) bool {
+	for _, k := range w.subscribes {
+		if k == s {
+			return true
+		}
+	}
+	return false
+}
+
+// remove forgets s and the chanId linked to it. The subscribes slice is
+// copied without s, so it shrinks and snapshots taken of it are
+// unaffected. w.mu must be held.
+func (w *WebSocketService) remove(s *subscribeToChannel) {
+	if w.chanMap[s.chanId] == s {
+		delete(w.chanMap, s.chanId)
+		w.retire(s.chanId)
+	}
+	for i, k := range w.subscribes {
+		if k == s {
+			w.subscribes = append(w.subscribes[:i:i], w.subscribes[i+1:]...)
+			break
+		}
+	}
+	w.release(s)
+	if s.removed != nil {
+		s.removed()
+	}
+	delete(w.inflight, s)
+	for i, k := range w.queued {
+		if k == s {
+			w.queued = append(w.queued[:i:i], w.queued[i+1:]...)
+			break
+		}
+	}
+}
+
+// checkBacklog reports a subscription whose buffered consumer channel is
+// full, so that the next send blocks the read loop. It reports once until
+// the consumer catches up.
+func (w *WebSocketService) checkBacklog(s *subscribeToChannel) {
+	if s.out == nil {
+		return
+	}
+	c := reflect.ValueOf(s.out)
+	full := c.Cap() > 0 && c.Len() == c.Cap()
+	if full && !s.blocked {
+		if w.OnBlockedSend != nil {
+			w.OnBlockedSend(s.chanId, c.Len())
 		} else {
-			itemsSlice := fullPayload[1]
-			i, _ := json.Marshal(itemsSlice)
-			var items [][]float64
-			err = json.Unmarshal(i, &items)
-			if err == nil {
-				chanId := fullPayload[0].(float64)
-				// we need to say the receiver, that we've got the entire book.
-				// normally, in this case it should reset the old book.
-				w.chanMap[chanId] <- append([][]float64{[]float64{0, 0, 0}}, items...)
+			log.Println("Consumer channel is full, the read loop blocks", s.Channel, s.Pair, s.chanId, c.Len())
+		}
+	}
+	s.blocked = full
+}
+
+// decodeDataFrame converts the payload following the chanId into a frame.
+// Heartbeats and payloads of unknown shape are reported as not ok.
+func decodeDataFrame(payload []interface{}) (dataFrame, bool) {
+	switch v := payload[0].(type) {
+	case []interface{}:
+		if len(v) > 0 {
+			if _, ok := v[0].(float64); ok {
+				// v2 update: [...]
+				return dataFrame{Rows: [][]float64{floatRow(v)}, Items: mixedRows([][]interface{}{v})}, true
+			}
+		}
+		// Snapshot: [[...], [...], ...]
+		rows := make([][]float64, 0, len(v))
+		items := make([][]interface{}, 0, len(v))
+		for _, item := range v {
+			row, ok := item.([]interface{})
+			if !ok {
+				return dataFrame{}, false
 			}
+			rows = append(rows, floatRow(row))
+			items = append(items, row)
 		}
+		return dataFrame{Snapshot: true, Rows: rows, Items: mixedRows(items)}, true
+	case string:
+		if len(payload) == 2 {
+			if row, ok := payload[1].([]interface{}); ok {
+				// v2 trades "te"/"tu": [...]
+				return dataFrame{Term: v, Rows: [][]float64{floatRow(row)}}, true
+			}
+		}
+		// Heartbeat "hb", or trades "te"/"tu" followed by a sequence id
+		if len(payload) < 3 {
+			return dataFrame{}, false
+		}
+		return dataFrame{Term: v, Rows: [][]float64{floatRow(payload[2:])}}, true
+	case float64:
+		// Book or ticker update
+		return dataFrame{Rows: [][]float64{floatRow(payload)}}, true
+	}
+	return dataFrame{}, false
+}
+
+// mixedRows returns rows if any of them has a field that isn't a number.
+func mixedRows(rows [][]interface{}) [][]interface{} {
+	for _, row := range rows {
+		for _, item := range row {
+			if _, ok := item.(float64); !ok {
+				return rows
+			}
+		}
+	}
+	return nil
+}
+
+// floatRow converts the numeric fields of a decoded array, leaving any
+// other value as zero.
+func floatRow(items []interface{}) []float64 {
+	row := make([]float64, len(items))
+	for i, item := range items {
+		row[i], _ = item.(float64)
 	}
+	return row
 }
 
 /////////////////////////////
@@ -227,14 +1728,63 @@ type privateConnect struct {
 	ApiKey      string `json:"apiKey"`
 	AuthSig     string `json:"authSig"`
 	AuthPayload string `json:"authPayload"`
+	// Dms is dmsCancelAll while SetCancelAllAfter is armed
+	Dms int `json:"dms,omitempty"`
 }
 
 // Private channel auth response
 type privateResponse struct {
-	Event  string  `json:"event"`
-	Status string  `json:"status"`
-	ChanId float64 `json:"chanId,omitempty"`
-	UserId float64 `json:"userId"`
+	Event  string                 `json:"event"`
+	Status string                 `json:"status"`
+	ChanId float64                `json:"chanId,omitempty"`
+	UserId float64                `json:"userId"`
+	Caps   map[string]capResponse `json:"caps"`
+	// Code and Msg explain a rejected authentication
+	Code int    `json:"code"`
+	Msg  string `json:"msg"`
+}
+
+type capResponse struct {
+	Read  int `json:"read"`
+	Write int `json:"write"`
+}
+
+// AuthInfo describes the account the private connection authenticated as.
+type AuthInfo struct {
+	UserId int64
+	// Caps are the API key permissions, only sent by the server on the
+	// version 2 protocol.
+	Caps Permissions
+}
+
+func (r *privateResponse) authInfo() AuthInfo {
+	perm := func(name string) KeyPerm {
+		c := r.Caps[name]
+		return KeyPerm{Read: c.Read != 0, Write: c.Write != 0}
+	}
+	return AuthInfo{
+		UserId: int64(r.UserId),
+		Caps: Permissions{
+			Account:   perm("account"),
+			History:   perm("history"),
+			Orders:    perm("orders"),
+			Positions: perm("positions"),
+			Funding:   perm("funding"),
+			Wallets:   perm("wallets"),
+			Withdraw:  perm("withdraw"),
+		},
+	}
+}
+
+// AuthInfo returns what the last successful authentication of the private
+// connection reported. It reports false before the first one.
+func (w *WebSocketService) AuthInfo() (AuthInfo, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.auth == nil {
+		return AuthInfo{}, false
+	}
+	return *w.auth, true
 }
 
 type TermData struct {
@@ -244,103 +1794,320 @@ type TermData struct {
 	// Examples:
 	// Term: ws, Data: ["exchange","BTC",0.01410829,0]
 	// Term: oc, Data: [0,"BTCUSD",0,-0.01,"","CANCELED",270,0,"2015-10-15T11:26:13Z",0]
-	Data  []interface{}
-	Error string
+	Data []interface{}
+	// SnapshotStart marks the beginning of a snapshot term (ps, ws, os, ...).
+	// It carries no Data; the snapshot entries follow. It is sent on connect
+	// and after every reconnect, so state built from earlier entries of that
+	// term should be cleared when it is received.
+	SnapshotStart bool
+	// Entries holds every entry of the snapshot on the SnapshotStart
+	// TermData, e.g. for OrdersSnapshot. They are delivered one by one too.
+	Entries [][]interface{}
+	// Status reports a change of the connection state, see STATUS_*
+	Status string
+	Error  string
+	// Err is the error described by Error, e.g. an *AuthError for a
+	// rejected authentication.
+	Err error
 }
 
+// Private channel TermData status values
+const (
+	// The private connection is being re-established with new credentials
+	STATUS_CREDENTIALS_ROTATED = "credentials rotated"
+	// The private connection is being re-established with the dead man's
+	// switch flag, see Client.SetCancelAllAfter
+	STATUS_CANCEL_ALL_ARMED = "cancel all after armed"
+)
+
 func (c *TermData) HasError() bool {
 	return len(c.Error) > 0
 }
 
+var errPrivateAuth = errors.New("Error connecting to private web socket channel.")
+
+// Codes of a rejected authentication, see AuthError
+const (
+	AUTH_CODE_FAILED    = 10100
+	AUTH_CODE_PAYLOAD   = 10111
+	AUTH_CODE_SIGNATURE = 10112
+	AUTH_CODE_ENCRYPTED = 10113
+	AUTH_CODE_NONCE     = 10114
+)
+
+// AuthError is returned when Bitfinex rejects the authentication of the
+// private connection. Code and Msg tell a bad key or signature from a
+// nonce problem, e.g. AUTH_CODE_NONCE after nonces were sent out of
+// order. errors.Is matches it with every other AuthError.
+type AuthError struct {
+	Status string
+	Code   int
+	Msg    string
+}
+
+func (e *AuthError) Error() string {
+	if e.Code == 0 && e.Msg == "" {
+		return errPrivateAuth.Error()
+	}
+	return fmt.Sprintf("%s %s: %s (code %d)", errPrivateAuth.Error(), e.Status, e.Msg, e.Code)
+}
+
+func (e *AuthError) Is(target error) bool {
+	return target == errPrivateAuth
+}
+
+// ConnectPrivate connects to the private channel and delivers its data to
+// ch until the connection fails, which is reported as a TermData error.
+// With AutoReconnect set a broken connection is dialed and authenticated
+// again every ReconnectInterval, until ClosePrivate is called. Rejected
+// authentication is never retried.
 func (w *WebSocketService) ConnectPrivate(ch chan TermData) {
+	w.mu.Lock()
+	w.privateClosed = false
+	w.mu.Unlock()
 
-	var d = websocket.Dialer{
-		Subprotocols:    []string{"p1", "p2"},
-		ReadBufferSize:  1024,
-		WriteBufferSize: 1024,
-		Proxy:           http.ProxyFromEnvironment,
+	out, flush := w.privateBuffer(ch)
+	defer flush()
+
+	ws, err := w.dialPrivate()
+	for err == nil {
+		err = w.readPrivate(ws, out)
+		ws.Close()
+		w.mu.Lock()
+		w.closeOrderResults()
+		w.mu.Unlock()
+		if status := w.takeReauth(); status != "" {
+			out <- TermData{
+				Status: status,
+			}
+			ws, err = w.dialPrivate()
+			if err != nil && w.AutoReconnect {
+				ws, err = w.redialPrivate()
+			}
+			continue
+		}
+		if !w.AutoReconnect || !w.retryable(err) {
+			break
+		}
+		if err == ErrPrivateOverflow {
+			w.waitDrained(out)
+		}
+		ws, err = w.redialPrivate()
 	}
 
-	ws, _, err := d.Dial(w.client.WebSocketURL, nil)
+	out <- TermData{
+		Error: err.Error(),
+		Err:   err,
+	}
+}
 
-	if w.client.WebSocketTLSSkipVerify {
-		d.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+// ClosePrivate closes the private connection and stops it reconnecting.
+func (w *WebSocketService) ClosePrivate() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.privateClosed = true
+	if w.privateWs != nil {
+		w.privateWs.Close()
+	}
+	w.closeOrderResults()
+}
+
+// RotateCredentials replaces the client API key and secret. A running
+// private connection is re-authenticated with the new key: it is closed
+// and dialed again, keeping the same TermData channel. The channel gets a
+// STATUS_CREDENTIALS_ROTATED status followed by fresh snapshots.
+func (w *WebSocketService) RotateCredentials(apiKey, apiSecret string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.client.Auth(apiKey, apiSecret)
+	w.reauthPrivate(STATUS_CREDENTIALS_ROTATED)
+}
+
+// armCancelAll re-authenticates a running private connection authenticated
+// without the dead man's switch flag once the switch is armed.
+func (w *WebSocketService) armCancelAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.privateDms && w.client.cancelAllArmed() {
+		w.reauthPrivate(STATUS_CANCEL_ALL_ARMED)
 	}
+}
+
+// reauthPrivate closes a running private connection for ConnectPrivate to
+// dial it again, announcing status. w.mu must be held.
+func (w *WebSocketService) reauthPrivate(status string) {
+	if w.privateWs != nil && !w.privateClosed {
+		w.reauth = status
+		w.privateWs.Close()
+	}
+}
 
-	ws, _, err = d.Dial(w.client.WebSocketURL, nil)
+// takeReauth returns and clears the status of a pending re-authentication,
+// empty without one.
+func (w *WebSocketService) takeReauth() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := w.reauth
+	w.reauth = ""
+	return status
+}
+
+// dialPrivate opens a private connection and sends the auth message.
+func (w *WebSocketService) dialPrivate() (*websocket.Conn, error) {
+	ws, _, err := w.dial()
 	if err != nil {
-		ch <- TermData{
-			Error: err.Error(),
-		}
-		return
+		return nil, err
 	}
 
-	payload := "AUTH" + fmt.Sprintf("%v", time.Now().Unix())
-	connectMsg, _ := json.Marshal(&privateConnect{
+	w.mu.Lock()
+	if w.privateClosed {
+		w.mu.Unlock()
+		ws.Close()
+		return nil, errors.New("private connection closed")
+	}
+	w.privateWs = ws
+
+	payload := "AUTH" + fmt.Sprintf("%v", w.client.now().Unix())
+	auth := privateConnect{
 		Event:       "auth",
-		ApiKey:      w.client.ApiKey,
+		ApiKey:      w.client.apiKey(),
 		AuthSig:     w.client.signPayload(payload),
 		AuthPayload: payload,
-	})
+	}
+	if w.client.cancelAllArmed() {
+		auth.Dms = dmsCancelAll
+	}
+	w.privateDms = auth.Dms != 0
+	connectMsg, _ := json.Marshal(&auth)
+	w.mu.Unlock()
 
+	if w.OnSendFrame != nil {
+		auth.ApiKey, auth.AuthSig = REDACTED, REDACTED
+		redacted, _ := json.Marshal(&auth)
+		w.OnSendFrame(redacted)
+	}
 	// Send auth message
 	err = ws.WriteMessage(websocket.TextMessage, connectMsg)
 	if err != nil {
-		ch <- TermData{
-			Error: err.Error(),
-		}
 		ws.Close()
-		return
+		return nil, err
 	}
+	return ws, nil
+}
 
-	var msg string
+// redialPrivate retries dialPrivate every ReconnectInterval until it
+// succeeds or ClosePrivate is called.
+func (w *WebSocketService) redialPrivate() (*websocket.Conn, error) {
+	for attempt := 1; ; attempt++ {
+		time.Sleep(w.ReconnectInterval)
+		if w.isPrivateClosed() {
+			return nil, errors.New("private connection closed")
+		}
+		ws, err := w.dialPrivate()
+		if err == nil {
+			return ws, nil
+		}
+		if !w.retryable(err) || (w.MaxReconnectAttempts > 0 && attempt >= w.MaxReconnectAttempts) {
+			return nil, &ReconnectError{Attempts: attempt, Err: err}
+		}
+		log.Println("Error reconnecting to private websocket", err)
+	}
+}
+
+func (w *WebSocketService) isPrivateClosed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.privateClosed
+}
+
+// readPrivate delivers the private channel data to ch until a read fails
+// or the authentication is rejected.
+func (w *WebSocketService) readPrivate(ws *websocket.Conn, ch chan TermData) error {
 	for {
-		_, p, err := ws.ReadMessage()
+		mt, p, err := ws.ReadMessage()
 		if err != nil {
-			ch <- TermData{
-				Error: err.Error(),
-			}
-			ws.Close()
-			return
-		} else {
-			msg = string(p)
-			event := &privateResponse{}
-			err = json.Unmarshal([]byte(msg), &event)
+			w.disconnected(true, err)
+			return err
+		}
+		if skip, err := w.skipFrame(mt, p); skip {
 			if err != nil {
-				// received data update
-				var data []interface{}
-				err = json.Unmarshal([]byte(msg), &data)
+				return err
+			}
+			continue
+		}
+
+		event := &privateResponse{}
+		err = w.unmarshal(p, &event)
+		if err == nil {
+			// received auth response
+			if event.Event == "auth" {
+				if event.Status != "OK" {
+					return &AuthError{Status: event.Status, Code: event.Code, Msg: event.Msg}
+				}
+				info := event.authInfo()
+				w.mu.Lock()
+				w.auth = &info
+				w.mu.Unlock()
+			}
+			continue
+		}
+
+		// received data update
+		var data []interface{}
+		if err = w.unmarshal(p, &data); err != nil || len(data) < 3 {
+			if w.StrictDecoding && (err != nil || !isPrivateHeartbeat(data)) {
 				if err == nil {
-					dataTerm := data[1].(string)
-					dataList := data[2].([]interface{})
-
-					// check for empty data
-					if len(dataList) > 0 {
-						if reflect.TypeOf(dataList[0]) == reflect.TypeOf([]interface{}{}) {
-							// received list of lists
-							for _, v := range dataList {
-								ch <- TermData{
-									Term: dataTerm,
-									Data: v.([]interface{}),
-								}
-							}
-						} else {
-							// received flat list
-							ch <- TermData{
-								Term: dataTerm,
-								Data: dataList,
-							}
-						}
-					}
+					err = fmt.Errorf("%d elements, want 3", len(data))
 				}
-			} else {
-				// received auth response
-				if event.Event == "auth" && event.Status != "OK" {
-					ch <- TermData{
-						Error: "Error connecting to private web socket channel.",
-					}
-					ws.Close()
+				return &DecodeError{Path: "private frame", Err: err.Error(), Raw: w.client.redact(string(p))}
+			}
+			continue
+		}
+		dataTerm, _ := data[1].(string)
+		dataList, ok := data[2].([]interface{})
+		if !ok {
+			if w.StrictDecoding {
+				return &DecodeError{Path: "term " + dataTerm, Err: fmt.Sprintf("%T, want an array", data[2]), Raw: w.client.redact(string(p))}
+			}
+			continue
+		}
+
+		if len(dataList) == 0 || reflect.TypeOf(dataList[0]) == reflect.TypeOf([]interface{}{}) {
+			// received list of lists, possibly empty: a snapshot
+			entries := make([][]interface{}, len(dataList))
+			for i, v := range dataList {
+				entries[i], _ = v.([]interface{})
+			}
+			err := w.sendPrivate(ch, TermData{
+				Term:          dataTerm,
+				SnapshotStart: true,
+				Entries:       entries,
+			})
+			for _, item := range entries {
+				if err != nil {
+					return err
 				}
+				err = w.sendPrivate(ch, TermData{
+					Term: dataTerm,
+					Data: item,
+				})
+			}
+			if err != nil {
+				return err
+			}
+		} else {
+			// received flat list
+			switch dataTerm {
+			case "on", "ou", "oc":
+				w.resolveOrder(dataList)
+			case "n":
+				w.resolveRejected(dataList)
+			}
+			if err := w.sendPrivate(ch, TermData{
+				Term: dataTerm,
+				Data: dataList,
+			}); err != nil {
+				return err
 			}
 		}
 	}
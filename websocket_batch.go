@@ -0,0 +1,54 @@
+package bitfinex
+
+import (
+	"net"
+	"sync"
+)
+
+// batchConn is the network connection below a websocket connection. Between
+// begin and flush, the frames written are buffered and sent with a single
+// write, instead of a write per frame.
+type batchConn struct {
+	net.Conn
+	mu       sync.Mutex
+	batching bool
+	buf      []byte
+}
+
+func (c *batchConn) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	if c.batching {
+		c.buf = append(c.buf, p...)
+		c.mu.Unlock()
+		return len(p), nil
+	}
+	c.mu.Unlock()
+	return c.Conn.Write(p)
+}
+
+// begin buffers the following writes. It does nothing on a nil batchConn,
+// when the connection was not dialed by WebSocketService.
+func (c *batchConn) begin() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.batching = true
+	c.mu.Unlock()
+}
+
+// flush sends the buffered writes and stops buffering.
+func (c *batchConn) flush() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batching = false
+	if len(c.buf) == 0 {
+		return nil
+	}
+	_, err := c.Conn.Write(c.buf)
+	c.buf = c.buf[:0]
+	return err
+}
@@ -0,0 +1,120 @@
+package bitfinex
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+type countingConn struct {
+	net.Conn
+	writes int
+	bytes  int
+}
+
+func (c *countingConn) Write(p []byte) (int, error) {
+	c.writes++
+	c.bytes += len(p)
+	return len(p), nil
+}
+
+func TestBatchConn(t *testing.T) {
+	conn := &countingConn{}
+	batch := &batchConn{Conn: conn}
+
+	batch.Write([]byte("a"))
+	batch.begin()
+	batch.Write([]byte("bc"))
+	batch.Write([]byte("def"))
+	if conn.writes != 1 {
+		t.Error("Expected", 1)
+		t.Error("Actual ", conn.writes)
+	}
+	if err := batch.flush(); err != nil {
+		t.Fatal(err)
+	}
+	batch.Write([]byte("g"))
+	if conn.writes != 3 || conn.bytes != 7 {
+		t.Error("Expected", "3 writes of 7 bytes")
+		t.Error("Actual ", conn.writes, conn.bytes)
+	}
+
+	var none *batchConn
+	none.begin()
+	if err := none.flush(); err != nil {
+		t.Error(err)
+	}
+}
+
+// BenchmarkSubscribeMessages measures sending MaxChannelsPerConnection
+// subscriptions until the server read them all.
+func BenchmarkSubscribeMessages(b *testing.B) {
+	for _, batched := range []bool{true, false} {
+		b.Run(fmt.Sprintf("batched=%v", batched), func(b *testing.B) {
+			read := make(chan struct{})
+			srv, c := newMockServer(b, func(ws *websocket.Conn) {
+				for i := 0; i < MaxChannelsPerConnection; i++ {
+					if _, _, err := ws.ReadMessage(); err != nil {
+						return
+					}
+				}
+				read <- struct{}{}
+				ws.ReadMessage()
+			})
+			defer srv.Close()
+
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				w := NewWebSocketService(c)
+				for j := 0; j < MaxChannelsPerConnection; j++ {
+					w.AddSubscribe(CHAN_TICKER, fmt.Sprintf("PAIR%d", j), 0, make(chan [][]float64))
+				}
+				if err := w.Connect(); err != nil {
+					b.Fatal(err)
+				}
+				if !batched {
+					w.batch = nil
+				}
+				b.StartTimer()
+				if err := w.sendSubscribeMessages(w.subscriptions()); err != nil {
+					b.Fatal(err)
+				}
+				<-read
+				w.ws.Close()
+			}
+		})
+	}
+}
+
+func TestSubscribeSendErrors(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	w := c.WebSocket
+	w.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64))
+	w.SubscribeCandles(BTCUSD, "1m", make(chan Candle))
+	if err := w.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.batch.Conn.Close()
+
+	err := w.sendSubscribeMessages(w.subscriptions())
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok || len(joined.Unwrap()) != 2 {
+		t.Fatal("Expected 2 joined errors, got", err)
+	}
+	var sendErr *SubscribeSendError
+	if !errors.As(err, &sendErr) || sendErr.Channel != CHAN_BOOK || sendErr.Pair != BTCUSD {
+		t.Error("Expected the book subscription to fail, got", sendErr)
+	}
+	if e := joined.Unwrap()[1].Error(); !strings.HasPrefix(e, "subscribe candles trade:1m:tBTCUSD: ") {
+		t.Error("Unexpected error", e)
+	}
+}
@@ -0,0 +1,204 @@
+package bitfinex
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// SubscribeBook adds a book subscription for pair that maintains the order
+// book locally and delivers a copy to c after every change, once Subscribe
+// is called. length is the number of price levels requested.
+//
+// After a reconnect the local book is discarded and an empty book with
+// Reset set is delivered. Updates resume with the fresh snapshot, so
+// deltas are never applied to a stale book.
+func (w *WebSocketService) SubscribeBook(pair string, length int, c chan *OrderBook) error {
+	return w.subscribeBook(pair, length, func(book *OrderBook) { c <- book }, c)
+}
+
+// SubscribeBookFunc is like SubscribeBook, calling fn on the read
+// goroutine instead, see AddSubscribeFunc.
+func (w *WebSocketService) SubscribeBookFunc(pair string, length int, fn func(*OrderBook)) error {
+	return w.subscribeBook(pair, length, fn, nil)
+}
+
+func (w *WebSocketService) subscribeBook(pair string, length int, fn func(*OrderBook), out interface{}) error {
+	b := newLiveBook()
+	return w.addSubscribe(&subscribeToChannel{
+		Channel: CHAN_BOOK,
+		Pair:    pair,
+		Len:     length,
+		out:     out,
+		deliver: func(f dataFrame) {
+			if b.apply(f) {
+				book := b.orderBook()
+				book.ServerTime = f.ServerTime
+				book.IsSnapshot = f.Snapshot
+				fn(book)
+			}
+		},
+		reset: func() {
+			b.reset()
+			fn(&OrderBook{Reset: true})
+		},
+	})
+}
+
+// BookEntry is a price level of the book channel.
+//
+// The sign of the amount tells the side: positive for bids, negative for
+// asks. A zero count removes the level, whatever its amount, which is then
+// 1 to remove a bid or -1 to remove an ask, so Side is still the side the
+// level is removed from.
+type BookEntry struct {
+	Price float64
+	// Count is the number of orders at the level, 0 when it was removed
+	Count int
+	// Amount is positive for bids and negative for asks
+	Amount float64
+	// Side is BUY for bids and SELL for asks, derived from Amount
+	Side Side
+}
+
+// ParseBookEntry decodes a [PRICE, COUNT, AMOUNT] row of the book channel,
+// e.g. one delivered by AddSubscribe. It reports false for a row of another
+// shape or with a zero amount, which has no side, such as SnapshotReset.
+func ParseBookEntry(row []float64) (BookEntry, bool) {
+	if len(row) < 3 || row[2] == 0 {
+		return BookEntry{}, false
+	}
+	return BookEntry{Price: row[0], Count: int(row[1]), Amount: row[2], Side: SideOf(row[2])}, true
+}
+
+// Removed reports whether the entry removes its level from the book.
+func (e BookEntry) Removed() bool {
+	return e.Count == 0
+}
+
+// Size is the absolute amount of the level, 0 once it is removed.
+func (e BookEntry) Size() float64 {
+	if e.Removed() {
+		return 0
+	}
+	return math.Abs(e.Amount)
+}
+
+// BookDiff is a set of book channel levels. A snapshot replaces the whole
+// book, otherwise Entries hold the changed levels only.
+type BookDiff struct {
+	Snapshot   bool
+	Entries    []BookEntry
+	ServerTime time.Time
+}
+
+// SubscribeBookDiffs adds a book subscription for pair that delivers the
+// changed levels to c as they arrive, for consumers that maintain their
+// own book, once Subscribe is called. Every (re)subscription starts with a
+// snapshot, so no reset sentinel is sent. Use SubscribeBook for a book
+// maintained by the library.
+func (w *WebSocketService) SubscribeBookDiffs(pair string, length int, c chan BookDiff) error {
+	return w.addSubscribe(&subscribeToChannel{
+		Channel: CHAN_BOOK,
+		Pair:    pair,
+		Len:     length,
+		out:     c,
+		deliver: func(f dataFrame) {
+			diff := BookDiff{
+				Snapshot:   f.Snapshot,
+				Entries:    make([]BookEntry, 0, len(f.Rows)),
+				ServerTime: f.ServerTime,
+			}
+			for _, row := range f.Rows {
+				if e, ok := ParseBookEntry(row); ok {
+					diff.Entries = append(diff.Entries, e)
+				}
+			}
+			c <- diff
+		},
+	})
+}
+
+// liveBook is an order book built from book channel frames.
+type liveBook struct {
+	// synced is set once a snapshot has been applied
+	synced bool
+	// price levels mapped to their amount
+	bids map[float64]float64
+	asks map[float64]float64
+}
+
+func newLiveBook() *liveBook {
+	b := &liveBook{}
+	b.reset()
+	return b
+}
+
+func (b *liveBook) reset() {
+	b.synced = false
+	b.bids = make(map[float64]float64)
+	b.asks = make(map[float64]float64)
+}
+
+// apply updates the book with a frame and reports whether it changed.
+// Updates received before the first snapshot are dropped.
+func (b *liveBook) apply(f dataFrame) bool {
+	if f.Snapshot {
+		b.reset()
+		b.synced = true
+	} else if !b.synced {
+		return false
+	}
+	for _, row := range f.Rows {
+		b.applyLevel(row)
+	}
+	return true
+}
+
+// applyLevel applies a [PRICE, COUNT, AMOUNT] level, see BookEntry.
+func (b *liveBook) applyLevel(row []float64) {
+	e, ok := ParseBookEntry(row)
+	if !ok {
+		return
+	}
+	side := b.bids
+	if e.Side == SELL {
+		side = b.asks
+	}
+	if e.Removed() {
+		delete(side, e.Price)
+		return
+	}
+	side[e.Price] = e.Size()
+}
+
+// orderBook returns a copy of the book, sorted best price first.
+func (b *liveBook) orderBook() *OrderBook {
+	return &OrderBook{
+		Bids: bookEntries(b.bids, BUY),
+		Asks: bookEntries(b.asks, SELL),
+	}
+}
+
+func bookEntries(levels map[float64]float64, side Side) []OrderBookEntry {
+	prices := make([]float64, 0, len(levels))
+	for price := range levels {
+		prices = append(prices, price)
+	}
+	if side == BUY {
+		sort.Sort(sort.Reverse(sort.Float64Slice(prices)))
+	} else {
+		sort.Float64s(prices)
+	}
+
+	entries := make([]OrderBookEntry, len(prices))
+	for i, price := range prices {
+		entries[i] = OrderBookEntry{
+			Price:  strconv.FormatFloat(price, 'f', -1, 64),
+			Amount: strconv.FormatFloat(levels[price], 'f', -1, 64),
+			Side:   side,
+		}
+	}
+	return entries
+}
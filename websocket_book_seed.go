@@ -0,0 +1,205 @@
+package bitfinex
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// seedTimeout bounds the REST request seeding a book.
+const seedTimeout = 10 * time.Second
+
+// SubscribeBookSeeded is like SubscribeBook, seeding the book from a REST
+// /book snapshot of length levels per side instead of the websocket one.
+// The request is sent when the first frame of the subscription arrives,
+// again after every reconnect. Websocket updates are held until it
+// completes, then the ones sent before the request started, by their
+// ServerTime, are dropped and the others applied on top of the snapshot.
+// When the request fails the websocket snapshot is used.
+//
+// Seeding is only gap-free with CONF_TIMESTAMP: without a ServerTime every
+// held update is applied, and one older than the snapshot may overwrite a
+// level with an older state. The start of the request is converted to the
+// server clock with ClockSkew, which assumes the skew measured on earlier
+// frames still holds; the latency it includes makes the start earlier, so
+// updates are kept rather than dropped.
+func (w *WebSocketService) SubscribeBookSeeded(pair string, length int, c chan *OrderBook) error {
+	if length == ChannelDefaultLen {
+		length = DefaultBookLen
+	}
+	fetch := func() (OrderBook, time.Time, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), seedTimeout)
+		defer cancel()
+		start := w.client.now().Add(w.ClockSkew())
+		book, err := w.client.OrderBook.GetContext(ctx, pair, length, length, false)
+		return book, start, err
+	}
+	s := &subscribeToChannel{
+		Channel: CHAN_BOOK,
+		Pair:    pair,
+		Len:     length,
+		out:     c,
+	}
+	b := newSeededBook(fetch, func(book *OrderBook) { w.deliverSeeded(s, c, book) })
+	s.deliver = b.apply
+	s.reset = b.reset
+	s.removed = b.cancel
+	return w.addSubscribe(s)
+}
+
+// deliverSeeded sends a book of s to c, also from the goroutine seeding
+// it: the book is dropped once s is removed, and s is unsubscribed when the
+// consumer closed c, as dispatch does.
+func (w *WebSocketService) deliverSeeded(s *subscribeToChannel, c chan *OrderBook, book *OrderBook) {
+	w.mu.Lock()
+	live := w.isSubscribed(s) && !s.released
+	w.mu.Unlock()
+	if !live {
+		return
+	}
+	if !sendBookRecover(c, book) {
+		w.dropClosed(s)
+	}
+}
+
+// sendBookRecover sends book to c and reports false if c is closed.
+func sendBookRecover(c chan *OrderBook, book *OrderBook) (sent bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if err, ok := r.(error); !ok || err.Error() != "send on closed channel" {
+				panic(r)
+			}
+			sent = false
+		}
+	}()
+	c <- book
+	return true
+}
+
+// seededBook is a liveBook seeded by fetch rather than the websocket
+// snapshot. fn is called with its lock held, from the read goroutine or
+// the one seeding the book, so books are delivered in order.
+type seededBook struct {
+	fetch func() (OrderBook, time.Time, error)
+	fn    func(*OrderBook)
+
+	mu   sync.Mutex
+	book *liveBook
+	// seeded is set once the fetched snapshot has been applied
+	seeded bool
+	// fetching is set while a fetch is running for the current generation
+	fetching bool
+	// held are the frames received while fetching
+	held []dataFrame
+	// gen identifies the subscription a fetch was started for, accessed
+	// atomically so that cancel doesn't need mu
+	gen int64
+}
+
+func newSeededBook(fetch func() (OrderBook, time.Time, error), fn func(*OrderBook)) *seededBook {
+	return &seededBook{fetch: fetch, fn: fn, book: newLiveBook()}
+}
+
+func (b *seededBook) apply(f dataFrame) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.seeded {
+		b.deliver(f)
+		return
+	}
+	b.held = append(b.held, f)
+	if !b.fetching {
+		b.fetching = true
+		go b.seed(atomic.LoadInt64(&b.gen))
+	}
+}
+
+func (b *seededBook) reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	atomic.AddInt64(&b.gen, 1)
+	b.seeded = false
+	b.fetching = false
+	b.held = nil
+	b.book.reset()
+	b.fn(&OrderBook{Reset: true})
+}
+
+// seed fetches the snapshot and applies the held frames on top of it,
+// unless the book was reset meanwhile.
+// cancel drops the result of a running fetch, once the subscription is
+// removed. It is called with the service mu held, so it doesn't take b.mu.
+func (b *seededBook) cancel() {
+	atomic.AddInt64(&b.gen, 1)
+}
+
+func (b *seededBook) seed(gen int64) {
+	snapshot, start, err := b.fetch()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if gen != atomic.LoadInt64(&b.gen) {
+		return
+	}
+	held := b.held
+	b.held = nil
+	b.seeded = true
+	if err != nil {
+		log.Println("Error seeding book, using the websocket snapshot:", err)
+		for _, f := range held {
+			b.deliver(f)
+		}
+		return
+	}
+
+	seed := dataFrame{Snapshot: true, Rows: seedRows(snapshot)}
+	for _, f := range held {
+		if f.Snapshot || (!f.ServerTime.IsZero() && f.ServerTime.Before(start)) {
+			continue
+		}
+		seed.Rows = append(seed.Rows, f.Rows...)
+		seed.ServerTime = f.ServerTime
+	}
+	b.deliver(seed)
+}
+
+func (b *seededBook) deliver(f dataFrame) {
+	if b.book.apply(f) {
+		book := b.book.orderBook()
+		book.ServerTime = f.ServerTime
+		book.IsSnapshot = f.Snapshot
+		b.fn(book)
+	}
+}
+
+// seedRows converts a REST book into [PRICE, COUNT, AMOUNT] levels. REST
+// levels have no order count, 1 stands for any.
+func seedRows(book OrderBook) [][]float64 {
+	rows := make([][]float64, 0, len(book.Bids)+len(book.Asks))
+	for _, e := range book.Bids {
+		if row, ok := seedRow(e, 1); ok {
+			rows = append(rows, row)
+		}
+	}
+	for _, e := range book.Asks {
+		if row, ok := seedRow(e, -1); ok {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+func seedRow(e OrderBookEntry, sign float64) ([]float64, bool) {
+	price, err := strconv.ParseFloat(e.Price, 64)
+	if err != nil {
+		return nil, false
+	}
+	amount, err := strconv.ParseFloat(e.Amount, 64)
+	if err != nil {
+		return nil, false
+	}
+	return []float64{price, 1, sign * amount}, true
+}
@@ -0,0 +1,186 @@
+package bitfinex
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSeededBook(t *testing.T) {
+	start := time.Unix(1000, 0)
+	fetched := make(chan struct{})
+	fetch := func() (OrderBook, time.Time, error) {
+		<-fetched
+		return OrderBook{
+			Bids: []OrderBookEntry{{Price: "450", Amount: "1"}, {Price: "449", Amount: "2"}},
+			Asks: []OrderBookEntry{{Price: "451", Amount: "3"}},
+		}, start, nil
+	}
+	books := make(chan *OrderBook, 10)
+	b := newSeededBook(fetch, func(book *OrderBook) { books <- book })
+
+	b.apply(dataFrame{Snapshot: true, Rows: [][]float64{{440, 1, 1}}, ServerTime: start.Add(-2 * time.Second)})
+	// already in the REST snapshot
+	b.apply(dataFrame{Rows: [][]float64{{448, 1, 5}}, ServerTime: start.Add(-time.Second)})
+	b.apply(dataFrame{Rows: [][]float64{{449, 0, 1}}, ServerTime: start.Add(time.Second)})
+	b.apply(dataFrame{Rows: [][]float64{{452, 1, -4}}, ServerTime: start.Add(2 * time.Second)})
+	select {
+	case v := <-books:
+		t.Fatal("Unexpected book before the seed", v)
+	default:
+	}
+	close(fetched)
+
+	book := receiveBook(t, books)
+	if len(book.Bids) != 1 || book.Bids[0].Price != "450" || len(book.Asks) != 2 || book.Asks[1].Price != "452" || !book.IsSnapshot {
+		t.Error("Unexpected seeded book", book)
+	}
+	if !book.ServerTime.Equal(start.Add(2 * time.Second)) {
+		t.Error("Expected", start.Add(2*time.Second))
+		t.Error("Actual ", book.ServerTime)
+	}
+
+	b.apply(dataFrame{Rows: [][]float64{{450, 0, 1}}})
+	if book := receiveBook(t, books); len(book.Bids) != 0 || len(book.Asks) != 2 || book.IsSnapshot {
+		t.Error("Unexpected book after the seed", book)
+	}
+}
+
+func TestSeededBookFallback(t *testing.T) {
+	fetch := func() (OrderBook, time.Time, error) {
+		return OrderBook{}, time.Time{}, errors.New("unavailable")
+	}
+	books := make(chan *OrderBook, 10)
+	b := newSeededBook(fetch, func(book *OrderBook) { books <- book })
+
+	b.apply(dataFrame{Snapshot: true, Rows: [][]float64{{440, 1, 1}}})
+	if book := receiveBook(t, books); len(book.Bids) != 1 || book.Bids[0].Price != "440" {
+		t.Error("Expected the websocket snapshot, got", book)
+	}
+}
+
+func TestSubscribeBookSeeded(t *testing.T) {
+	requested := make(chan string, 2)
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		requested <- req.URL.String()
+		msg := `{"bids":[{"price":"450","amount":"1","timestamp":"1444266681.0"}],"asks":[{"price":"451","amount":"2","timestamp":"1444266681.0"}]}`
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[440,1,1]]]`,
+			`[5,452,1,-1]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	books := make(chan *OrderBook, 10)
+	c.WebSocket.SubscribeBookSeeded(BTCUSD, ChannelDefaultLen, books)
+	go c.WebSocket.Subscribe()
+
+	book := receiveBook(t, books)
+	if u := <-requested; u != DefaultBaseURL+"book/btcusd?limit_asks=25&limit_bids=25" {
+		t.Error("Unexpected request", u)
+	}
+	// the update is applied once seeded, the websocket snapshot is not
+	for len(book.Asks) < 2 {
+		book = receiveBook(t, books)
+	}
+	if len(book.Bids) != 1 || book.Bids[0].Price != "450" || book.Asks[0].Price != "451" || book.Asks[1].Price != "452" {
+		t.Error("Unexpected book", book)
+	}
+}
+
+func TestSubscribeBookSeededRemoved(t *testing.T) {
+	fetching := make(chan struct{}, 2)
+	fetched := make(chan struct{})
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		fetching <- struct{}{}
+		<-fetched
+		msg := `{"bids":[{"price":"450","amount":"1","timestamp":"1444266681.0"}],"asks":[]}`
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(msg)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+	unsubscribed := make(chan unsubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"book","chanId":6,"pair":"LTCUSD"}`,
+			`[5,[[440,1,1]]]`,
+			`[6,[[40,1,1]]]`,
+		)
+		var msg unsubscribeMsg
+		ws.ReadJSON(&msg)
+		unsubscribed <- msg
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	removed := make(chan *OrderBook, 10)
+	closed := make(chan *OrderBook, 10)
+	c.WebSocket.SubscribeBookSeeded(BTCUSD, ChannelDefaultLen, removed)
+	c.WebSocket.SubscribeBookSeeded(LTCUSD, ChannelDefaultLen, closed)
+	go c.WebSocket.Subscribe()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-fetching:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the seed requests")
+		}
+	}
+	// removed and closed by the consumer while seeding
+	c.WebSocket.mu.Lock()
+	var btc *subscribeToChannel
+	for _, s := range c.WebSocket.subscribes {
+		if s.Pair == BTCUSD {
+			btc = s
+		}
+	}
+	c.WebSocket.remove(btc)
+	c.WebSocket.mu.Unlock()
+	close(closed)
+	close(fetched)
+
+	select {
+	case msg := <-unsubscribed:
+		if msg.ChanId != 6 {
+			t.Error("Expected", 6)
+			t.Error("Actual ", msg.ChanId)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the closed book to be unsubscribed")
+	}
+	select {
+	case v := <-removed:
+		t.Error("Unexpected book for a removed subscription", v)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
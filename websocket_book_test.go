@@ -0,0 +1,172 @@
+package bitfinex
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribeBook(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[449,2,1.5],[451,1,-2],[448,1,3]]]`,
+			`[5,449,0,1]`,
+			`[5,452,1,-0.5]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	books := make(chan *OrderBook, 10)
+	c.WebSocket.SubscribeBook(BTCUSD, 25, books)
+	go c.WebSocket.Subscribe()
+
+	book := receiveBook(t, books)
+	if len(book.Bids) != 2 || book.Bids[0].Price != "449" || book.Asks[0].Amount != "2" ||
+		book.Bids[0].Side != BUY || book.Asks[0].Side != SELL || !book.IsSnapshot {
+		t.Error("Unexpected snapshot book", book)
+	}
+
+	book = receiveBook(t, books)
+	if len(book.Bids) != 1 || book.Bids[0].Price != "448" || book.IsSnapshot {
+		t.Error("Expected level 449 to be removed", book)
+	}
+
+	book = receiveBook(t, books)
+	if len(book.Asks) != 2 || book.Asks[1].Price != "452" {
+		t.Error("Expected ask 452 to be added", book)
+	}
+}
+
+func TestSubscribeBookReconnect(t *testing.T) {
+	var connections int32
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		n := atomic.AddInt32(&connections, 1)
+		readSubscribe(t, ws)
+		if n == 1 {
+			writeFrames(ws,
+				`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+				`[5,[[449,2,1.5],[451,1,-2]]]`,
+			)
+			return
+		}
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":9,"pair":"BTCUSD"}`,
+			`[9,[[440,1,1],[441,1,-1]]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = 10 * time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	books := make(chan *OrderBook, 10)
+	c.WebSocket.SubscribeBook(BTCUSD, 25, books)
+	done := make(chan error)
+	go func() { done <- c.WebSocket.Subscribe() }()
+
+	if book := receiveBook(t, books); book.Bids[0].Price != "449" {
+		t.Error("Unexpected first book", book)
+	}
+	if book := receiveBook(t, books); !book.Reset || len(book.Bids) != 0 {
+		t.Error("Expected reset book", book)
+	}
+	if book := receiveBook(t, books); book.Reset || book.Bids[0].Price != "440" {
+		t.Error("Expected fresh snapshot book", book)
+	}
+
+	c.WebSocket.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe did not return after Close")
+	}
+}
+
+func receiveBook(t *testing.T, c chan *OrderBook) *OrderBook {
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for book")
+	}
+	return nil
+}
+
+func TestSubscribeBookDiffs(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[449,1,1],[451,2,-2]]]`,
+			`[5,"hb"]`,
+			`[5,449,0,1]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	diffs := make(chan BookDiff, 10)
+	c.WebSocket.SubscribeBookDiffs(BTCUSD, 25, diffs)
+	go c.WebSocket.Subscribe()
+
+	expected := []BookDiff{
+		{Snapshot: true, Entries: []BookEntry{{449, 1, 1, BUY}, {451, 2, -2, SELL}}},
+		{Entries: []BookEntry{{449, 0, 1, BUY}}},
+	}
+	for _, e := range expected {
+		select {
+		case d := <-diffs:
+			if !reflect.DeepEqual(d, e) {
+				t.Error("Expected", e)
+				t.Error("Actual ", d)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for diff")
+		}
+	}
+}
+
+func TestParseBookEntry(t *testing.T) {
+	cases := []struct {
+		row     []float64
+		entry   BookEntry
+		ok      bool
+		removed bool
+		size    float64
+	}{
+		{[]float64{449, 2, 1.5}, BookEntry{449, 2, 1.5, BUY}, true, false, 1.5},
+		{[]float64{451, 1, -0.5}, BookEntry{451, 1, -0.5, SELL}, true, false, 0.5},
+		// a zero count removes the level, the amount sign tells the side
+		{[]float64{449, 0, 1}, BookEntry{449, 0, 1, BUY}, true, true, 0},
+		{[]float64{451, 0, -1}, BookEntry{451, 0, -1, SELL}, true, true, 0},
+		{SnapshotReset, BookEntry{}, false, false, 0},
+		{[]float64{449, 2}, BookEntry{}, false, false, 0},
+	}
+	for _, c := range cases {
+		e, ok := ParseBookEntry(c.row)
+		if ok != c.ok || e != c.entry || (ok && (e.Removed() != c.removed || e.Size() != c.size)) {
+			t.Error("Expected", c.entry, c.ok, c.removed, c.size)
+			t.Error("Actual ", e, ok, e.Removed(), e.Size())
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package bitfinex
+
+import (
+	"sort"
+	"time"
+)
+
+// Candle is an OHLC candle of the candles channel:
+// [MTS, OPEN, CLOSE, HIGH, LOW, VOLUME]
+type Candle struct {
+	Timestamp time.Time
+	Open      float64
+	Close     float64
+	High      float64
+	Low       float64
+	Volume    float64
+}
+
+// CandleKey returns the candles channel key of pair for timeframe, e.g.
+// "trade:1m:tBTCUSD" for CandleKey(BTCUSD, "1m").
+func CandleKey(pair, timeframe string) string {
+	return "trade:" + timeframe + ":" + NormalizeSymbol(pair, SYMBOL_V2)
+}
+
+// SubscribeCandles adds a candles subscription for pair and timeframe,
+// e.g. "1m", "1h" or "1D", delivering candles to c once Subscribe is
+// called. The snapshot is delivered oldest first, then every update of
+// the current candle; an update with the Timestamp of the last candle
+// replaces it. Candles are only served by the v2 endpoint, so the client
+// WebSocketURL must be DefaultWebSocketV2URL.
+func (w *WebSocketService) SubscribeCandles(pair, timeframe string, c chan Candle) error {
+	return w.addSubscribe(&subscribeToChannel{
+		Channel: CHAN_CANDLES,
+		Pair:    NormalizeSymbol(pair, SYMBOL_WEBSOCKET),
+		Key:     CandleKey(pair, timeframe),
+		out:     c,
+		deliver: func(f dataFrame) {
+			candles := decodeCandles(f.Rows)
+			if f.Snapshot {
+				sort.Slice(candles, func(i, j int) bool {
+					return candles[i].Timestamp.Before(candles[j].Timestamp)
+				})
+			}
+			for _, candle := range candles {
+				c <- candle
+			}
+		},
+	})
+}
+
+func decodeCandles(rows [][]float64) []Candle {
+	candles := make([]Candle, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 6 {
+			continue
+		}
+		candles = append(candles, Candle{
+			Timestamp: time.Unix(0, int64(row[0])*int64(time.Millisecond)),
+			Open:      row[1],
+			Close:     row[2],
+			High:      row[3],
+			Low:       row[4],
+			Volume:    row[5],
+		})
+	}
+	return candles
+}
@@ -0,0 +1,145 @@
+package bitfinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribeCandles(t *testing.T) {
+	subscribed := make(chan SubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		subscribed <- readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"candles","chanId":7,"key":"trade:1h:tBTCUSD"}`,
+			`{"event":"subscribed","channel":"candles","chanId":8,"key":"trade:1m:tBTCUSD"}`,
+			`[8,[[1364824440000,5,6,7,4,2],[1364824380000,4,5,6,3,1.5]]]`,
+			`[8,"hb"]`,
+			`[8,[1364824440000,5,6.5,7,4,2.5]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	candles := make(chan Candle, 10)
+	if err := c.WebSocket.SubscribeCandles("btcusd", "1m", candles); err != nil {
+		t.Fatal(err)
+	}
+	c.WebSocket.SubscribeCandles(BTCUSD, "1h", make(chan Candle, 10))
+	if err := c.WebSocket.SubscribeCandles(BTCUSD, "1m", candles); err != ErrAlreadySubscribed {
+		t.Error("Expected", ErrAlreadySubscribed)
+		t.Error("Actual ", err)
+	}
+	go c.WebSocket.Subscribe()
+
+	if msg := <-subscribed; msg.Channel != CHAN_CANDLES || msg.Key != "trade:1m:tBTCUSD" || msg.Pair != "" || msg.Len != "" {
+		t.Error("Unexpected subscribe message", msg)
+	}
+
+	expected := []Candle{
+		{Timestamp: time.Unix(1364824380, 0), Open: 4, Close: 5, High: 6, Low: 3, Volume: 1.5},
+		{Timestamp: time.Unix(1364824440, 0), Open: 5, Close: 6, High: 7, Low: 4, Volume: 2},
+		{Timestamp: time.Unix(1364824440, 0), Open: 5, Close: 6.5, High: 7, Low: 4, Volume: 2.5},
+	}
+	for _, e := range expected {
+		select {
+		case v := <-candles:
+			if !v.Timestamp.Equal(e.Timestamp) || v.Close != e.Close || v.Volume != e.Volume {
+				t.Error("Expected", e)
+				t.Error("Actual ", v)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for candles")
+		}
+	}
+}
+
+func TestUnsubscribeKey(t *testing.T) {
+	unsubscribed := make(chan unsubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"candles","chanId":7,"key":"trade:1m:tBTCUSD"}`,
+			`{"event":"subscribed","channel":"candles","chanId":8,"key":"trade:1h:tBTCUSD"}`,
+			`[7,[1364824440000,5,6,7,4,2]]`,
+			`[8,[1364821200000,4,5,6,3,1.5]]`,
+		)
+		var msg unsubscribeMsg
+		ws.ReadJSON(&msg)
+		unsubscribed <- msg
+		writeFrames(ws,
+			`[8,[1364821200000,4,5.5,6,3,2.5]]`,
+			`[7,[1364824440000,5,6.5,7,4,2.5]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	minutes := make(chan Candle, 10)
+	hours := make(chan Candle, 10)
+	c.WebSocket.SubscribeCandles(BTCUSD, "1m", minutes)
+	c.WebSocket.SubscribeCandles(BTCUSD, "1h", hours)
+	if err := c.WebSocket.SetPriorityKey(CHAN_CANDLES, CandleKey(BTCUSD, "1h"), 1); err != nil {
+		t.Fatal(err)
+	}
+	go c.WebSocket.Subscribe()
+
+	for _, ch := range []chan Candle{minutes, hours} {
+		select {
+		case <-ch:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for candles")
+		}
+	}
+	if c.WebSocket.LastUpdateKey(CHAN_CANDLES, CandleKey(BTCUSD, "1h")).IsZero() {
+		t.Error("Expected a last update for the 1h candles")
+	}
+
+	// the candles are subscribed by key, not by pair
+	if err := c.WebSocket.Unsubscribe(CHAN_CANDLES, BTCUSD); err != ErrNotSubscribed {
+		t.Error("Expected", ErrNotSubscribed)
+		t.Error("Actual ", err)
+	}
+	if err := c.WebSocket.UnsubscribeKey(CHAN_CANDLES, CandleKey(BTCUSD, "1h")); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-unsubscribed:
+		if msg.Event != "unsubscribe" || msg.ChanId != 8 {
+			t.Error("Expected", unsubscribeMsg{Event: "unsubscribe", ChanId: 8})
+			t.Error("Actual ", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for unsubscribe")
+	}
+	select {
+	case v := <-minutes:
+		if v.Close != 6.5 {
+			t.Error("Unexpected candle", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for candles")
+	}
+	select {
+	case v := <-hours:
+		t.Error("Unexpected 1h candle", v)
+	default:
+	}
+	if err := c.WebSocket.UnsubscribeKey(CHAN_CANDLES, CandleKey(BTCUSD, "1h")); err != ErrNotSubscribed {
+		t.Error("Expected", ErrNotSubscribed)
+		t.Error("Actual ", err)
+	}
+}
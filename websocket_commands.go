@@ -0,0 +1,142 @@
+package bitfinex
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// DefaultCommandQueueSize is the default CommandQueueSize.
+const DefaultCommandQueueSize = 64
+
+// wsCommand is a message to send on the public connection from the read
+// loop, queued by a call made on another goroutine while Subscribe runs.
+// The subscriptions are updated by the caller, under mu, the command only
+// carries the message.
+type wsCommand struct {
+	// subscribe is a subscription added by AddSubscribe
+	subscribe *subscribeToChannel
+	// closed receives the result of sending the close frame of Close
+	closed chan error
+	// unsubscribe is the chanId to unsubscribe otherwise
+	unsubscribe float64
+}
+
+// enqueue hands c to the read loop owning queue, or runs it right away
+// without one, while Subscribe doesn't run. queue and done are w.commands
+// and w.readDone, read under w.mu along with the change of the
+// subscriptions c belongs to. It blocks while the queue is full, unless the
+// loop returns.
+func (w *WebSocketService) enqueue(queue chan wsCommand, done chan struct{}, c wsCommand) error {
+	if queue == nil {
+		return w.runCommand(c, false)
+	}
+	select {
+	case queue <- c:
+		return nil
+	case <-done:
+		return w.runCommand(c, false)
+	}
+}
+
+// runCommand sends the message of c, from the read loop when live is set.
+// Subscriptions are only sent by the read loop: otherwise they are sent
+// with the others when Subscribe runs again, or after the reconnect.
+func (w *WebSocketService) runCommand(c wsCommand, live bool) error {
+	if c.closed != nil {
+		w.mu.Lock()
+		ws := w.ws
+		w.mu.Unlock()
+		err := writeClose(ws)
+		c.closed <- err
+		return err
+	}
+	if c.subscribe != nil {
+		if !live {
+			return nil
+		}
+		return w.sendAdded(c.subscribe)
+	}
+	msg, _ := json.Marshal(unsubscribeMsg{Event: "unsubscribe", ChanId: c.unsubscribe})
+	return w.write(msg)
+}
+
+// runQueued runs a command taken by the read loop. A failed write breaks
+// the connection, which the read loop reports, so errors are only logged.
+func (w *WebSocketService) runQueued(c wsCommand) {
+	if err := w.runCommand(c, true); err != nil {
+		log.Println("Error sending queued command", err)
+	}
+}
+
+// sendAdded sends the subscribe message of a subscription added while
+// Subscribe runs, unless it was removed meanwhile. It is queued instead
+// while MaxInFlightSubscribes messages are not confirmed.
+func (w *WebSocketService) sendAdded(s *subscribeToChannel) error {
+	w.mu.Lock()
+	if !w.isSubscribed(s) {
+		w.mu.Unlock()
+		return nil
+	}
+	if n := w.MaxInFlightSubscribes; n > 0 && len(w.inflight) >= n {
+		w.queued = append(w.queued, s)
+		w.mu.Unlock()
+		return nil
+	}
+	w.mu.Unlock()
+	if err := w.sendSubscribe(s); err != nil {
+		return &SubscribeSendError{Channel: s.Channel, Pair: s.Pair, Key: s.Key, Err: err}
+	}
+	w.sent(s)
+	return nil
+}
+
+// flushCommands sends the messages left in queue, outside the read loop.
+// The connection may be closed already, so errors are ignored.
+func (w *WebSocketService) flushCommands(queue chan wsCommand) {
+	for {
+		select {
+		case c := <-queue:
+			w.runCommand(c, false)
+		default:
+			return
+		}
+	}
+}
+
+// queueClose has the read loop owning queue send the close frame, after
+// the messages queued before, and reports whether it did so in time. It
+// doesn't when the loop is not running, or blocked delivering a frame.
+func (w *WebSocketService) queueClose(queue chan wsCommand, done chan struct{}) (bool, error) {
+	if queue == nil {
+		return false, nil
+	}
+	c := wsCommand{closed: make(chan error, 1)}
+	timeout := time.NewTimer(closeTimeout)
+	defer timeout.Stop()
+	select {
+	case queue <- c:
+	case <-done:
+		return false, nil
+	case <-timeout.C:
+		return false, nil
+	}
+	select {
+	case err := <-c.closed:
+		return true, err
+	case <-done:
+		// the frame may have been sent while draining the queue, sending it
+		// again only fails
+		return false, nil
+	case <-timeout.C:
+		return false, nil
+	}
+}
+
+// writeClose sends a normal closure frame.
+func writeClose(ws *websocket.Conn) error {
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	return ws.WriteControl(websocket.CloseMessage, msg, time.Now().Add(closeTimeout))
+}
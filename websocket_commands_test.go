@@ -0,0 +1,198 @@
+package bitfinex
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestUnsubscribeQueued(t *testing.T) {
+	unsubscribed := make(chan unsubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"trades","chanId":4,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`[4,"te",1,1443659698,236.42,0.5]`,
+			`[4,"te",2,1443659699,236.43,0.5]`,
+		)
+		var msg unsubscribeMsg
+		ws.ReadJSON(&msg)
+		unsubscribed <- msg
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+	trades := make(chan TradeUpdate)
+	c.WebSocket.SubscribeTrades(BTCUSD, trades)
+	c.WebSocket.SubscribeTicker(BTCUSD, make(chan TickerUpdate))
+	go c.WebSocket.Subscribe()
+
+	<-trades
+	// the read loop is blocked delivering the second trade
+	time.Sleep(20 * time.Millisecond)
+	returned := make(chan error, 1)
+	go func() { returned <- c.WebSocket.Unsubscribe(CHAN_TICKER, BTCUSD) }()
+	select {
+	case err := <-returned:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Unsubscribe blocked on the read loop")
+	}
+	select {
+	case msg := <-unsubscribed:
+		t.Fatal("Expected the read loop to send the unsubscribe, got", msg)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	<-trades
+	select {
+	case msg := <-unsubscribed:
+		if msg.ChanId != 3 {
+			t.Error("Expected", 3)
+			t.Error("Actual ", msg.ChanId)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for unsubscribe")
+	}
+}
+
+func TestUnsubscribeInFlight(t *testing.T) {
+	removed := make(chan struct{})
+	unsubscribed := make(chan unsubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		<-removed
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`[3,236.41,1,236.43,1,0,0,236.42,10,240,230]`,
+			`{"event":"subscribed","channel":"trades","chanId":4,"pair":"BTCUSD"}`,
+			`[4,"te",1,1443659698,236.42,0.5]`,
+		)
+		var msg unsubscribeMsg
+		ws.ReadJSON(&msg)
+		unsubscribed <- msg
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+	c.WebSocket.SubscribeTicker(BTCUSD, make(chan TickerUpdate, 1))
+	trades := make(chan TradeUpdate, 1)
+	c.WebSocket.SubscribeTrades(BTCUSD, trades)
+	go c.WebSocket.Subscribe()
+
+	// sent, not confirmed yet
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		c.WebSocket.mu.Lock()
+		n := len(c.WebSocket.inflight)
+		c.WebSocket.mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := c.WebSocket.Unsubscribe(CHAN_TICKER, BTCUSD); err != nil {
+		t.Fatal(err)
+	}
+	close(removed)
+
+	select {
+	case msg := <-unsubscribed:
+		if msg.Event != "unsubscribe" || msg.ChanId != 3 {
+			t.Error("Expected", unsubscribeMsg{Event: "unsubscribe", ChanId: 3})
+			t.Error("Actual ", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for unsubscribe")
+	}
+	select {
+	case <-trades:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the trade")
+	}
+
+	c.WebSocket.mu.Lock()
+	defer c.WebSocket.mu.Unlock()
+	if len(c.WebSocket.pending) != 0 || len(c.WebSocket.abandoned) != 0 || c.WebSocket.chanMap[3] != nil {
+		t.Error("Expected chanId 3 forgotten, got", c.WebSocket.pending, c.WebSocket.abandoned, c.WebSocket.chanMap)
+	}
+}
+
+func TestAddSubscribeWhileRunning(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws, `{"event":"subscribed","channel":"trades","chanId":4,"pair":"BTCUSD"}`)
+		for i := 0; i < 3; i++ {
+			msg := readSubscribe(t, ws)
+			if msg.Event != "subscribe" || msg.Channel != CHAN_TICKER {
+				t.Error("Unexpected message", msg)
+				return
+			}
+			chanId := 10 + i
+			writeFrames(ws,
+				fmt.Sprintf(`{"event":"subscribed","channel":"ticker","chanId":%d,"pair":"%s"}`, chanId, msg.Pair),
+				fmt.Sprintf(`[%d,449,1,451,2,-1,-0.01,450,1000,460,440]`, chanId),
+			)
+		}
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+	c.WebSocket.SubscribeTrades(BTCUSD, make(chan TradeUpdate, 1))
+	go c.WebSocket.Subscribe()
+	for deadline := time.Now().Add(time.Second); c.WebSocket.Health().Confirmed != 1; {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the trades subscription")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	pairs := []string{BTCUSD, LTCUSD, ETHUSD}
+	tickers := make(chan TickerUpdate, len(pairs))
+	var wg sync.WaitGroup
+	for _, pair := range pairs {
+		wg.Add(1)
+		go func(pair string) {
+			defer wg.Done()
+			if err := c.WebSocket.SubscribeTicker(pair, tickers); err != nil {
+				t.Error(pair, err)
+			}
+		}(pair)
+	}
+	wg.Wait()
+	if err := c.WebSocket.SubscribeTicker(BTCUSD, tickers); err != ErrAlreadySubscribed {
+		t.Error("Expected", ErrAlreadySubscribed)
+		t.Error("Actual ", err)
+	}
+
+	for range pairs {
+		select {
+		case v := <-tickers:
+			if v.LastPrice != 450 {
+				t.Error("Unexpected ticker", v)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the tickers added at runtime")
+		}
+	}
+}
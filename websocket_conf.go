@@ -0,0 +1,165 @@
+package bitfinex
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// Flags of the conf event
+const (
+	// Adds the server timestamp in milliseconds to each data message
+	CONF_TIMESTAMP = 32768
+	// Adds a sequence number to each data message
+	CONF_SEQ_ALL = 65536
+)
+
+type confMsg struct {
+	Event  string `json:"event"`
+	Flags  int    `json:"flags"`
+	Status string `json:"status,omitempty"`
+}
+
+// Configure enables the features given as CONF_* flags for the public
+// connection. The flags are sent right away when connected and again
+// after every reconnect, before the subscriptions are replayed.
+//
+// With CONF_SEQ_ALL, Subscribe checks that messages arrive without gaps.
+// Sequence numbers are shared by all the channels of a connection, so the
+// channel of a missing message is unknown: on a gap every channel is
+// unsubscribed and subscribed again for a fresh snapshot. Consumers are
+// told like after a reconnect, e.g. SubscribeBook delivers a book with
+// Reset set, and OnSequenceGap is called.
+func (w *WebSocketService) Configure(flags int) error {
+	w.mu.Lock()
+	w.flags = flags
+	connected := w.ws != nil
+	w.mu.Unlock()
+	if !connected {
+		return nil
+	}
+	return w.sendConf()
+}
+
+func (w *WebSocketService) sendConf() error {
+	w.mu.Lock()
+	flags := w.flags
+	w.mu.Unlock()
+	msg, _ := json.Marshal(confMsg{Event: "conf", Flags: flags})
+	return w.write(msg)
+}
+
+func (w *WebSocketService) confFlags() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flags
+}
+
+// ClockSkew estimates how far the server clock is ahead of the local one,
+// from the server time of the last frame received with CONF_TIMESTAMP. The
+// estimate includes the network latency. It is 0 before the first frame.
+func (w *WebSocketService) ClockSkew() time.Duration {
+	return time.Duration(atomic.LoadInt64(&w.skew))
+}
+
+// SequenceGapError reports messages missing from the public connection.
+type SequenceGapError struct {
+	Expected int64
+	Got      int64
+}
+
+func (e *SequenceGapError) Error() string {
+	return fmt.Sprintf("sequence gap: expected %d, got %d", e.Expected, e.Got)
+}
+
+// handleConfEvent checks the reply to a conf event.
+func handleConfEvent(msg []byte, unmarshal func([]byte, interface{}) error) error {
+	var conf confMsg
+	if err := unmarshal(msg, &conf); err != nil {
+		return nil
+	}
+	if conf.Status != "" && conf.Status != "OK" {
+		return fmt.Errorf("conf %d failed: %s", conf.Flags, conf.Status)
+	}
+	return nil
+}
+
+type unsubscribeMsg struct {
+	Event  string  `json:"event"`
+	Status string  `json:"status,omitempty"`
+	ChanId float64 `json:"chanId"`
+}
+
+// resnapshot resubscribes every linked channel after a sequence gap.
+func (w *WebSocketService) resnapshot(gap *SequenceGapError) error {
+	if w.OnSequenceGap != nil {
+		w.OnSequenceGap(gap)
+	} else {
+		log.Println("Resubscribing after", gap)
+	}
+	w.mu.Lock()
+	linked := make(map[float64]*subscribeToChannel, len(w.chanMap))
+	for chanId, s := range w.chanMap {
+		linked[chanId] = s
+	}
+	w.mu.Unlock()
+	for chanId, s := range linked {
+		msg, _ := json.Marshal(unsubscribeMsg{Event: "unsubscribe", ChanId: chanId})
+		if err := w.write(msg); err != nil {
+			return err
+		}
+		w.mu.Lock()
+		delete(w.chanMap, chanId)
+		w.resubscribing[chanId] = s
+		w.mu.Unlock()
+		w.resetSub(s)
+	}
+	return nil
+}
+
+// handleUnsubscribed subscribes a channel resubscribed by resnapshot again.
+func (w *WebSocketService) handleUnsubscribed(msg []byte) error {
+	var event unsubscribeMsg
+	if err := w.unmarshal(msg, &event); err != nil {
+		return nil
+	}
+	w.mu.Lock()
+	s, ok := w.resubscribing[event.ChanId]
+	if !ok {
+		w.mu.Unlock()
+		return nil
+	}
+	delete(w.resubscribing, event.ChanId)
+	w.retire(event.ChanId)
+	cleared := !w.isSubscribed(s)
+	w.mu.Unlock()
+	if cleared {
+		return nil
+	}
+	return w.sendSubscribe(s)
+}
+
+// trailer removes the sequence number and timestamp the conf flags append
+// to a data message, checking the sequence.
+func (w *WebSocketService) trailer(payload []interface{}, f *dataFrame) ([]interface{}, error) {
+	flags := w.confFlags()
+	if flags&CONF_TIMESTAMP != 0 && len(payload) > 2 {
+		ts, _ := payload[len(payload)-1].(float64)
+		f.ServerTime = time.Unix(0, int64(ts)*int64(time.Millisecond))
+		atomic.StoreInt64(&w.skew, int64(time.Until(f.ServerTime)))
+		payload = payload[:len(payload)-1]
+	}
+	if flags&CONF_SEQ_ALL != 0 && len(payload) > 2 {
+		seq, _ := payload[len(payload)-1].(float64)
+		payload = payload[:len(payload)-1]
+		gap := w.seq != 0 && int64(seq) != w.seq+1
+		expected := w.seq + 1
+		w.seq = int64(seq)
+		if gap {
+			return payload, &SequenceGapError{Expected: expected, Got: int64(seq)}
+		}
+	}
+	return payload, nil
+}
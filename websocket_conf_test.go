@@ -0,0 +1,106 @@
+package bitfinex
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestConfigureSequence(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		if msg := readSubscribe(t, ws); msg.Event != "conf" {
+			t.Error("Expected conf before subscribing, got", msg)
+		}
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"conf","status":"OK","flags":98304}`,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1.5]],1,1600000000000]`,
+			`[5,450.5,1,0.5,2,1600000000001]`,
+			`[5,"hb",3,1600000000002]`,
+			`[5,451,1,-1,5,1600000000003]`,
+			`[5,453,1,-1,6,1600000000004]`,
+		)
+		if msg := readSubscribe(t, ws); msg.Event != "unsubscribe" || msg.ChanId != 5 {
+			t.Error("Expected unsubscribe of chanId 5, got", msg)
+		}
+		writeFrames(ws, `{"event":"unsubscribed","status":"OK","chanId":5}`)
+		if msg := readSubscribe(t, ws); msg.Event != "subscribe" || msg.Pair != BTCUSD {
+			t.Error("Expected subscribe again, got", msg)
+		}
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[452,1,1]],7,1600000000005]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	gaps := make(chan *SequenceGapError, 1)
+	c.WebSocket.OnSequenceGap = func(gap *SequenceGapError) { gaps <- gap }
+	if err := c.WebSocket.Configure(CONF_SEQ_ALL | CONF_TIMESTAMP); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	events := make(chan MarketEvent, 10)
+	c.WebSocket.AddSubscribeEvents(CHAN_BOOK, BTCUSD, 25, events)
+	go c.WebSocket.Subscribe()
+
+	if e := receiveEvent(t, events); !e.Snapshot || e.ServerTime.UnixNano() != 1600000000000*1e6 {
+		t.Error("Unexpected snapshot", e)
+	}
+	if e := receiveEvent(t, events); e.Data.([][]float64)[0][0] != 450.5 || e.ServerTime.UnixNano() != 1600000000001*1e6 {
+		t.Error("Unexpected update", e)
+	}
+	// the messages after the gap are dropped until the fresh snapshot
+	if e := receiveEvent(t, events); !e.Snapshot || e.Data.([][]float64)[0][0] != 452 {
+		t.Error("Expected a fresh snapshot, got", e)
+	}
+	if gap := <-gaps; gap.Expected != 4 || gap.Got != 5 {
+		t.Error("Expected", &SequenceGapError{Expected: 4, Got: 5})
+		t.Error("Actual ", gap)
+	}
+}
+
+func TestServerTime(t *testing.T) {
+	serverTime := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			fmt.Sprintf(`[3,449,1,451,2,-1,-0.01,450,1000,460,440,%d]`, serverTime.UnixNano()/1e6),
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.Configure(CONF_TIMESTAMP)
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	tickers := make(chan TickerUpdate, 1)
+	c.WebSocket.SubscribeTicker(BTCUSD, tickers)
+	go c.WebSocket.Subscribe()
+
+	select {
+	case v := <-tickers:
+		if !v.ServerTime.Equal(serverTime) || v.Low != 440 {
+			t.Error("Expected", serverTime)
+			t.Error("Actual ", v.ServerTime, v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for ticker")
+	}
+	if skew := c.WebSocket.ClockSkew(); skew < 59*time.Minute || skew > time.Hour {
+		t.Error("Expected a skew of about an hour, got", skew)
+	}
+}
@@ -0,0 +1,135 @@
+package bitfinex
+
+import (
+	"strconv"
+)
+
+// scanNumericFrame decodes data frames made of numbers only, which is most
+// of the book and ticker traffic, without going through interface{}
+// values. Anything else, like heartbeats or trades terms, is reported as
+// not ok and left to the generic decoder.
+func scanNumericFrame(p []byte) (float64, dataFrame, bool) {
+	s := frameScanner{p: p}
+	if !s.consume('[') {
+		return 0, dataFrame{}, false
+	}
+	chanId, ok := s.number()
+	if !ok || !s.consume(',') {
+		return 0, dataFrame{}, false
+	}
+
+	if s.peek() != '[' {
+		// Book or ticker update
+		row, ok := s.numbers()
+		if !ok || !s.end() {
+			return 0, dataFrame{}, false
+		}
+		return chanId, dataFrame{Rows: [][]float64{row}}, true
+	}
+
+	s.consume('[')
+	if c := s.peek(); c != '[' && c != ']' {
+		// v2 update: [...]
+		row, ok := s.numbers()
+		if !ok || !s.consume(']') || !s.end() {
+			return 0, dataFrame{}, false
+		}
+		return chanId, dataFrame{Rows: [][]float64{row}}, true
+	}
+
+	// Snapshot: [[...], [...], ...]
+	rows := make([][]float64, 0, 8)
+	if !s.consume(']') {
+		for {
+			if !s.consume('[') {
+				return 0, dataFrame{}, false
+			}
+			row, ok := s.numbers()
+			if !ok {
+				return 0, dataFrame{}, false
+			}
+			rows = append(rows, row)
+			if s.consume(']') {
+				break
+			}
+			if !s.consume(',') {
+				return 0, dataFrame{}, false
+			}
+		}
+	}
+	if !s.consume(']') || !s.end() {
+		return 0, dataFrame{}, false
+	}
+	return chanId, dataFrame{Snapshot: true, Rows: rows}, true
+}
+
+type frameScanner struct {
+	p []byte
+	i int
+}
+
+func (s *frameScanner) skipSpace() {
+	for s.i < len(s.p) && (s.p[s.i] == ' ' || s.p[s.i] == '\n' || s.p[s.i] == '\r' || s.p[s.i] == '\t') {
+		s.i++
+	}
+}
+
+func (s *frameScanner) peek() byte {
+	s.skipSpace()
+	if s.i == len(s.p) {
+		return 0
+	}
+	return s.p[s.i]
+}
+
+func (s *frameScanner) consume(c byte) bool {
+	if s.peek() != c {
+		return false
+	}
+	s.i++
+	return true
+}
+
+func (s *frameScanner) end() bool {
+	s.skipSpace()
+	return s.i == len(s.p)
+}
+
+func (s *frameScanner) number() (float64, bool) {
+	s.skipSpace()
+	start := s.i
+	for s.i < len(s.p) {
+		c := s.p[s.i]
+		if (c < '0' || c > '9') && c != '-' && c != '+' && c != '.' && c != 'e' && c != 'E' {
+			break
+		}
+		s.i++
+	}
+	if start == s.i {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(string(s.p[start:s.i]), 64)
+	return f, err == nil
+}
+
+// numbers reads comma separated numbers up to and including the closing
+// bracket.
+func (s *frameScanner) numbers() ([]float64, bool) {
+	row := make([]float64, 0, 4)
+	if s.consume(']') {
+		return row, true
+	}
+	for {
+		f, ok := s.number()
+		if !ok {
+			return nil, false
+		}
+		row = append(row, f)
+		if s.consume(']') {
+			return row, true
+		}
+		if !s.consume(',') {
+			return nil, false
+		}
+	}
+}
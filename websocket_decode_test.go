@@ -0,0 +1,113 @@
+package bitfinex
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// bookStream is a recorded BTCUSD book subscription: the confirmation, a
+// snapshot, heartbeats and level updates.
+var bookStream = [][]byte{
+	[]byte(`{"event":"subscribed","channel":"book","chanId":67,"prec":"P0","freq":"F0","len":"25","pair":"BTCUSD"}`),
+	[]byte(`[67,[[6385,1,0.2],[6384.9,2,1.38822348],[6384.5,1,0.5],[6384,3,2.0625],[6383.7,1,0.1],[6386,1,-0.46],[6386.2,2,-1.2],[6387,1,-0.03],[6388.1,4,-3.5],[6389,1,-0.25]]]`),
+	[]byte(`[67,6385,2,0.25]`),
+	[]byte(`[67,6384.9,0,1]`),
+	[]byte(`[67,"hb"]`),
+	[]byte(`[67,6386,2,-0.5]`),
+	[]byte(`[67,6387,0,-1]`),
+	[]byte(`[67,6384.8,1,0.75]`),
+	[]byte(`[67,6386.5,1,-0.12]`),
+	[]byte(`[67,6385,1,0.2]`),
+}
+
+// decodeGeneric is how handleDataMessage decodes frames the scanner rejects.
+func decodeGeneric(p []byte) (float64, dataFrame, bool) {
+	var payload []interface{}
+	if err := json.Unmarshal(p, &payload); err != nil || len(payload) < 2 {
+		return 0, dataFrame{}, false
+	}
+	chanId, _ := payload[0].(float64)
+	f, ok := decodeDataFrame(payload[1:])
+	return chanId, f, ok
+}
+
+func TestScanNumericFrame(t *testing.T) {
+	cases := []struct {
+		frame []byte
+		// ok is false for the frames left to the generic decoder
+		ok bool
+	}{
+		{bookStream[1], true},
+		{bookStream[2], true},
+		{bookStream[3], true},
+		{bookStream[4], false},
+		{bookStream[5], true},
+		{bookStream[6], true},
+		{bookStream[7], true},
+		{bookStream[8], true},
+		{bookStream[9], true},
+		{[]byte(`[5,[]]`), true},
+		{[]byte(` [ 5 , [ [1e-8, 2] , [3,-4.5] ] ] `), true},
+		{[]byte(`[2,449,10,450,12,1,0.01,449.5,1000,455,440]`), true},
+		{[]byte(`[7,[1364824380000,4,5,6,3,1.5]]`), true},
+		{[]byte(`[67,"hb"]`), false},
+		{[]byte(`[1,"te","1-2",3,4,5]`), false},
+		{[]byte(`[5,[[1,2],"x"]]`), false},
+		{[]byte(`[5,[1,2]`), false},
+		{[]byte(`[5,1,2`), false},
+		{[]byte(`{"event":"info"}`), false},
+	}
+	for _, c := range cases {
+		chanId, f, ok := scanNumericFrame(c.frame)
+		if ok != c.ok {
+			t.Error("Expected ok", c.ok, "for", string(c.frame))
+			t.Error("Actual ", ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		wantId, want, _ := decodeGeneric(c.frame)
+		if chanId != wantId || !reflect.DeepEqual(f, want) {
+			t.Error("Expected", wantId, want)
+			t.Error("Actual ", chanId, f)
+		}
+	}
+}
+
+func BenchmarkBookStream(b *testing.B) {
+	w := NewClient().WebSocket
+	c := make(chan [][]float64, len(bookStream))
+	w.AddSubscribe(CHAN_BOOK, BTCUSD, 25, c)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range bookStream {
+			w.handleMessage(p)
+		}
+		for len(c) > 0 {
+			<-c
+		}
+	}
+}
+
+// BenchmarkDecodeFrame compares the numeric scanner with the json decoding
+// of the same book frames.
+func BenchmarkDecodeFrame(b *testing.B) {
+	decoders := map[string]func([]byte) (float64, dataFrame, bool){
+		"scan":    scanNumericFrame,
+		"generic": decodeGeneric,
+	}
+	for name, decode := range decoders {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				for _, p := range bookStream[1:] {
+					decode(p)
+				}
+			}
+		})
+	}
+}
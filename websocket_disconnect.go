@@ -0,0 +1,83 @@
+package bitfinex
+
+import (
+	"errors"
+
+	"github.com/gorilla/websocket"
+)
+
+// DisconnectReason classifies why a connection was closed, from the code
+// of the close frame the server sent.
+type DisconnectReason string
+
+const (
+	DISCONNECT_NORMAL           DisconnectReason = "normal"
+	DISCONNECT_GOING_AWAY       DisconnectReason = "going away"
+	DISCONNECT_PROTOCOL_ERROR   DisconnectReason = "protocol error"
+	DISCONNECT_UNSUPPORTED_DATA DisconnectReason = "unsupported data"
+	DISCONNECT_POLICY_VIOLATION DisconnectReason = "policy violation"
+	DISCONNECT_MESSAGE_TOO_BIG  DisconnectReason = "message too big"
+	DISCONNECT_INTERNAL_ERROR   DisconnectReason = "internal error"
+	DISCONNECT_SERVICE_RESTART  DisconnectReason = "service restart"
+	DISCONNECT_TRY_AGAIN_LATER  DisconnectReason = "try again later"
+	// The connection ended without a close frame
+	DISCONNECT_ABNORMAL DisconnectReason = "abnormal"
+	// A close frame with another code
+	DISCONNECT_OTHER DisconnectReason = "other"
+	// The read failed below the websocket protocol, e.g. a network error
+	// or a connection closed locally
+	DISCONNECT_NETWORK DisconnectReason = "network"
+)
+
+var disconnectReasons = map[int]DisconnectReason{
+	websocket.CloseNormalClosure:           DISCONNECT_NORMAL,
+	websocket.CloseGoingAway:               DISCONNECT_GOING_AWAY,
+	websocket.CloseProtocolError:           DISCONNECT_PROTOCOL_ERROR,
+	websocket.CloseUnsupportedData:         DISCONNECT_UNSUPPORTED_DATA,
+	websocket.CloseAbnormalClosure:         DISCONNECT_ABNORMAL,
+	websocket.ClosePolicyViolation:         DISCONNECT_POLICY_VIOLATION,
+	websocket.CloseMessageTooBig:           DISCONNECT_MESSAGE_TOO_BIG,
+	websocket.CloseInternalServerErr:       DISCONNECT_INTERNAL_ERROR,
+	websocket.CloseServiceRestart:          DISCONNECT_SERVICE_RESTART,
+	websocket.CloseTryAgainLater:           DISCONNECT_TRY_AGAIN_LATER,
+	websocket.CloseInvalidFramePayloadData: DISCONNECT_UNSUPPORTED_DATA,
+}
+
+// Disconnect describes a connection that broke, for OnDisconnect.
+type Disconnect struct {
+	// Private is set for the private connection
+	Private bool
+	Reason  DisconnectReason
+	// Code and Text are those of the close frame, zero without one
+	Code int
+	Text string
+	// Err is the read error, returned by Subscribe or reported by
+	// ConnectPrivate unless the connection is re-established
+	Err error
+}
+
+// DisconnectReasonOf classifies a read error returned by Subscribe or
+// wrapped in a *ReconnectError.
+func DisconnectReasonOf(err error) DisconnectReason {
+	var ce *websocket.CloseError
+	if !errors.As(err, &ce) {
+		return DISCONNECT_NETWORK
+	}
+	if reason, ok := disconnectReasons[ce.Code]; ok {
+		return reason
+	}
+	return DISCONNECT_OTHER
+}
+
+// disconnected reports a read error of a connection to OnDisconnect.
+func (w *WebSocketService) disconnected(private bool, err error) {
+	if w.OnDisconnect == nil {
+		return
+	}
+	d := Disconnect{Private: private, Reason: DisconnectReasonOf(err), Err: err}
+	var ce *websocket.CloseError
+	if errors.As(err, &ce) {
+		d.Code, d.Text = ce.Code, ce.Text
+	}
+	w.OnDisconnect(d)
+}
@@ -0,0 +1,57 @@
+package bitfinex
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestOnDisconnect(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "too many subscriptions"))
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	var disconnects []Disconnect
+	c.WebSocket.OnDisconnect = func(d Disconnect) {
+		disconnects = append(disconnects, d)
+	}
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64))
+
+	err := c.WebSocket.Subscribe()
+	if reason := DisconnectReasonOf(err); reason != DISCONNECT_POLICY_VIOLATION {
+		t.Error("Expected", DISCONNECT_POLICY_VIOLATION)
+		t.Error("Actual ", reason)
+	}
+	if len(disconnects) != 1 {
+		t.Fatal("Expected one disconnect, got", disconnects)
+	}
+	d := disconnects[0]
+	if d.Private || d.Reason != DISCONNECT_POLICY_VIOLATION || d.Code != websocket.ClosePolicyViolation || d.Text != "too many subscriptions" || d.Err != err {
+		t.Error("Unexpected disconnect", d)
+	}
+}
+
+func TestDisconnectReasonOf(t *testing.T) {
+	reasons := map[error]DisconnectReason{
+		&websocket.CloseError{Code: websocket.CloseNormalClosure}:                                DISCONNECT_NORMAL,
+		&websocket.CloseError{Code: websocket.CloseServiceRestart}:                               DISCONNECT_SERVICE_RESTART,
+		&websocket.CloseError{Code: websocket.CloseAbnormalClosure}:                              DISCONNECT_ABNORMAL,
+		&websocket.CloseError{Code: 4000}:                                                        DISCONNECT_OTHER,
+		&ReconnectError{Attempts: 1, Err: &websocket.CloseError{Code: websocket.CloseGoingAway}}: DISCONNECT_GOING_AWAY,
+		errors.New("use of closed network connection"):                                           DISCONNECT_NETWORK,
+	}
+	for err, expected := range reasons {
+		if reason := DisconnectReasonOf(err); reason != expected {
+			t.Error("Expected", expected, "for", err)
+			t.Error("Actual ", reason)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package bitfinex
+
+import "time"
+
+// MarketEvent is a public channel update delivered by AddSubscribeEvents.
+type MarketEvent struct {
+	ChanId   float64
+	Channel  string
+	Pair     string
+	Snapshot bool
+	// ServerTime is the time the server sent the data, set with CONF_TIMESTAMP.
+	ServerTime time.Time
+	// Data holds the typed payload for the channel:
+	//   ticker: TickerUpdate
+	//   trades: TradeUpdate, one event per trade
+	//   book:   [][]float64 of [PRICE, COUNT, AMOUNT] levels
+	Data interface{}
+}
+
+// AddSubscribeEvents adds a subscription delivering MarketEvents to c once
+// Subscribe is called. Several subscriptions can share the same channel,
+// multiplexing the whole feed into one select loop like the private
+// channel's TermData.
+func (w *WebSocketService) AddSubscribeEvents(channel string, pair string, length int, c chan MarketEvent) error {
+	s := &subscribeToChannel{
+		Channel: channel,
+		Pair:    pair,
+		Len:     length,
+		out:     c,
+	}
+	s.deliver = func(f dataFrame) {
+		event := MarketEvent{
+			ChanId:     s.chanId,
+			Channel:    s.Channel,
+			Pair:       s.Pair,
+			Snapshot:   f.Snapshot,
+			ServerTime: f.ServerTime,
+		}
+		switch s.Channel {
+		case CHAN_TICKER:
+			t, ok := decodeTicker(f)
+			if !ok {
+				return
+			}
+			event.Data = t
+		case CHAN_TRADE:
+			for _, t := range decodeTrades(f) {
+				event.Data = t
+				c <- event
+			}
+			return
+		default:
+			event.Data = f.Rows
+		}
+		c <- event
+	}
+	return w.addSubscribe(s)
+}
@@ -0,0 +1,94 @@
+package bitfinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestAddSubscribeEvents(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"book","chanId":4,"pair":"BTCUSD"}`,
+			`[4,[[449,2,1.5]]]`,
+			`[3,449,1,451,2,-1,-0.01,450,1000,460,440]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	events := make(chan MarketEvent, 10)
+	c.WebSocket.AddSubscribeEvents(CHAN_TICKER, BTCUSD, 0, events)
+	c.WebSocket.AddSubscribeEvents(CHAN_BOOK, BTCUSD, 25, events)
+	go c.WebSocket.Subscribe()
+
+	e := receiveEvent(t, events)
+	rows, ok := e.Data.([][]float64)
+	if e.ChanId != 4 || e.Channel != CHAN_BOOK || !e.Snapshot || !ok || len(rows) != 1 {
+		t.Error("Unexpected book event", e)
+	}
+
+	e = receiveEvent(t, events)
+	ticker, ok := e.Data.(TickerUpdate)
+	if e.ChanId != 3 || e.Pair != BTCUSD || e.Snapshot || !ok || ticker.LastPrice != 450 || ticker.Low != 440 {
+		t.Error("Unexpected ticker event", e)
+	}
+}
+
+func receiveEvent(t *testing.T, c chan MarketEvent) MarketEvent {
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+	return MarketEvent{}
+}
+
+func TestCloseChannelsOnError(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":2,"pair":"BTCUSD"}`,
+			`[2,449,10,450,12,1,0.01,449.5,1000,455,440]`,
+		)
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+	c.WebSocket.CloseChannelsOnError = true
+
+	events := make(chan MarketEvent, 10)
+	c.WebSocket.AddSubscribeEvents(CHAN_TICKER, BTCUSD, 0, events)
+	c.WebSocket.AddSubscribeEvents(CHAN_TRADE, BTCUSD, 0, events)
+
+	done := make(chan error)
+	go func() { done <- c.WebSocket.Subscribe() }()
+
+	var n int
+	for range events {
+		n++
+	}
+	if n != 1 {
+		t.Error("Expected 1 event before the channel was closed, got", n)
+	}
+	if err := <-done; err == nil {
+		t.Error("Expected Subscribe to return the read error")
+	}
+	if len(c.WebSocket.subscribes) != 0 {
+		t.Error("Expected subscriptions to be cleared")
+	}
+}
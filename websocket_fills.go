@@ -0,0 +1,133 @@
+package bitfinex
+
+import (
+	"math"
+	"sync"
+)
+
+// Fill is an execution of one of the account's orders, decoded from a
+// private "tu" term:
+// [SEQ, TRADE_ID, PAIR, TIMESTAMP, ORDER_ID, AMOUNT, PRICE, ORDER_TYPE,
+// ORDER_PRICE, FEE, FEE_CURRENCY]
+type Fill struct {
+	TradeId   int64
+	Pair      string
+	Timestamp int64
+	OrderId   int64
+	// Amount is positive for buys and negative for sells
+	Amount      float64
+	Price       float64
+	Fee         float64
+	FeeCurrency string
+}
+
+func decodeFill(d TermData) (Fill, bool) {
+	if d.Term != "tu" || len(d.Data) < 7 {
+		return Fill{}, false
+	}
+	num := func(i int) float64 {
+		if i >= len(d.Data) {
+			return 0
+		}
+		f, _ := d.Data[i].(float64)
+		return f
+	}
+	f := Fill{
+		TradeId:   int64(num(1)),
+		Timestamp: int64(num(3)),
+		OrderId:   int64(num(4)),
+		Amount:    num(5),
+		Price:     num(6),
+		Fee:       num(9),
+	}
+	f.Pair, _ = d.Data[2].(string)
+	if len(d.Data) > 10 {
+		f.FeeCurrency, _ = d.Data[10].(string)
+	}
+	return f, f.OrderId != 0
+}
+
+// OrderFills sums up the fills of an order.
+type OrderFills struct {
+	OrderId int64
+	// Filled is the executed amount, negative for sell orders
+	Filled float64
+	// AvgPrice is the volume weighted price of the fills
+	AvgPrice float64
+	// Fees are summed regardless of their currency
+	Fees  float64
+	Fills []Fill
+}
+
+// FillTracker correlates the fills of the private feed with their orders.
+// Pass every TermData received from ConnectPrivate to Apply. Fills seen
+// twice, e.g. when replayed after a reconnect, are counted once.
+type FillTracker struct {
+	mu     sync.Mutex
+	orders map[int64]*OrderFills
+	trades map[int64]bool
+}
+
+func NewFillTracker() *FillTracker {
+	return &FillTracker{
+		orders: make(map[int64]*OrderFills),
+		trades: make(map[int64]bool),
+	}
+}
+
+// Apply records d if it is a fill and returns the updated fills of its
+// order. It reports false for any other term.
+func (t *FillTracker) Apply(d TermData) (OrderFills, bool) {
+	f, ok := decodeFill(d)
+	if !ok {
+		return OrderFills{}, false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	o, exists := t.orders[f.OrderId]
+	if !exists {
+		o = &OrderFills{OrderId: f.OrderId}
+		t.orders[f.OrderId] = o
+	}
+	if !t.trades[f.TradeId] {
+		t.trades[f.TradeId] = true
+		size := math.Abs(o.Filled) + math.Abs(f.Amount)
+		if size > 0 {
+			o.AvgPrice = (o.AvgPrice*math.Abs(o.Filled) + f.Price*math.Abs(f.Amount)) / size
+		}
+		o.Filled += f.Amount
+		o.Fees += f.Fee
+		o.Fills = append(o.Fills, f)
+	}
+	return o.copy(), true
+}
+
+// Fills returns the fills recorded for orderId.
+func (t *FillTracker) Fills(orderId int64) (OrderFills, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	o, ok := t.orders[orderId]
+	if !ok {
+		return OrderFills{}, false
+	}
+	return o.copy(), true
+}
+
+// Forget drops the fills of an order that is no longer of interest.
+func (t *FillTracker) Forget(orderId int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if o, ok := t.orders[orderId]; ok {
+		for _, f := range o.Fills {
+			delete(t.trades, f.TradeId)
+		}
+		delete(t.orders, orderId)
+	}
+}
+
+func (o *OrderFills) copy() OrderFills {
+	c := *o
+	c.Fills = append([]Fill(nil), o.Fills...)
+	return c
+}
@@ -0,0 +1,42 @@
+package bitfinex
+
+import (
+	"testing"
+)
+
+func TestFillTracker(t *testing.T) {
+	terms := []TermData{
+		{Term: "te", Data: []interface{}{"1-2", "BTCUSD", 1444276597.0, 900.0, 0.5, 450.0, "LIMIT", 451.0}},
+		{Term: "tu", Data: []interface{}{"1-2", 11.0, "BTCUSD", 1444276597.0, 900.0, 0.5, 450.0, "LIMIT", 451.0, -0.1, "USD"}},
+		{Term: "tu", Data: []interface{}{"1-3", 12.0, "BTCUSD", 1444276598.0, 900.0, 1.5, 451.0, "LIMIT", 451.0, -0.3, "USD"}},
+		// replayed after a reconnect
+		{Term: "tu", Data: []interface{}{"1-3", 12.0, "BTCUSD", 1444276598.0, 900.0, 1.5, 451.0, "LIMIT", 451.0, -0.3, "USD"}},
+		{Term: "tu", Data: []interface{}{"1-4", 13.0, "BTCUSD", 1444276599.0, 901.0, -1.0, 452.0, "LIMIT", 452.0, -0.2, "USD"}},
+		{Term: "ws", Data: []interface{}{"exchange", "BTC", 0.01, 0.0}},
+	}
+
+	tracker := NewFillTracker()
+	var applied int
+	for _, d := range terms {
+		if _, ok := tracker.Apply(d); ok {
+			applied++
+		}
+	}
+	if applied != 4 {
+		t.Error("Expected", 4)
+		t.Error("Actual ", applied)
+	}
+
+	fills, ok := tracker.Fills(900)
+	if !ok || fills.Filled != 2 || fills.AvgPrice != 450.75 || len(fills.Fills) != 2 || fills.Fees != -0.4 {
+		t.Error("Unexpected fills of order 900", fills)
+	}
+	if fills, _ := tracker.Fills(901); fills.Filled != -1 || fills.AvgPrice != 452 || fills.Fills[0].FeeCurrency != "USD" {
+		t.Error("Unexpected fills of order 901", fills)
+	}
+
+	tracker.Forget(900)
+	if _, ok := tracker.Fills(900); ok {
+		t.Error("Expected the fills of order 900 to be dropped")
+	}
+}
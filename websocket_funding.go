@@ -0,0 +1,84 @@
+package bitfinex
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// FundingOfferRequest is a funding offer sent with SendFundingOfferNew.
+type FundingOfferRequest struct {
+	// Currency is the funding currency, e.g. USD
+	Currency string
+	// Amount is the positive amount offered
+	Amount float64
+	// Rate is the daily rate, e.g. 0.0002 for 0.02%
+	Rate float64
+	// Period is the number of days the funding is offered for
+	Period int64
+	// Direction is LEND to offer funds, LOAN to ask for them, LEND when
+	// empty
+	Direction string
+	// Hidden keeps the offer off the public funding book
+	Hidden bool
+}
+
+// wsPayload converts the offer into the fields of a v2 funding offer input.
+func (r FundingOfferRequest) wsPayload() (map[string]interface{}, error) {
+	if r.Currency == "" {
+		return nil, errors.New("funding offer without currency")
+	}
+	if r.Amount <= 0 {
+		return nil, errors.New("funding offer amount must be positive")
+	}
+	if r.Period <= 0 {
+		return nil, errors.New("funding offer period must be positive")
+	}
+	amount := r.Amount
+	switch r.Direction {
+	case "", LEND:
+	case LOAN:
+		amount = -amount
+	default:
+		return nil, errors.New("funding offer direction must be " + LEND + " or " + LOAN)
+	}
+
+	fields := map[string]interface{}{
+		"type":   "LIMIT",
+		"symbol": "f" + strings.ToUpper(r.Currency),
+		"amount": strconv.FormatFloat(amount, 'f', -1, 64),
+		"rate":   strconv.FormatFloat(r.Rate, 'f', -1, 64),
+		"period": r.Period,
+	}
+	if r.Hidden {
+		fields["flags"] = orderFlagHidden
+	}
+	return fields, nil
+}
+
+// SendFundingOfferNew sends a new funding offer on the private connection,
+// which must be established with ConnectPrivate. The offer is confirmed by
+// a fon term on the private feed, or refused by a fon-req notification.
+func (w *WebSocketService) SendFundingOfferNew(req FundingOfferRequest) error {
+	fields, err := req.wsPayload()
+	if err != nil {
+		return err
+	}
+	msg, err := json.Marshal([]interface{}{0, "fon", nil, fields})
+	if err != nil {
+		return err
+	}
+	return w.writePrivate(msg)
+}
+
+// SendFundingOfferCancel cancels the funding offer with id on the private
+// connection. The cancellation is confirmed by a foc term on the private
+// feed.
+func (w *WebSocketService) SendFundingOfferCancel(id int64) error {
+	msg, err := json.Marshal([]interface{}{0, "foc", nil, map[string]interface{}{"id": id}})
+	if err != nil {
+		return err
+	}
+	return w.writePrivate(msg)
+}
@@ -0,0 +1,61 @@
+package bitfinex
+
+import (
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSendFundingOffer(t *testing.T) {
+	sent := make(chan []interface{}, 2)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeFrames(ws, `[0,"ws",[]]`)
+		for i := 0; i < 2; i++ {
+			var msg []interface{}
+			ws.ReadJSON(&msg)
+			sent <- msg
+		}
+		writeFrames(ws,
+			`[0,"fon",[41,"fUSD",1573000000000,1573000000000,-100,-100,"LIMIT",null,null,0,"ACTIVE",null,null,null,0.0002,30,0,0,null,0,null]]`,
+			`[0,"foc",[41,"fUSD",1573000000000,1573000000000,-100,-100,"LIMIT",null,null,0,"CANCELED",null,null,null,0.0002,30,0,0,null,0,null]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+	receiveTerm(t, terms)
+
+	offer := FundingOfferRequest{Currency: "usd", Amount: 100, Rate: 0.0002, Period: 30, Direction: LOAN}
+	if err := c.WebSocket.SendFundingOfferNew(FundingOfferRequest{Currency: "USD", Period: 30}); err == nil {
+		t.Error("Expected an error for an offer without amount")
+	}
+	if err := c.WebSocket.SendFundingOfferNew(offer); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WebSocket.SendFundingOfferCancel(41); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-sent
+	fields, _ := msg[3].(map[string]interface{})
+	if msg[1] != "fon" || fields["type"] != "LIMIT" || fields["symbol"] != "fUSD" || fields["amount"] != "-100" ||
+		fields["rate"] != "0.0002" || fields["period"] != float64(30) || fields["flags"] != nil {
+		t.Error("Unexpected funding offer input", msg)
+	}
+	msg = <-sent
+	fields, _ = msg[3].(map[string]interface{})
+	if msg[1] != "foc" || fields["id"] != float64(41) {
+		t.Error("Unexpected funding offer cancel", msg)
+	}
+
+	for _, term := range []string{"fon", "foc"} {
+		if v := receiveTerm(t, terms); v.Term != term || v.Data[0] != float64(41) {
+			t.Error("Expected", term, "of offer 41")
+			t.Error("Actual ", v)
+		}
+	}
+}
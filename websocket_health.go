@@ -0,0 +1,103 @@
+package bitfinex
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// HealthStatus is a snapshot of the state of the public connection, meant
+// to be served as JSON by a monitoring endpoint.
+type HealthStatus struct {
+	Connected   bool `json:"connected"`
+	Maintenance bool `json:"maintenance"`
+	// Reconnects counts the successful reconnects since the service was
+	// created
+	Reconnects int64 `json:"reconnects"`
+	// LastError is the last error that broke the read loop
+	LastError string `json:"last_error,omitempty"`
+	// Subscriptions is the number of subscriptions, Confirmed of them have
+	// been acknowledged by Bitfinex and Pending are waiting for it
+	Subscriptions int             `json:"subscriptions"`
+	Confirmed     int             `json:"confirmed"`
+	Pending       int             `json:"pending"`
+	Channels      []ChannelHealth `json:"channels"`
+}
+
+// ChannelHealth describes a single subscription.
+type ChannelHealth struct {
+	Channel   string  `json:"channel"`
+	Pair      string  `json:"pair"`
+	ChanId    float64 `json:"chan_id,omitempty"`
+	Confirmed bool    `json:"confirmed"`
+	// LastFrame is when the last data frame was delivered, zero before
+	// the first one
+	LastFrame time.Time `json:"last_frame"`
+}
+
+// Health reports the state of the public connection. It is safe to call
+// from any goroutine while Subscribe runs.
+func (w *WebSocketService) Health() HealthStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	h := HealthStatus{
+		Connected:     w.connected,
+		Maintenance:   w.maintenance,
+		Reconnects:    atomic.LoadInt64(&w.reconnects),
+		Subscriptions: len(w.subscribes),
+		Channels:      make([]ChannelHealth, 0, len(w.subscribes)),
+	}
+	if w.lastErr != nil {
+		h.LastError = w.lastErr.Error()
+	}
+	for _, s := range w.subscribes {
+		c := ChannelHealth{Channel: s.Channel, Pair: s.Pair}
+		if linked, ok := w.chanMap[s.chanId]; ok && linked == s {
+			c.ChanId = s.chanId
+			c.Confirmed = true
+			h.Confirmed++
+		}
+		if ns := atomic.LoadInt64(&s.lastFrame); ns != 0 {
+			c.LastFrame = time.Unix(0, ns)
+		}
+		h.Channels = append(h.Channels, c)
+	}
+	h.Pending = h.Subscriptions - h.Confirmed
+	return h
+}
+
+// LastUpdate returns when the last data frame of the subscription of
+// channel and pair was delivered, heartbeats excluded, or the zero time
+// before the first one or without such a subscription. A ticker frozen
+// during a maintenance, while the connection and its heartbeats carry on,
+// shows as a LastUpdate falling behind. It is safe to call from any
+// goroutine while Subscribe runs. The channels subscribed by key, e.g.
+// candles, use LastUpdateKey.
+func (w *WebSocketService) LastUpdate(channel, pair string) time.Time {
+	return w.lastUpdate(channel, NormalizeSymbol(pair, SYMBOL_WEBSOCKET), "")
+}
+
+// LastUpdateKey is LastUpdate for the subscription of channel and key,
+// e.g. CHAN_CANDLES and CandleKey(BTCUSD, "1m").
+func (w *WebSocketService) LastUpdateKey(channel, key string) time.Time {
+	return w.lastUpdate(channel, "", key)
+}
+
+func (w *WebSocketService) lastUpdate(channel, pair, key string) time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if s := w.lookup(channel, pair, key); s != nil {
+		if ns := atomic.LoadInt64(&s.lastFrame); ns != 0 {
+			return time.Unix(0, ns)
+		}
+	}
+	return time.Time{}
+}
+
+func (w *WebSocketService) setLastErr(err error) {
+	if err == nil {
+		return
+	}
+	w.mu.Lock()
+	w.lastErr = err
+	w.mu.Unlock()
+}
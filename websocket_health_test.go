@@ -0,0 +1,115 @@
+package bitfinex
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestHealth(t *testing.T) {
+	var connections int32
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		n := atomic.AddInt32(&connections, 1)
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		if n == 1 {
+			// break the connection to count a reconnect
+			return
+		}
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1.5]]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = 10 * time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	c.WebSocket.AddSubscribe(CHAN_TICKER, BTCUSD, 0, make(chan [][]float64, 10))
+	go c.WebSocket.Subscribe()
+	receiveRaw(t, book)
+
+	h := c.WebSocket.Health()
+	if !h.Connected || h.Reconnects != 1 || h.LastError == "" {
+		t.Error("Unexpected connection state", h)
+	}
+	if h.Subscriptions != 2 || h.Confirmed != 1 || h.Pending != 1 {
+		t.Error("Expected", "2 subscriptions, 1 confirmed, 1 pending")
+		t.Error("Actual ", h.Subscriptions, h.Confirmed, h.Pending)
+	}
+	if ch := h.Channels[0]; !ch.Confirmed || ch.ChanId != 5 || ch.LastFrame.IsZero() {
+		t.Error("Unexpected book health", ch)
+	}
+	if ch := h.Channels[1]; ch.Confirmed || !ch.LastFrame.IsZero() {
+		t.Error("Unexpected ticker health", ch)
+	}
+
+	c.WebSocket.Close()
+	if c.WebSocket.Health().Connected {
+		t.Error("Expected disconnected after Close")
+	}
+}
+
+func TestLastUpdate(t *testing.T) {
+	proceed := make(chan struct{})
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"ticker","chanId":4,"pair":"ETHUSD"}`,
+			`[3,449,1,451,2,-1,-0.01,450,1000,460,440]`,
+			`[4,9,1,11,2,-1,-0.01,10,1000,12,8]`,
+		)
+		<-proceed
+		// the BTCUSD ticker freezes while the connection carries on
+		writeFrames(ws, `[3,"hb"]`, `[4,9,1,11,2,-1,-0.01,10.5,1000,12,8]`)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	btc := make(chan [][]float64, 10)
+	eth := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_TICKER, BTCUSD, 0, btc)
+	c.WebSocket.AddSubscribe(CHAN_TICKER, ETHUSD, 0, eth)
+	if v := c.WebSocket.LastUpdate(CHAN_TICKER, BTCUSD); !v.IsZero() {
+		t.Error("Expected the zero time before the first frame, got", v)
+	}
+	go c.WebSocket.Subscribe()
+	receiveRaw(t, btc)
+	receiveRaw(t, eth)
+
+	first := c.WebSocket.LastUpdate(CHAN_TICKER, BTCUSD)
+	if first.IsZero() || time.Since(first) > time.Second {
+		t.Error("Unexpected last update", first)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(proceed)
+	receiveRaw(t, eth)
+
+	if v := c.WebSocket.LastUpdate(CHAN_TICKER, BTCUSD); !v.Equal(first) {
+		t.Error("Expected", first)
+		t.Error("Actual ", v)
+	}
+	if v := c.WebSocket.LastUpdate(CHAN_TICKER, ETHUSD); !v.After(first) {
+		t.Error("Expected an ETHUSD update after", first, "got", v)
+	}
+	if v := c.WebSocket.LastUpdate(CHAN_BOOK, BTCUSD); !v.IsZero() {
+		t.Error("Expected the zero time without subscription, got", v)
+	}
+}
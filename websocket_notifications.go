@@ -0,0 +1,73 @@
+package bitfinex
+
+import (
+	"fmt"
+	"time"
+)
+
+// Notification statuses
+const (
+	NOTIFICATION_SUCCESS = "SUCCESS"
+	NOTIFICATION_ERROR   = "ERROR"
+	NOTIFICATION_FAILURE = "FAILURE"
+)
+
+// Notification is the acknowledgment of a request sent on the private
+// connection, or an error message, delivered by the n term:
+// [MTS, TYPE, MESSAGE_ID, _, NOTIFY_INFO, CODE, STATUS, TEXT]
+type Notification struct {
+	Timestamp time.Time
+	// Type is the request acknowledged, e.g. on-req, oc-req or ou-req
+	Type      string
+	MessageId int64
+	// Info is the object the request was about, e.g. the order, see Order
+	Info []interface{}
+	Code int64
+	// Status is one of the NOTIFICATION_* values
+	Status string
+	Text   string
+}
+
+// Failed reports whether the request was rejected.
+func (n Notification) Failed() bool {
+	return n.Status == NOTIFICATION_ERROR || n.Status == NOTIFICATION_FAILURE
+}
+
+// Order decodes Info as the order of an on-req, oc-req or ou-req
+// notification.
+func (n Notification) Order() (OrderUpdate, error) {
+	switch n.Type {
+	case "on-req", "oc-req", "ou-req":
+		return decodeOrderUpdate(n.Info)
+	}
+	return OrderUpdate{}, fmt.Errorf("%s notification carries no order", n.Type)
+}
+
+// Notification decodes the data of an n term.
+func (c *TermData) Notification() (Notification, error) {
+	if c.Term != "n" {
+		return Notification{}, fmt.Errorf("%s is not a notification", c.Term)
+	}
+	if len(c.Data) < 8 {
+		return Notification{}, &DecodeError{Path: "n", Err: fmt.Sprintf("%d fields, want 8", len(c.Data))}
+	}
+	num := func(i int) float64 {
+		v, _ := c.Data[i].(float64)
+		return v
+	}
+	n := Notification{
+		MessageId: int64(num(2)),
+		Code:      int64(num(5)),
+	}
+	if mts := num(0); mts != 0 {
+		n.Timestamp = time.Unix(0, int64(mts)*int64(time.Millisecond))
+	}
+	var ok bool
+	if n.Type, ok = c.Data[1].(string); !ok {
+		return Notification{}, &DecodeError{Path: "n field 1", Err: fmt.Sprintf("%T, want a string", c.Data[1])}
+	}
+	n.Info, _ = c.Data[4].([]interface{})
+	n.Status, _ = c.Data[6].(string)
+	n.Text, _ = c.Data[7].(string)
+	return n, nil
+}
@@ -0,0 +1,49 @@
+package bitfinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestNotification(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeFrames(ws,
+			`[0,"n",[1575291219660,"on-req",null,null,[101,null,7,"tBTCUSD",1575291219660,1575291219660,0.01,0.01,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,7000,0,0,0,null,null,null,0,0,null],null,"SUCCESS","Submitting exchange limit buy order for 0.01 BTC."]]`,
+			`[0,"n",[1575291219661,"oc-req",null,null,[102,null,8,"tBTCUSD",null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null,null],10001,"ERROR","Order not found."]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+
+	term := receiveTerm(t, terms)
+	n, err := term.Notification()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n.Type != "on-req" || n.Status != NOTIFICATION_SUCCESS || n.Failed() || n.Text != "Submitting exchange limit buy order for 0.01 BTC." ||
+		!n.Timestamp.Equal(time.Unix(0, 1575291219660*int64(time.Millisecond))) {
+		t.Error("Unexpected notification", n)
+	}
+	if o, err := n.Order(); err != nil || o.Id != 101 || o.Cid != 7 || o.Price != 7000 {
+		t.Error("Unexpected order", o, err)
+	}
+
+	term = receiveTerm(t, terms)
+	if n, err = term.Notification(); err != nil {
+		t.Fatal(err)
+	}
+	if !n.Failed() || n.Code != 10001 || n.Text != "Order not found." {
+		t.Error("Unexpected notification", n)
+	}
+
+	if _, err := (&TermData{Term: "ws"}).Notification(); err == nil {
+		t.Error("Expected an error for a ws term")
+	}
+}
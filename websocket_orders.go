@@ -0,0 +1,323 @@
+package bitfinex
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// OrderUpdate is an order of the private feed, as listed by the os term
+// and sent by the on, ou and oc terms:
+// [ORD_ID, ORD_PAIR, ORD_AMOUNT, ORD_AMOUNT_ORIG, ORD_TYPE, ORD_STATUS,
+// ORD_PRICE, ORD_PRICE_AVG, ORD_CREATED_AT, ORD_NOTIFY, ORD_HIDDEN, ORD_OCO]
+// The v2 endpoint sends the longer v2 order arrays, which are decoded too.
+type OrderUpdate struct {
+	Id int64
+	// Cid is the client order id, only sent by the v2 endpoint
+	Cid  int64
+	Pair string
+	// Amount is the remaining amount, negative for sell orders
+	Amount         float64
+	OriginalAmount float64
+	Type           string
+	Status         string
+	Price          float64
+	AvgPrice       float64
+	CreatedAt      time.Time
+	Notify         bool
+	Hidden         bool
+}
+
+// OrdersSnapshot decodes the open orders of an os snapshot. It is meant
+// for the TermData with SnapshotStart set, which carries all of them.
+func (c *TermData) OrdersSnapshot() ([]OrderUpdate, error) {
+	if c.Term != "os" || !c.SnapshotStart {
+		return nil, fmt.Errorf("%s is not an orders snapshot", c.Term)
+	}
+	orders := make([]OrderUpdate, 0, len(c.Entries))
+	for i, entry := range c.Entries {
+		o, err := decodeOrderUpdate(entry)
+		if err != nil {
+			return nil, &DecodeError{Path: fmt.Sprintf("os row %d", i), Err: err.Error()}
+		}
+		orders = append(orders, o)
+	}
+	return orders, nil
+}
+
+func decodeOrderUpdate(data []interface{}) (OrderUpdate, error) {
+	if len(data) > 18 {
+		if _, v1 := data[1].(string); !v1 {
+			return decodeOrderUpdateV2(data)
+		}
+	}
+	if len(data) < 9 {
+		return OrderUpdate{}, fmt.Errorf("%d fields, want at least 9", len(data))
+	}
+	var (
+		o       OrderUpdate
+		id      float64
+		created string
+		ok      = true
+	)
+	num := func(i int, f *float64) {
+		v, isNum := data[i].(float64)
+		ok = ok && isNum
+		*f = v
+	}
+	str := func(i int, s *string) {
+		v, isStr := data[i].(string)
+		ok = ok && isStr
+		*s = v
+	}
+	num(0, &id)
+	str(1, &o.Pair)
+	num(2, &o.Amount)
+	num(3, &o.OriginalAmount)
+	str(4, &o.Type)
+	str(5, &o.Status)
+	num(6, &o.Price)
+	num(7, &o.AvgPrice)
+	str(8, &created)
+	if !ok {
+		return OrderUpdate{}, fmt.Errorf("unexpected field types in %v", data)
+	}
+	o.Id = int64(id)
+	if created != "" {
+		t, err := time.Parse(time.RFC3339, created)
+		if err != nil {
+			return OrderUpdate{}, err
+		}
+		o.CreatedAt = t
+	}
+	flag := func(i int) bool {
+		if i >= len(data) {
+			return false
+		}
+		v, _ := data[i].(float64)
+		return v != 0
+	}
+	o.Notify = flag(9)
+	o.Hidden = flag(10)
+	return o, nil
+}
+
+// decodeOrderUpdateV2 decodes a v2 order array:
+// [ID, GID, CID, SYMBOL, MTS_CREATE, MTS_UPDATE, AMOUNT, AMOUNT_ORIG, TYPE,
+// TYPE_PREV, MTS_TIF, _, FLAGS, STATUS, _, _, PRICE, PRICE_AVG, ...,
+// NOTIFY (23), HIDDEN (24)]
+func decodeOrderUpdateV2(data []interface{}) (OrderUpdate, error) {
+	num := func(i int) float64 {
+		if i >= len(data) {
+			return 0
+		}
+		v, _ := data[i].(float64)
+		return v
+	}
+	symbol, ok := data[3].(string)
+	if !ok {
+		return OrderUpdate{}, fmt.Errorf("unexpected field types in %v", data)
+	}
+	o := OrderUpdate{
+		Id:             int64(num(0)),
+		Cid:            int64(num(2)),
+		Pair:           NormalizeSymbol(symbol, SYMBOL_WEBSOCKET),
+		Amount:         num(6),
+		OriginalAmount: num(7),
+		Price:          num(16),
+		AvgPrice:       num(17),
+		Notify:         num(23) != 0,
+		Hidden:         num(24) != 0 || int(num(12))&orderFlagHidden != 0,
+	}
+	o.Type, _ = data[8].(string)
+	o.Status, _ = data[13].(string)
+	if mts := num(4); mts != 0 {
+		o.CreatedAt = time.Unix(0, int64(mts)*int64(time.Millisecond))
+	}
+	return o, nil
+}
+
+// v2 order flags
+const (
+	orderFlagHidden   = 64
+	orderFlagPostOnly = 4096
+	orderFlagOCO      = 16384
+)
+
+// ErrNoCID is returned by SubmitOrder for an order without a CID.
+var ErrNoCID = errors.New("websocket orders require a CID")
+
+// SubmitOrder sends a new order on the private connection, which must be
+// established with ConnectPrivate. The order needs a CID, unique per day:
+// its confirmation on the private feed is delivered by OrderResult. The
+// confirmations only carry the cid on the v2 endpoint, so the client
+// WebSocketURL must be DefaultWebSocketV2URL to use OrderResult. The order
+// is validated as by OrderService.Submit, margin symbols included.
+func (w *WebSocketService) SubmitOrder(order SubmitOrder) error {
+	if order.CID == 0 {
+		return ErrNoCID
+	}
+	fields, err := order.wsPayload()
+	if err != nil {
+		return err
+	}
+	if err := w.client.Orders.checkMargin(context.Background(), order); err != nil {
+		return err
+	}
+	msg, err := json.Marshal([]interface{}{0, "on", nil, fields})
+	if err != nil {
+		return err
+	}
+	w.OrderResult(order.CID)
+	return w.writePrivate(msg)
+}
+
+// OrderResult returns a channel receiving the first on, ou or oc update of
+// the order with client order id cid, for orders sent with SubmitOrder.
+// Call it before SubmitOrder: it returns the same channel until the order
+// is resolved, a new one after. An order rejected by an on-req
+// notification resolves with the Status "ERROR: " and the notification
+// text. The channel is closed without an update when the private
+// connection breaks or ClosePrivate is called first, the update may have
+// been lost then: check the order snapshot of the next connection.
+func (w *WebSocketService) OrderResult(cid int64) <-chan OrderUpdate {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.orderResults == nil {
+		w.orderResults = make(map[int64]chan OrderUpdate)
+	}
+	c, ok := w.orderResults[cid]
+	if !ok {
+		c = make(chan OrderUpdate, 1)
+		w.orderResults[cid] = c
+	}
+	return c
+}
+
+// resolveOrder delivers an order update to the OrderResult channel of its
+// cid, if any.
+func (w *WebSocketService) resolveOrder(data []interface{}) {
+	o, err := decodeOrderUpdate(data)
+	if err != nil {
+		return
+	}
+	w.deliverResult(o)
+}
+
+// resolveRejected resolves the OrderResult of an order rejected by an
+// on-req notification.
+func (w *WebSocketService) resolveRejected(data []interface{}) {
+	n, err := (&TermData{Term: "n", Data: data}).Notification()
+	if err != nil || n.Type != "on-req" || !n.Failed() {
+		return
+	}
+	o, err := n.Order()
+	if err != nil {
+		return
+	}
+	o.Status = n.Status + ": " + n.Text
+	w.deliverResult(o)
+}
+
+// closeOrderResults closes the OrderResult channels still waiting, whose
+// updates could only come on the connection that ended. w.mu must be held.
+func (w *WebSocketService) closeOrderResults() {
+	for cid, c := range w.orderResults {
+		close(c)
+		delete(w.orderResults, cid)
+	}
+}
+
+func (w *WebSocketService) deliverResult(o OrderUpdate) {
+	if o.Cid == 0 {
+		return
+	}
+	w.mu.Lock()
+	c, ok := w.orderResults[o.Cid]
+	delete(w.orderResults, o.Cid)
+	w.mu.Unlock()
+	if ok {
+		c <- o
+	}
+}
+
+// writePrivate sends a frame on the private connection.
+func (w *WebSocketService) writePrivate(msg []byte) error {
+	w.mu.Lock()
+	ws := w.privateWs
+	closed := w.privateClosed
+	w.mu.Unlock()
+	if ws == nil || closed {
+		return errors.New("private connection not established")
+	}
+	if w.OnSendFrame != nil {
+		w.OnSendFrame(msg)
+	}
+	w.privateWriteMu.Lock()
+	defer w.privateWriteMu.Unlock()
+	return ws.WriteMessage(websocket.TextMessage, msg)
+}
+
+// v2OrderTypes are the v2 names of the v1 order types.
+var v2OrderTypes = map[OrderType]string{
+	ORDER_TYPE_MARKET:                 "MARKET",
+	ORDER_TYPE_LIMIT:                  "LIMIT",
+	ORDER_TYPE_STOP:                   "STOP",
+	ORDER_TYPE_TRAILING_STOP:          "TRAILING STOP",
+	ORDER_TYPE_FILL_OR_KILL:           "FOK",
+	ORDER_TYPE_EXCHANGE_MARKET:        "EXCHANGE MARKET",
+	ORDER_TYPE_EXCHANGE_LIMIT:         "EXCHANGE LIMIT",
+	ORDER_TYPE_EXCHANGE_STOP:          "EXCHANGE STOP",
+	ORDER_TYPE_EXCHANGE_TRAILING_STOP: "EXCHANGE TRAILING STOP",
+	ORDER_TYPE_EXCHANGE_FILL_OR_KILL:  "EXCHANGE FOK",
+}
+
+// wsPayload converts the order into the fields of a v2 new order input.
+func (o SubmitOrder) wsPayload() (map[string]interface{}, error) {
+	// the REST payload validates the order
+	rest, err := o.payload()
+	if err != nil {
+		return nil, err
+	}
+
+	orderType, ok := v2OrderTypes[rest["type"].(OrderType)]
+	if !ok {
+		return nil, fmt.Errorf("order type %q has no websocket equivalent", rest["type"])
+	}
+
+	amount := o.Amount
+	if o.Side != "" {
+		amount = o.Side.Signed(amount)
+	}
+	fields := map[string]interface{}{
+		"cid":    o.CID,
+		"type":   orderType,
+		"symbol": NormalizeSymbol(o.Symbol, SYMBOL_V2),
+		"amount": strconv.FormatFloat(amount, 'f', -1, 64),
+		"price":  rest["price"],
+	}
+	flags := 0
+	if o.Hidden {
+		flags |= orderFlagHidden
+	}
+	if o.PostOnly {
+		flags |= orderFlagPostOnly
+	}
+	if o.OCO {
+		flags |= orderFlagOCO
+		if SideOf(amount) == BUY {
+			fields["price_oco_stop"] = rest["buy_price_oco"]
+		} else {
+			fields["price_oco_stop"] = rest["sell_price_oco"]
+		}
+	}
+	if flags != 0 {
+		fields["flags"] = flags
+	}
+	return fields, nil
+}
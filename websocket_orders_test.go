@@ -0,0 +1,228 @@
+package bitfinex
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestOrdersSnapshot(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeFrames(ws,
+			`[0,"os",[[1,"BTCUSD",0.5,1,"EXCHANGE LIMIT","ACTIVE",270,0,"2015-10-15T11:26:13Z",0,1,0],[2,"ETHUSD",-2,-2,"LIMIT","ACTIVE",10,0,"2015-10-15T11:26:14Z",0]]]`,
+			`[0,"os",[[3,"BTCUSD"]]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+
+	start := receiveTerm(t, terms)
+	orders, err := start.OrdersSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []OrderUpdate{
+		{Id: 1, Pair: BTCUSD, Amount: 0.5, OriginalAmount: 1, Type: "EXCHANGE LIMIT", Status: "ACTIVE", Price: 270,
+			CreatedAt: time.Date(2015, 10, 15, 11, 26, 13, 0, time.UTC), Hidden: true},
+		{Id: 2, Pair: ETHUSD, Amount: -2, OriginalAmount: -2, Type: "LIMIT", Status: "ACTIVE", Price: 10,
+			CreatedAt: time.Date(2015, 10, 15, 11, 26, 14, 0, time.UTC)},
+	}
+	if len(orders) != len(expected) {
+		t.Fatal("Expected", len(expected), "orders, got", orders)
+	}
+	for i, o := range orders {
+		if o != expected[i] {
+			t.Error("Expected", expected[i])
+			t.Error("Actual ", o)
+		}
+	}
+
+	// the orders are delivered one by one as well
+	v := receiveTerm(t, terms)
+	if v.SnapshotStart || len(v.Data) != 12 {
+		t.Error("Expected the first order, got", v)
+	}
+	if _, err := v.OrdersSnapshot(); err == nil {
+		t.Error("Expected an error for a single order")
+	}
+	receiveTerm(t, terms)
+
+	start = receiveTerm(t, terms)
+	if _, err := start.OrdersSnapshot(); err == nil || err.Error() != "decoding os row 0: 2 fields, want at least 9" {
+		t.Error("Expected a decode error, got", err)
+	}
+}
+
+func TestSubmitOrderResult(t *testing.T) {
+	sent := make(chan []interface{}, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeFrames(ws, `[0,"ws",[]]`)
+		var msg []interface{}
+		ws.ReadJSON(&msg)
+		sent <- msg
+		writeFrames(ws,
+			`[0,"on",[100,null,8,"tETHUSD",1573000000000,1573000000000,1,1,"EXCHANGE LIMIT",null,null,null,0,"ACTIVE",null,null,10,0,0,0,null,null,null,0,0,null]]`,
+			`[0,"on",[101,null,7,"tBTCUSD",1573000000000,1573000000000,-0.5,-0.5,"EXCHANGE LIMIT",null,null,null,4096,"ACTIVE",null,null,450,0,0,0,null,null,null,0,0,null]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+	receiveTerm(t, terms)
+
+	order := SubmitOrder{Symbol: BTCUSD, Amount: 0.5, Side: SELL, Price: 450, Type: ORDER_TYPE_LIMIT, PostOnly: true}
+	if err := c.WebSocket.SubmitOrder(order); err != ErrNoCID {
+		t.Error("Expected", ErrNoCID)
+		t.Error("Actual ", err)
+	}
+	order.CID = 7
+	result := c.WebSocket.OrderResult(7)
+	if err := c.WebSocket.SubmitOrder(order); err != nil {
+		t.Fatal(err)
+	}
+
+	msg := <-sent
+	fields, _ := msg[3].(map[string]interface{})
+	if msg[1] != "on" || fields["cid"] != float64(7) || fields["type"] != "EXCHANGE LIMIT" || fields["symbol"] != "tBTCUSD" ||
+		fields["amount"] != "-0.5" || fields["price"] != "450" || fields["flags"] != float64(4096) {
+		t.Error("Unexpected order input", msg)
+	}
+
+	select {
+	case o := <-result:
+		if o.Id != 101 || o.Cid != 7 || o.Pair != BTCUSD || o.Amount != -0.5 || o.Status != "ACTIVE" || o.Price != 450 {
+			t.Error("Unexpected order result", o)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the order result")
+	}
+}
+
+func TestWsPayloadOrderTypes(t *testing.T) {
+	cases := []struct {
+		t    OrderType
+		want string
+	}{
+		{ORDER_TYPE_MARKET, "MARKET"},
+		{ORDER_TYPE_LIMIT, "LIMIT"},
+		{ORDER_TYPE_STOP, "STOP"},
+		{ORDER_TYPE_TRAILING_STOP, "TRAILING STOP"},
+		{ORDER_TYPE_FILL_OR_KILL, "FOK"},
+		{ORDER_TYPE_EXCHANGE_MARKET, "EXCHANGE MARKET"},
+		{ORDER_TYPE_EXCHANGE_LIMIT, "EXCHANGE LIMIT"},
+		{ORDER_TYPE_EXCHANGE_STOP, "EXCHANGE STOP"},
+		{ORDER_TYPE_EXCHANGE_TRAILING_STOP, "EXCHANGE TRAILING STOP"},
+		{ORDER_TYPE_EXCHANGE_FILL_OR_KILL, "EXCHANGE FOK"},
+	}
+	for _, c := range cases {
+		margin := !strings.HasPrefix(string(c.t), exchangePrefix)
+		fields, err := SubmitOrder{Symbol: BTCUSD, Amount: 1, Price: 450, Type: c.t, CID: 1, Margin: margin}.wsPayload()
+		if err != nil {
+			t.Error(c.t, err)
+			continue
+		}
+		if fields["type"] != c.want {
+			t.Error("Expected", c.want)
+			t.Error("Actual ", fields["type"])
+		}
+	}
+}
+
+func TestSubmitOrderMarginCheck(t *testing.T) {
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`[{"pair":"btcusd","margin":true},{"pair":"xrpbtc","margin":false}]`)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+
+	w := NewClient().WebSocket
+	err := w.SubmitOrder(SubmitOrder{Symbol: "XRPBTC", Amount: 1, Price: 1, Type: ORDER_TYPE_LIMIT, Margin: true, CID: 3})
+	if err == nil || !strings.Contains(err.Error(), "margin") {
+		t.Error("Expected the margin check to refuse XRPBTC, got", err)
+	}
+}
+
+func TestOrderResultClosed(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeFrames(ws, `[0,"ws",[]]`)
+		// the order is never confirmed
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+	receiveTerm(t, terms)
+
+	result := c.WebSocket.OrderResult(5)
+	if err := c.WebSocket.SubmitOrder(SubmitOrder{Symbol: BTCUSD, Amount: 0.01, Price: 7000, Type: ORDER_TYPE_LIMIT, CID: 5}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case o, ok := <-result:
+		if ok {
+			t.Error("Unexpected order result", o)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the order result to be closed")
+	}
+
+	// registered while not connected
+	pending := c.WebSocket.OrderResult(6)
+	c.WebSocket.ClosePrivate()
+	if _, ok := <-pending; ok {
+		t.Error("Expected the order result to be closed by ClosePrivate")
+	}
+	c.WebSocket.mu.Lock()
+	defer c.WebSocket.mu.Unlock()
+	if len(c.WebSocket.orderResults) != 0 {
+		t.Error("Expected no order result left, got", c.WebSocket.orderResults)
+	}
+}
+
+func TestSubmitOrderRejected(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeFrames(ws, `[0,"ws",[]]`)
+		ws.ReadMessage()
+		writeFrames(ws, `[0,"n",[1575291219660,"on-req",null,null,[null,null,9,"tBTCUSD",null,null,0.01,0.01,"EXCHANGE LIMIT",null,null,null,0,null,null,null,7000,0,0,0,null,null,null,0,0,null],null,"ERROR","Invalid order: not enough exchange balance"]]`)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+	receiveTerm(t, terms)
+
+	result := c.WebSocket.OrderResult(9)
+	if err := c.WebSocket.SubmitOrder(SubmitOrder{Symbol: BTCUSD, Amount: 0.01, Price: 7000, Type: ORDER_TYPE_LIMIT, CID: 9}); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case o := <-result:
+		if o.Status != "ERROR: Invalid order: not enough exchange balance" {
+			t.Error("Unexpected order result", o)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the order result")
+	}
+}
@@ -0,0 +1,87 @@
+package bitfinex
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// OverflowPolicy is what the private connection does when its TermData
+// buffer is full, see PrivateBufferSize.
+type OverflowPolicy int
+
+const (
+	// OVERFLOW_BLOCK stops reading until the consumer catches up, like the
+	// public channels do. OnBlockedSend is called with chanId 0, the
+	// private channel, once until it catches up.
+	OVERFLOW_BLOCK OverflowPolicy = iota
+	// OVERFLOW_RECONNECT breaks the connection with ErrPrivateOverflow
+	// instead, so that it isn't dropped by Bitfinex for not reading. With
+	// AutoReconnect the connection is re-established and every snapshot
+	// sent again once the consumer drained the buffer, nothing is lost
+	// silently.
+	OVERFLOW_RECONNECT
+)
+
+// ErrPrivateOverflow breaks the private connection when the TermData
+// buffer is full with OVERFLOW_RECONNECT.
+var ErrPrivateOverflow = errors.New("private channel consumer too slow, buffer full")
+
+// privateBuffer returns the channel readPrivate delivers to: ch itself, or
+// with a positive PrivateBufferSize a buffer forwarded to ch by a goroutine
+// of its own, so that a slow consumer doesn't stall reading. The returned
+// func closes the buffer once everything was sent to it and waits until
+// it is forwarded.
+func (w *WebSocketService) privateBuffer(ch chan TermData) (chan TermData, func()) {
+	if w.PrivateBufferSize <= 0 {
+		return ch, func() {}
+	}
+	buf := make(chan TermData, w.PrivateBufferSize)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for td := range buf {
+			ch <- td
+		}
+	}()
+	return buf, func() {
+		close(buf)
+		<-done
+	}
+}
+
+// sendPrivate delivers td to ch, applying PrivateOverflow when the
+// buffered ch is full.
+func (w *WebSocketService) sendPrivate(ch chan TermData, td TermData) error {
+	if cap(ch) == 0 {
+		ch <- td
+		return nil
+	}
+	select {
+	case ch <- td:
+		w.privateBlocked = false
+		return nil
+	default:
+	}
+	if w.PrivateOverflow == OVERFLOW_RECONNECT {
+		return ErrPrivateOverflow
+	}
+	if !w.privateBlocked {
+		if w.OnBlockedSend != nil {
+			w.OnBlockedSend(0, len(ch))
+		} else {
+			log.Println("Private consumer channel is full, the read loop blocks", len(ch))
+		}
+	}
+	w.privateBlocked = true
+	ch <- td
+	return nil
+}
+
+// waitDrained waits until the consumer received everything queued in ch,
+// or the private connection is closed.
+func (w *WebSocketService) waitDrained(ch chan TermData) {
+	for len(ch) > 0 && !w.isPrivateClosed() {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,113 @@
+package bitfinex
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func writeTradeUpdates(ws *websocket.Conn, n int) {
+	for i := 1; i <= n; i++ {
+		writeFrames(ws, fmt.Sprintf(`[0,"tu",[%d,"tBTCUSD",1573000000000,7,0.01,450,"EXCHANGE LIMIT",450,-1,-0.001,"USD"]]`, i))
+	}
+}
+
+func TestPrivateBuffer(t *testing.T) {
+	pong := make(chan struct{}, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeTradeUpdates(ws, 10)
+		ws.SetPongHandler(func(string) error {
+			pong <- struct{}{}
+			return nil
+		})
+		ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second))
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.PrivateBufferSize = 20
+	terms := make(chan TermData)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+
+	// the ping is answered while nothing was received yet
+	select {
+	case <-pong:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the pong, reading stalled")
+	}
+	for i := 1; i <= 10; i++ {
+		if v := receiveTerm(t, terms); v.Term != "tu" || v.Data[0] != float64(i) {
+			t.Error("Expected trade", i)
+			t.Error("Actual ", v)
+		}
+	}
+}
+
+func TestPrivateOverflowBlock(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeTradeUpdates(ws, 5)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	blocked := make(chan float64, 5)
+	c.WebSocket.OnBlockedSend = func(chanId float64, backlog int) { blocked <- chanId }
+	terms := make(chan TermData, 2)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+
+	select {
+	case chanId := <-blocked:
+		if chanId != 0 {
+			t.Error("Expected", 0)
+			t.Error("Actual ", chanId)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for OnBlockedSend")
+	}
+	for i := 1; i <= 5; i++ {
+		if v := receiveTerm(t, terms); v.Data[0] != float64(i) {
+			t.Error("Expected trade", i)
+			t.Error("Actual ", v)
+		}
+	}
+	if len(blocked) != 0 {
+		t.Error("Expected OnBlockedSend once, got", len(blocked)+1)
+	}
+}
+
+func TestPrivateOverflowReconnect(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeTradeUpdates(ws, 10)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.PrivateBufferSize = 2
+	c.WebSocket.PrivateOverflow = OVERFLOW_RECONNECT
+	terms := make(chan TermData)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+
+	time.Sleep(50 * time.Millisecond)
+	for i := 1; ; i++ {
+		v := receiveTerm(t, terms)
+		if v.HasError() {
+			if v.Err != ErrPrivateOverflow || i > 4 {
+				t.Error("Expected", ErrPrivateOverflow, "after at most 3 trades")
+				t.Error("Actual ", v.Err, "after", i-1)
+			}
+			break
+		}
+		if v.Data[0] != float64(i) {
+			t.Error("Expected trade", i)
+			t.Error("Actual ", v)
+		}
+	}
+}
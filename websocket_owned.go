@@ -0,0 +1,125 @@
+package bitfinex
+
+import (
+	"reflect"
+)
+
+// SubscribeTickerChan is like SubscribeTicker with a channel of buffer
+// updates created and owned by the service. It is closed when the
+// subscription is removed by Unsubscribe or ClearSubscriptions, or when
+// Subscribe returns after Close, so ranging over it terminates.
+func (w *WebSocketService) SubscribeTickerChan(pair string, buffer int) (<-chan TickerUpdate, error) {
+	c := make(chan TickerUpdate, buffer)
+	if err := w.SubscribeTicker(pair, c); err != nil {
+		return nil, err
+	}
+	w.own(c)
+	return c, nil
+}
+
+// SubscribeTradesChan is like SubscribeTrades with a channel owned by the
+// service, see SubscribeTickerChan.
+func (w *WebSocketService) SubscribeTradesChan(pair string, buffer int) (<-chan TradeUpdate, error) {
+	c := make(chan TradeUpdate, buffer)
+	if err := w.SubscribeTrades(pair, c); err != nil {
+		return nil, err
+	}
+	w.own(c)
+	return c, nil
+}
+
+// SubscribeBookChan is like SubscribeBook with a channel owned by the
+// service, see SubscribeTickerChan.
+func (w *WebSocketService) SubscribeBookChan(pair string, length, buffer int) (<-chan *OrderBook, error) {
+	c := make(chan *OrderBook, buffer)
+	if err := w.SubscribeBook(pair, length, c); err != nil {
+		return nil, err
+	}
+	w.own(c)
+	return c, nil
+}
+
+// own marks the subscription delivering to out as owning it.
+func (w *WebSocketService) own(out interface{}) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, s := range w.subscribes {
+		if s.out == out {
+			s.owned = true
+		}
+	}
+}
+
+// release closes the channel of s if the service owns it. While Subscribe
+// runs, its goroutine may be sending to the channel, so it closes it
+// instead, before or after the send. w.mu must be held.
+func (w *WebSocketService) release(s *subscribeToChannel) {
+	if !s.owned || s.released {
+		return
+	}
+	s.released = true
+	if w.delivering {
+		w.releasing = append(w.releasing, s)
+		return
+	}
+	reflect.ValueOf(s.out).Close()
+}
+
+// isReleased reports whether the channel of s must not receive anymore.
+func (w *WebSocketService) isReleased(s *subscribeToChannel) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return s.released
+}
+
+// closeReleased closes the owned channels released while Subscribe runs,
+// from its goroutine.
+func (w *WebSocketService) closeReleased() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closeReleasing()
+}
+
+// closeReleasing closes the channels in w.releasing. w.mu must be held.
+func (w *WebSocketService) closeReleasing() {
+	for _, s := range w.releasing {
+		reflect.ValueOf(s.out).Close()
+	}
+	w.releasing = nil
+}
+
+// stopDelivering closes the channels left by Subscribe as it returns, the
+// ones released from now on are closed right away.
+func (w *WebSocketService) stopDelivering() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closeReleasing()
+	w.delivering = false
+}
+
+// resetSub tells the consumer of s that a new snapshot follows, unless its
+// channel was released.
+func (w *WebSocketService) resetSub(s *subscribeToChannel) {
+	if s.reset != nil && !w.isReleased(s) {
+		s.reset()
+	}
+}
+
+// releaseOwned removes the subscriptions owning their channel, closing it,
+// once the service was closed.
+func (w *WebSocketService) releaseOwned() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.closed && !w.stopped {
+		return
+	}
+	var owned []*subscribeToChannel
+	for _, s := range w.subscribes {
+		if s.owned {
+			owned = append(owned, s)
+		}
+	}
+	for _, s := range owned {
+		w.remove(s)
+	}
+}
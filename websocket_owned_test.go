@@ -0,0 +1,109 @@
+package bitfinex
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribeOwnedChannels(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"trades","chanId":4,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[3,449,1,451,2,-1,-0.01,450,1000,460,440]`,
+			`[4,"te",1,1443659698,236.42,0.5]`,
+			`[5,[[449,2,1.5]]]`,
+		)
+		// the unsubscribe, then the close
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	tickers, err := c.WebSocket.SubscribeTickerChan(BTCUSD, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	trades, _ := c.WebSocket.SubscribeTradesChan(BTCUSD, 10)
+	books, _ := c.WebSocket.SubscribeBookChan(BTCUSD, 25, 10)
+	if _, err := c.WebSocket.SubscribeTickerChan(BTCUSD, 10); err != ErrAlreadySubscribed {
+		t.Error("Expected", ErrAlreadySubscribed)
+		t.Error("Actual ", err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- c.WebSocket.Subscribe() }()
+
+	if v := <-tickers; v.LastPrice != 450 {
+		t.Error("Unexpected ticker", v)
+	}
+	c.WebSocket.Unsubscribe(CHAN_TICKER, BTCUSD)
+	receiveClosed(t, "tickers", tickers)
+
+	if v := <-books; len(v.Bids) != 1 {
+		t.Error("Unexpected book", v)
+	}
+	if v := <-trades; v.Price != 236.42 {
+		t.Error("Unexpected trade", v)
+	}
+	// the trades and the book close at shutdown
+	c.WebSocket.Close()
+	if err := <-done; !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Error("Expected a normal closure, got", err)
+	}
+	receiveClosed(t, "trades", trades)
+	receiveClosed(t, "books", books)
+}
+
+func TestReleaseWhileDelivering(t *testing.T) {
+	w := NewClient().WebSocket
+	// as while Subscribe runs
+	w.delivering = true
+	books, err := w.SubscribeBookChan(BTCUSD, 25, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := w.subscriptions()[0]
+	w.ClearSubscriptions()
+	// left to the delivering goroutine, which resets no released channel
+	w.resetSub(s)
+	w.dispatch(s, dataFrame{Snapshot: true})
+	select {
+	case v, ok := <-books:
+		t.Fatal("Expected books neither closed nor sent to, got", v, ok)
+	default:
+	}
+	w.stopDelivering()
+	receiveClosed(t, "books", books)
+}
+
+// receiveClosed drains ch until it is closed.
+func receiveClosed(t *testing.T, name string, ch interface{}) {
+	deadline := time.After(time.Second)
+	cases := []reflect.SelectCase{
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ch)},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(deadline)},
+	}
+	for {
+		chosen, _, ok := reflect.Select(cases)
+		if chosen == 1 {
+			t.Fatal("timed out waiting for", name, "to be closed")
+		}
+		if !ok {
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package bitfinex
+
+import (
+	"log"
+	"time"
+)
+
+// maxSubscribeAttempts bounds the subscribe messages sent for a
+// subscription that is never confirmed.
+const maxSubscribeAttempts = 5
+
+// sendWindow sends the subscriptions allowed by MaxInFlightSubscribes,
+// queueing the others until the first ones are confirmed.
+func (w *WebSocketService) sendWindow(subscribes []*subscribeToChannel) []*subscribeToChannel {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.subGen++
+	w.inflight = make(map[*subscribeToChannel]int)
+	w.queued = nil
+	if n := w.MaxInFlightSubscribes; n > 0 && len(subscribes) > n {
+		w.queued = append(w.queued, subscribes[n:]...)
+		subscribes = subscribes[:n]
+	}
+	return subscribes
+}
+
+// sent tracks a subscribe message until its subscribed event arrives,
+// sending it again after SubscribeAckTimeout.
+func (w *WebSocketService) sent(s *subscribeToChannel) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.inflight[s]++
+	if w.SubscribeAckTimeout <= 0 {
+		return
+	}
+	gen, attempt := w.subGen, w.inflight[s]
+	time.AfterFunc(w.SubscribeAckTimeout, func() {
+		w.ackTimeout(s, gen, attempt)
+	})
+}
+
+func (w *WebSocketService) ackTimeout(s *subscribeToChannel, gen, attempt int) {
+	w.mu.Lock()
+	stale := w.closed || gen != w.subGen || w.inflight[s] != attempt
+	w.mu.Unlock()
+	if stale {
+		return
+	}
+	if attempt >= maxSubscribeAttempts {
+		log.Println("Giving up subscribing", s.Channel, s.Pair, "after", attempt, "attempts")
+		w.acked(s)
+		return
+	}
+	log.Println("No subscribed event, subscribing again", s.Channel, s.Pair)
+	if err := w.sendSubscribe(s); err != nil {
+		log.Println("Error subscribing", s.Channel, s.Pair, err)
+		return
+	}
+	w.sent(s)
+}
+
+// acked stops tracking s, whose subscription was confirmed or refused,
+// and sends the next queued subscription.
+func (w *WebSocketService) acked(s *subscribeToChannel) {
+	w.mu.Lock()
+	if _, ok := w.inflight[s]; !ok {
+		w.mu.Unlock()
+		return
+	}
+	delete(w.inflight, s)
+	if len(w.queued) == 0 {
+		w.mu.Unlock()
+		return
+	}
+	next := w.queued[0]
+	w.queued = w.queued[1:]
+	w.mu.Unlock()
+
+	if err := w.sendSubscribe(next); err != nil {
+		log.Println("Error subscribing", next.Channel, next.Pair, err)
+		return
+	}
+	w.sent(next)
+}
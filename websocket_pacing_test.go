@@ -0,0 +1,84 @@
+package bitfinex
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribePacing(t *testing.T) {
+	done := make(chan struct{})
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		defer close(done)
+		msgs := make(chan SubscribeMsg, 10)
+		go func() {
+			defer close(msgs)
+			for {
+				var msg SubscribeMsg
+				if ws.ReadJSON(&msg) != nil {
+					return
+				}
+				msgs <- msg
+			}
+		}()
+		next := func(channel string) SubscribeMsg {
+			select {
+			case msg := <-msgs:
+				if msg.Channel != channel {
+					t.Error("Expected", channel)
+					t.Error("Actual ", msg)
+				}
+				return msg
+			case <-time.After(time.Second):
+				t.Error("timed out waiting for", channel)
+			}
+			return SubscribeMsg{}
+		}
+		none := func(wait time.Duration) {
+			select {
+			case msg := <-msgs:
+				t.Error("Unexpected message", msg)
+			case <-time.After(wait):
+			}
+		}
+
+		book := next(CHAN_BOOK)
+		none(50 * time.Millisecond)
+		writeFrames(ws, fmt.Sprintf(`{"event":"subscribed","channel":"book","chanId":1,"pair":%q}`, book.Pair))
+
+		// not confirmed in time, so sent again
+		next(CHAN_TRADE)
+		trades := next(CHAN_TRADE)
+		writeFrames(ws, fmt.Sprintf(`{"event":"subscribed","channel":"trades","chanId":2,"pair":%q}`, trades.Pair))
+
+		// refused, so the window moves on without retrying it
+		ticker := next(CHAN_TICKER)
+		writeFrames(ws, fmt.Sprintf(`{"event":"error","msg":"subscribe: dup","code":10301,"channel":"ticker","pair":%q}`, ticker.Pair))
+		none(150 * time.Millisecond)
+	})
+	defer srv.Close()
+
+	c.WebSocket.MaxInFlightSubscribes = 1
+	c.WebSocket.SubscribeAckTimeout = 100 * time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64, 10))
+	c.WebSocket.AddSubscribe(CHAN_TRADE, BTCUSD, 0, make(chan [][]float64, 10))
+	c.WebSocket.AddSubscribe(CHAN_TICKER, BTCUSD, 0, make(chan [][]float64, 10))
+	go c.WebSocket.Subscribe()
+
+	select {
+	case <-done:
+	case <-time.After(3 * time.Second):
+		t.Fatal("timed out")
+	}
+	if h := c.WebSocket.Health(); h.Confirmed != 2 {
+		t.Error("Expected", 2)
+		t.Error("Actual ", h.Confirmed)
+	}
+}
@@ -0,0 +1,163 @@
+package bitfinex
+
+import (
+	"sync"
+)
+
+// Pool spreads subscriptions over as many websocket connections as the
+// MaxChannelsPerConnection limit requires. Subscriptions are added like on
+// a WebSocketService, before calling Subscribe; passing the same channel to
+// several of them, e.g. with AddSubscribeEvents, merges the data of every
+// connection into one feed. Each connection reconnects on its own.
+type Pool struct {
+	// Configure, when set, is called on every connection the pool opens,
+	// before anything is subscribed on it. Connections have AutoReconnect
+	// set by default.
+	Configure func(w *WebSocketService)
+
+	client *Client
+	mu     sync.Mutex
+	conns  []*WebSocketService
+}
+
+// NewPool returns an empty pool, connections are created as subscriptions
+// are added.
+func NewPool(c *Client) *Pool {
+	return &Pool{client: c}
+}
+
+// Connections returns the connections opened so far.
+func (p *Pool) Connections() []*WebSocketService {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*WebSocketService(nil), p.conns...)
+}
+
+// add subscribes on the last connection, or on a new one when it is full.
+func (p *Pool) add(channel, pair string, subscribe func(w *WebSocketService) error) error {
+	pair = NormalizeSymbol(pair, SYMBOL_WEBSOCKET)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, c := range p.conns {
+		for _, s := range c.subscriptions() {
+			if s.Channel == channel && s.Pair == pair {
+				return ErrAlreadySubscribed
+			}
+		}
+	}
+
+	var w *WebSocketService
+	if n := len(p.conns); n > 0 && len(p.conns[n-1].subscriptions()) < MaxChannelsPerConnection {
+		w = p.conns[n-1]
+	} else {
+		w = NewWebSocketService(p.client)
+		w.AutoReconnect = true
+		if p.Configure != nil {
+			p.Configure(w)
+		}
+		p.conns = append(p.conns, w)
+	}
+	return subscribe(w)
+}
+
+func (p *Pool) AddSubscribe(channel string, pair string, length int, c chan [][]float64) error {
+	return p.add(channel, pair, func(w *WebSocketService) error {
+		return w.AddSubscribe(channel, pair, length, c)
+	})
+}
+
+func (p *Pool) AddSubscribeEvents(channel string, pair string, length int, c chan MarketEvent) error {
+	return p.add(channel, pair, func(w *WebSocketService) error {
+		return w.AddSubscribeEvents(channel, pair, length, c)
+	})
+}
+
+func (p *Pool) SubscribeBook(pair string, length int, c chan *OrderBook) error {
+	return p.add(CHAN_BOOK, pair, func(w *WebSocketService) error {
+		return w.SubscribeBook(pair, length, c)
+	})
+}
+
+func (p *Pool) SubscribeBookDiffs(pair string, length int, c chan BookDiff) error {
+	return p.add(CHAN_BOOK, pair, func(w *WebSocketService) error {
+		return w.SubscribeBookDiffs(pair, length, c)
+	})
+}
+
+func (p *Pool) SubscribeTrades(pair string, c chan TradeUpdate) error {
+	return p.add(CHAN_TRADE, pair, func(w *WebSocketService) error {
+		return w.SubscribeTrades(pair, c)
+	})
+}
+
+func (p *Pool) SubscribeTicker(pair string, c chan TickerUpdate) error {
+	return p.add(CHAN_TICKER, pair, func(w *WebSocketService) error {
+		return w.SubscribeTicker(pair, c)
+	})
+}
+
+func (p *Pool) SubscribeLastPrice(pair string, c chan TickerUpdate) error {
+	return p.add(CHAN_TICKER, pair, func(w *WebSocketService) error {
+		return w.SubscribeLastPrice(pair, c)
+	})
+}
+
+// SubscribeAllTickers is like WebSocketService.SubscribeAllTickers without
+// the limit of a single connection.
+func (p *Pool) SubscribeAllTickers() (map[string]chan TickerUpdate, error) {
+	pairs, err := p.client.Pairs.All()
+	if err != nil {
+		return nil, err
+	}
+	return subscribeTickers(pairs, p.SubscribeTicker, func(pair string) error {
+		return p.unsubscribe(CHAN_TICKER, pair)
+	})
+}
+
+// unsubscribe removes the subscription of channel and pair from the
+// connection holding it.
+func (p *Pool) unsubscribe(channel, pair string) error {
+	for _, w := range p.Connections() {
+		if err := w.Unsubscribe(channel, pair); err != ErrNotSubscribed {
+			return err
+		}
+	}
+	return ErrNotSubscribed
+}
+
+// Subscribe connects every connection and watches them until all of them
+// return, returning the first error. With AutoReconnect that happens once
+// Close is called.
+func (p *Pool) Subscribe() error {
+	conns := p.Connections()
+	for i, w := range conns {
+		if err := w.Connect(); err != nil {
+			for _, c := range conns[:i] {
+				c.Close()
+			}
+			return err
+		}
+	}
+
+	errs := make(chan error, len(conns))
+	for _, w := range conns {
+		go func(w *WebSocketService) {
+			errs <- w.Subscribe()
+		}(w)
+	}
+
+	var first error
+	for range conns {
+		if err := <-errs; first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close closes every connection.
+func (p *Pool) Close() {
+	for _, w := range p.Connections() {
+		w.Close()
+	}
+}
@@ -0,0 +1,71 @@
+package bitfinex
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestPool(t *testing.T) {
+	var connections, chanIds int32
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		atomic.AddInt32(&connections, 1)
+		for {
+			_, p, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			var msg SubscribeMsg
+			json.Unmarshal(p, &msg)
+			id := atomic.AddInt32(&chanIds, 1)
+			writeFrames(ws,
+				fmt.Sprintf(`{"event":"subscribed","channel":"ticker","chanId":%d,"pair":"%s"}`, id, msg.Pair),
+				fmt.Sprintf(`[%d,1,1,2,1,0,0,1.5,10,2,1]`, id),
+			)
+		}
+	})
+	defer srv.Close()
+
+	pool := NewPool(c)
+	pool.Configure = func(w *WebSocketService) { w.ReconnectInterval = 10 * time.Millisecond }
+	events := make(chan MarketEvent, MaxChannelsPerConnection+1)
+	for i := 0; i <= MaxChannelsPerConnection; i++ {
+		if err := pool.AddSubscribeEvents(CHAN_TICKER, fmt.Sprintf("P%02dUSD", i), 0, events); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := pool.AddSubscribeEvents(CHAN_TICKER, "P00USD", 0, events); err != ErrAlreadySubscribed {
+		t.Error("Expected", ErrAlreadySubscribed)
+		t.Error("Actual ", err)
+	}
+	if n := len(pool.Connections()); n != 2 {
+		t.Fatal("Expected 2 connections, got", n)
+	}
+
+	done := make(chan error)
+	go func() { done <- pool.Subscribe() }()
+
+	pairs := make(map[string]bool)
+	for len(pairs) <= MaxChannelsPerConnection {
+		select {
+		case e := <-events:
+			pairs[e.Pair] = true
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for events, got", len(pairs))
+		}
+	}
+	if n := atomic.LoadInt32(&connections); n != 2 {
+		t.Error("Expected 2 connections to the server, got", n)
+	}
+
+	pool.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Subscribe did not return after Close")
+	}
+}
@@ -0,0 +1,72 @@
+package bitfinex
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// BookPrecision is the price aggregation level of a book subscription.
+type BookPrecision string
+
+const (
+	// PRECISION_P0 aggregates by 5 significant digits, the default
+	PRECISION_P0 BookPrecision = "P0"
+	PRECISION_P1 BookPrecision = "P1"
+	PRECISION_P2 BookPrecision = "P2"
+	PRECISION_P3 BookPrecision = "P3"
+	// PRECISION_P4 aggregates by 1 significant digit, PROTOCOL_V2 only
+	PRECISION_P4 BookPrecision = "P4"
+	// PRECISION_R0 is the raw book: rows are [ORDER_ID, PRICE, AMOUNT], so
+	// it only suits AddSubscribe and AddSubscribeFunc.
+	PRECISION_R0 BookPrecision = "R0"
+)
+
+// ErrInvalidPrecision is returned for a book precision the protocol
+// version of the service doesn't accept.
+var ErrInvalidPrecision = errors.New("invalid book precision")
+
+// validPrecisions maps a protocol version to the precisions it accepts.
+var validPrecisions = map[ProtocolVersion][]BookPrecision{
+	PROTOCOL_V1: {PRECISION_P0, PRECISION_P1, PRECISION_P2, PRECISION_P3, PRECISION_R0},
+	PROTOCOL_V2: {PRECISION_P0, PRECISION_P1, PRECISION_P2, PRECISION_P3, PRECISION_P4, PRECISION_R0},
+}
+
+// precision returns prec in its canonical case, e.g. "p1" as PRECISION_P1,
+// or an error wrapping ErrInvalidPrecision when version doesn't accept it.
+// An empty prec is the channel default.
+func precision(prec BookPrecision, version ProtocolVersion) (BookPrecision, error) {
+	if prec == "" {
+		return "", nil
+	}
+	p := BookPrecision(strings.ToUpper(strings.TrimSpace(string(prec))))
+	for _, v := range validPrecisions[version] {
+		if p == v {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("%w %q with protocol v%d", ErrInvalidPrecision, string(prec), version)
+}
+
+// SetBookPrecision sets the precision of the book subscription of pair,
+// sent with the subscribe message. PRECISION_P4 requires PROTOCOL_V2, an
+// error wrapping ErrInvalidPrecision is returned for a precision the
+// protocol version doesn't accept, rather than Bitfinex answering with an
+// error event and the book staying empty. The precision is checked again
+// when the subscription is sent, in case Version changed meanwhile.
+func (w *WebSocketService) SetBookPrecision(pair string, prec BookPrecision) error {
+	p, err := precision(prec, w.Protocol())
+	if err != nil {
+		return err
+	}
+	pair = NormalizeSymbol(pair, SYMBOL_WEBSOCKET)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for _, s := range w.subscribes {
+		if s.Channel == CHAN_BOOK && s.Pair == pair {
+			s.Prec = p
+			return nil
+		}
+	}
+	return ErrNotSubscribed
+}
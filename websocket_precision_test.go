@@ -0,0 +1,95 @@
+package bitfinex
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestPrecision(t *testing.T) {
+	tests := []struct {
+		prec     BookPrecision
+		version  ProtocolVersion
+		expected BookPrecision
+		err      bool
+	}{
+		{"", PROTOCOL_V1, "", false},
+		{PRECISION_P3, PROTOCOL_V1, PRECISION_P3, false},
+		{"p1", PROTOCOL_V1, PRECISION_P1, false},
+		{PRECISION_R0, PROTOCOL_V1, PRECISION_R0, false},
+		{PRECISION_P4, PROTOCOL_V1, "", true},
+		{PRECISION_P4, PROTOCOL_V2, PRECISION_P4, false},
+		{"P5", PROTOCOL_V2, "", true},
+		{"R1", PROTOCOL_V2, "", true},
+	}
+	for _, tt := range tests {
+		p, err := precision(tt.prec, tt.version)
+		if p != tt.expected || (err != nil) != tt.err {
+			t.Error("Expected", tt.expected, tt.err, "for", tt.prec, tt.version)
+			t.Error("Actual ", p, err)
+		}
+		if err != nil && !errors.Is(err, ErrInvalidPrecision) {
+			t.Error("Expected", ErrInvalidPrecision)
+			t.Error("Actual ", err)
+		}
+	}
+}
+
+func TestSetBookPrecision(t *testing.T) {
+	subscribed := make(chan SubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		subscribed <- readSubscribe(t, ws)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.Version = PROTOCOL_V2
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64))
+	c.WebSocket.AddSubscribe(CHAN_BOOK, ETHUSD, 25, make(chan [][]float64))
+	if err := c.WebSocket.SetBookPrecision(BTCUSD, "P5"); !errors.Is(err, ErrInvalidPrecision) {
+		t.Error("Expected", ErrInvalidPrecision)
+		t.Error("Actual ", err)
+	}
+	if err := c.WebSocket.SetBookPrecision(LTCUSD, PRECISION_P1); err != ErrNotSubscribed {
+		t.Error("Expected", ErrNotSubscribed)
+		t.Error("Actual ", err)
+	}
+	if err := c.WebSocket.SetBookPrecision(BTCUSD, PRECISION_P4); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WebSocket.SetBookPrecision(ETHUSD, "p1"); err != nil {
+		t.Fatal(err)
+	}
+
+	// P4 is no longer valid once the service speaks v1, the BTCUSD book
+	// isn't sent
+	c.WebSocket.Version = PROTOCOL_V1
+	done := make(chan error, 1)
+	go func() { done <- c.WebSocket.Subscribe() }()
+
+	select {
+	case msg := <-subscribed:
+		if msg.Pair != ETHUSD || msg.Prec != "P1" {
+			t.Error("Unexpected subscribe message", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for subscribe")
+	}
+	select {
+	case err := <-done:
+		var sendErr *SubscribeSendError
+		if !errors.As(err, &sendErr) || sendErr.Pair != BTCUSD || !errors.Is(err, ErrInvalidPrecision) {
+			t.Error("Expected", ErrInvalidPrecision, "for", BTCUSD)
+			t.Error("Actual ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Subscribe")
+	}
+}
@@ -0,0 +1,41 @@
+package bitfinex
+
+// LastPrice returns the last price seen for pair on a ticker or trades
+// subscription, whichever was received last. It requires CacheLastPrices
+// and reports false until a price was seen. It is safe to call from any
+// goroutine.
+func (w *WebSocketService) LastPrice(pair string) (float64, bool) {
+	w.pricesMu.RLock()
+	defer w.pricesMu.RUnlock()
+	price, ok := w.prices[NormalizeSymbol(pair, SYMBOL_WEBSOCKET)]
+	return price, ok
+}
+
+// recordPrice updates the last price of the pair of s from a frame.
+func (w *WebSocketService) recordPrice(s *subscribeToChannel, f dataFrame) {
+	var price float64
+	switch s.Channel {
+	case CHAN_TICKER:
+		t, ok := decodeTicker(f)
+		if !ok {
+			return
+		}
+		price = t.LastPrice
+	case CHAN_TRADE:
+		trades := decodeTrades(f)
+		if len(trades) == 0 {
+			return
+		}
+		// chronological order, the last one is the newest
+		price = trades[len(trades)-1].Price
+	default:
+		return
+	}
+
+	w.pricesMu.Lock()
+	if w.prices == nil {
+		w.prices = make(map[string]float64)
+	}
+	w.prices[s.Pair] = price
+	w.pricesMu.Unlock()
+}
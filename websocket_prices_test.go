@@ -0,0 +1,54 @@
+package bitfinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestLastPrice(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":2,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"trades","chanId":3,"pair":"ETHUSD"}`,
+			`[2,450,1,451,2,-3,-0.01,450.5,1000,460,440]`,
+			`[3,[[2,1444141857,11.2,0.5],[1,1444141800,11,1]]]`,
+			`[3,"te","1-ETHUSD",1444141900,11.4,-0.2]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.CacheLastPrices = true
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	if _, ok := c.WebSocket.LastPrice(BTCUSD); ok {
+		t.Error("Expected no price before any frame")
+	}
+	trades := make(chan TradeUpdate, 10)
+	c.WebSocket.SubscribeTicker(BTCUSD, make(chan TickerUpdate, 10))
+	c.WebSocket.SubscribeTrades(ETHUSD, trades)
+	go c.WebSocket.Subscribe()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-trades:
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for trades")
+		}
+	}
+	if price, ok := c.WebSocket.LastPrice("btcusd"); !ok || price != 450.5 {
+		t.Error("Expected", 450.5)
+		t.Error("Actual ", price, ok)
+	}
+	if price, ok := c.WebSocket.LastPrice(ETHUSD); !ok || price != 11.4 {
+		t.Error("Expected", 11.4)
+		t.Error("Actual ", price, ok)
+	}
+}
@@ -0,0 +1,197 @@
+package bitfinex
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// readAuth reads the auth request and accepts it.
+func readAuth(t *testing.T, ws *websocket.Conn) privateConnect {
+	var msg privateConnect
+	ws.ReadJSON(&msg)
+	writeFrames(ws, `{"event":"auth","status":"OK","chanId":0,"userId":42}`)
+	return msg
+}
+
+func receiveTerm(t *testing.T, c chan TermData) TermData {
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for term data")
+	}
+	return TermData{}
+}
+
+func TestConnectPrivateReconnect(t *testing.T) {
+	var connections int32
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		n := atomic.AddInt32(&connections, 1)
+		readAuth(t, ws)
+		writeFrames(ws,
+			`[0,"hb"]`,
+			`[0,"ws",[["exchange","BTC",1.5,0]]]`,
+			`[0,"os",[]]`,
+		)
+		if n == 1 {
+			return
+		}
+		writeFrames(ws, `[0,"wu",["exchange","BTC",2,0]]`)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = 10 * time.Millisecond
+
+	terms := make(chan TermData, 20)
+	go c.WebSocket.ConnectPrivate(terms)
+
+	expected := []TermData{
+		{Term: "ws", SnapshotStart: true},
+		{Term: "ws", Data: []interface{}{"exchange", "BTC", 1.5, 0.0}},
+		{Term: "os", SnapshotStart: true},
+		{Term: "ws", SnapshotStart: true},
+		{Term: "ws", Data: []interface{}{"exchange", "BTC", 1.5, 0.0}},
+		{Term: "os", SnapshotStart: true},
+		{Term: "wu", Data: []interface{}{"exchange", "BTC", 2.0, 0.0}},
+	}
+	for _, e := range expected {
+		v := receiveTerm(t, terms)
+		if v.HasError() || v.Term != e.Term || v.SnapshotStart != e.SnapshotStart || len(v.Data) != len(e.Data) {
+			t.Error("Expected", e)
+			t.Error("Actual ", v)
+		}
+	}
+
+	c.WebSocket.ClosePrivate()
+	if v := receiveTerm(t, terms); !v.HasError() {
+		t.Error("Expected error after ClosePrivate, got", v)
+	}
+}
+
+func TestConnectPrivateAuthFailure(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		ws.ReadMessage()
+		writeFrames(ws, `{"event":"auth","status":"FAILED","chanId":0,"code":10114,"msg":"nonce: small"}`)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+
+	v := receiveTerm(t, terms)
+	expected := "Error connecting to private web socket channel. FAILED: nonce: small (code 10114)"
+	if v.Error != expected {
+		t.Error("Expected", expected)
+		t.Error("Actual ", v.Error)
+	}
+	var authErr *AuthError
+	if !errors.As(v.Err, &authErr) || authErr.Code != AUTH_CODE_NONCE || authErr.Msg != "nonce: small" {
+		t.Error("Expected an AuthError with code", AUTH_CODE_NONCE)
+		t.Error("Actual ", v.Err)
+	}
+	if !errors.Is(v.Err, errPrivateAuth) || isRetryable(v.Err) {
+		t.Error("Expected a non retryable auth error, got", v.Err)
+	}
+}
+
+func TestRotateCredentials(t *testing.T) {
+	keys := make(chan string, 2)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		auth := readAuth(t, ws)
+		keys <- auth.ApiKey
+		writeFrames(ws, `[0,"ws",[["exchange","BTC",1.5,0]]]`)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.Auth("old-key", "old-secret")
+	terms := make(chan TermData, 20)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+
+	receiveTerm(t, terms)
+	receiveTerm(t, terms)
+	c.WebSocket.RotateCredentials("new-key", "new-secret")
+
+	expected := []TermData{
+		{Status: STATUS_CREDENTIALS_ROTATED},
+		{Term: "ws", SnapshotStart: true},
+		{Term: "ws"},
+	}
+	for _, e := range expected {
+		v := receiveTerm(t, terms)
+		if v.HasError() || v.Term != e.Term || v.Status != e.Status || v.SnapshotStart != e.SnapshotStart {
+			t.Error("Expected", e)
+			t.Error("Actual ", v)
+		}
+	}
+
+	if k := <-keys; k != "old-key" {
+		t.Error("Expected", "old-key")
+		t.Error("Actual ", k)
+	}
+	if k := <-keys; k != "new-key" {
+		t.Error("Expected", "new-key")
+		t.Error("Actual ", k)
+	}
+}
+
+func TestAuthInfo(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		var msg privateConnect
+		ws.ReadJSON(&msg)
+		writeFrames(ws,
+			`{"event":"auth","status":"OK","chanId":0,"userId":42,"caps":{"orders":{"read":1,"write":1},"withdraw":{"read":0,"write":0}}}`,
+			`[0,"os",[]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if _, ok := c.WebSocket.AuthInfo(); ok {
+		t.Error("Expected no auth info before connecting")
+	}
+
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+	receiveTerm(t, terms)
+
+	info, ok := c.WebSocket.AuthInfo()
+	if !ok || info.UserId != 42 || !info.Caps.Orders.Write || info.Caps.Withdraw.Read {
+		t.Error("Unexpected auth info", info)
+	}
+}
+
+func TestOnSendFrame(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.Auth("key", "secret")
+	frames := make(chan string, 10)
+	c.WebSocket.OnSendFrame = func(frame []byte) { frames <- string(frame) }
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+
+	select {
+	case frame := <-frames:
+		if !strings.Contains(frame, `"authSig":"***"`) || !strings.Contains(frame, `"apiKey":"***"`) {
+			t.Error("Unexpected auth frame", frame)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the auth frame")
+	}
+}
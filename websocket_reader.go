@@ -0,0 +1,101 @@
+package bitfinex
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ErrIdleTimeout is returned by Subscribe when nothing was received on the
+// public connection for IdleTimeout. It is retried with AutoReconnect.
+var ErrIdleTimeout = errors.New("no message received within the idle timeout")
+
+// wsFrame is a message read from a connection.
+type wsFrame struct {
+	mt int
+	p  []byte
+}
+
+// wsReader reads a connection on its own goroutine, so that the read loop
+// can wait on timers and its context besides the next frame. There is a
+// single one per connection: a frame read after the loop returned is kept
+// for the loop started again on the same connection.
+type wsReader struct {
+	ws *websocket.Conn
+	// frames is closed once reading stops
+	frames chan wsFrame
+	// err is the read error, set before frames is closed
+	err  error
+	quit chan struct{}
+}
+
+func newWsReader(ws *websocket.Conn) *wsReader {
+	r := &wsReader{ws: ws, frames: make(chan wsFrame), quit: make(chan struct{})}
+	go r.run()
+	return r
+}
+
+func (r *wsReader) run() {
+	defer close(r.frames)
+	for {
+		mt, p, err := r.ws.ReadMessage()
+		if err != nil {
+			r.err = err
+			return
+		}
+		select {
+		case r.frames <- wsFrame{mt: mt, p: p}:
+		case <-r.quit:
+			return
+		}
+	}
+}
+
+// reader returns the reader of the public connection, replacing the one
+// of a previous connection.
+func (w *WebSocketService) reader() *wsReader {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wsReader == nil || w.wsReader.ws != w.ws {
+		if w.wsReader != nil {
+			// its connection is closed, a pending read returns
+			close(w.wsReader.quit)
+		}
+		w.wsReader = newWsReader(w.ws)
+	}
+	return w.wsReader
+}
+
+// idleTimer fires after IdleTimeout without a frame. Its channel is nil,
+// never ready, without IdleTimeout.
+type idleTimer struct {
+	t *time.Timer
+	C <-chan time.Time
+}
+
+func newIdleTimer(d time.Duration) *idleTimer {
+	if d <= 0 {
+		return &idleTimer{}
+	}
+	t := time.NewTimer(d)
+	return &idleTimer{t: t, C: t.C}
+}
+
+// reset restarts the timer after a frame. It must not have fired, or its
+// channel must have been drained.
+func (i *idleTimer) reset(d time.Duration) {
+	if i.t == nil {
+		return
+	}
+	if !i.t.Stop() {
+		<-i.t.C
+	}
+	i.t.Reset(d)
+}
+
+func (i *idleTimer) stop() {
+	if i.t != nil {
+		i.t.Stop()
+	}
+}
@@ -0,0 +1,95 @@
+package bitfinex
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestIdleTimeout(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws, `{"event":"subscribed","channel":"trades","chanId":4,"pair":"BTCUSD"}`)
+		for i := 0; i < 3; i++ {
+			time.Sleep(30 * time.Millisecond)
+			writeFrames(ws, `[4,"hb"]`)
+		}
+		// silent from now on
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+	c.WebSocket.IdleTimeout = 100 * time.Millisecond
+	c.WebSocket.SubscribeTrades(BTCUSD, make(chan TradeUpdate, 1))
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- c.WebSocket.Subscribe() }()
+	select {
+	case err := <-done:
+		if err != ErrIdleTimeout {
+			t.Error("Expected", ErrIdleTimeout)
+			t.Error("Actual ", err)
+		}
+		// the heartbeats kept the connection alive
+		if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+			t.Error("Expected the timeout after the heartbeats, got it after", elapsed)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for the idle timeout")
+	}
+}
+
+func TestSubscribeContext(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"trades","chanId":4,"pair":"BTCUSD"}`,
+			`[4,"te",1,1443659698,236.42,0.5]`,
+		)
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	trades := make(chan TradeUpdate, 1)
+	c.WebSocket.SubscribeTrades(BTCUSD, trades)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- c.WebSocket.SubscribeContext(ctx) }()
+	select {
+	case <-trades:
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the trade")
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != context.Canceled {
+			t.Error("Expected", context.Canceled)
+			t.Error("Actual ", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for SubscribeContext to return")
+	}
+	if !c.WebSocket.isClosed() {
+		t.Error("Expected the connection to be closed")
+	}
+}
@@ -0,0 +1,133 @@
+package bitfinex
+
+import (
+	"strings"
+	"time"
+)
+
+// StatusUpdate is a message of the status channel. Derivatives keys, e.g.
+// "deriv:tBTCF0:USTF0", fill the derivatives fields; the "liq:global" key
+// fills Liquidations instead.
+type StatusUpdate struct {
+	Key       string
+	Timestamp time.Time
+
+	// Derivatives status:
+	// [MTS, _, DERIV_PRICE, SPOT_PRICE, _, INSURANCE_FUND_BALANCE, _,
+	// NEXT_FUNDING_EVT_MTS, NEXT_FUNDING_ACCRUED, NEXT_FUNDING_STEP, _,
+	// CURRENT_FUNDING, _, _, MARK_PRICE, _, _, OPEN_INTEREST]
+	DerivPrice           float64
+	SpotPrice            float64
+	InsuranceFundBalance float64
+	NextFunding          time.Time
+	NextFundingAccrued   float64
+	NextFundingStep      int
+	FundingRate          float64
+	MarkPrice            float64
+	OpenInterest         float64
+
+	Liquidations []Liquidation
+}
+
+// Liquidation is an entry of the liquidation feed:
+// ["pos", POS_ID, MTS, _, SYMBOL, AMOUNT, BASE_PRICE, _, IS_MATCH,
+// IS_MARKET_SOLD, _, LIQUIDATION_PRICE]
+type Liquidation struct {
+	PositionId       int64
+	Timestamp        time.Time
+	Symbol           string
+	Amount           float64
+	BasePrice        float64
+	IsMatch          bool
+	IsMarketSold     bool
+	LiquidationPrice float64
+}
+
+// SubscribeStatus adds a status channel subscription for key, delivering
+// updates to c once Subscribe is called. The channel is only served by the
+// v2 endpoint, so the client WebSocketURL must be DefaultWebSocketV2URL.
+func (w *WebSocketService) SubscribeStatus(key string, c chan StatusUpdate) error {
+	return w.addSubscribe(&subscribeToChannel{
+		Channel: CHAN_STATUS,
+		Key:     key,
+		out:     c,
+		deliver: func(f dataFrame) {
+			if update, ok := decodeStatus(key, f); ok {
+				c <- update
+			}
+		},
+	})
+}
+
+func decodeStatus(key string, f dataFrame) (StatusUpdate, bool) {
+	update := StatusUpdate{Key: key}
+	if strings.HasPrefix(key, "liq:") {
+		for _, row := range f.Items {
+			if liq, ok := decodeLiquidation(row); ok {
+				update.Liquidations = append(update.Liquidations, liq)
+			}
+		}
+		if len(update.Liquidations) == 0 {
+			return update, false
+		}
+		update.Timestamp = update.Liquidations[len(update.Liquidations)-1].Timestamp
+		return update, true
+	}
+
+	if len(f.Rows) == 0 || len(f.Rows[0]) < 18 {
+		return update, false
+	}
+	row := f.Rows[0]
+	update.Timestamp = msTime(row[0])
+	update.DerivPrice = row[2]
+	update.SpotPrice = row[3]
+	update.InsuranceFundBalance = row[5]
+	if row[7] != 0 {
+		update.NextFunding = msTime(row[7])
+	}
+	update.NextFundingAccrued = row[8]
+	update.NextFundingStep = int(row[9])
+	update.FundingRate = row[11]
+	update.MarkPrice = row[14]
+	update.OpenInterest = row[17]
+	return update, true
+}
+
+func decodeLiquidation(row []interface{}) (Liquidation, bool) {
+	if len(row) < 12 {
+		return Liquidation{}, false
+	}
+	if kind, _ := row[0].(string); kind != "pos" {
+		return Liquidation{}, false
+	}
+	nums := floatRow(row)
+	symbol, _ := row[4].(string)
+	return Liquidation{
+		PositionId:       int64(nums[1]),
+		Timestamp:        msTime(nums[2]),
+		Symbol:           symbol,
+		Amount:           nums[5],
+		BasePrice:        nums[6],
+		IsMatch:          nums[8] == 1,
+		IsMarketSold:     nums[9] == 1,
+		LiquidationPrice: nums[11],
+	}, true
+}
+
+func msTime(ms float64) time.Time {
+	return time.Unix(0, int64(ms)*int64(time.Millisecond))
+}
+
+// isStatus reports whether payload belongs to a status subscription, whose
+// rows mix numbers with nulls and strings. It locks mu, the subscriptions
+// change while the read loop runs.
+func (w *WebSocketService) isStatus(payload []interface{}) bool {
+	if len(payload) == 0 {
+		return false
+	}
+	chanId, _ := payload[0].(float64)
+	w.mu.Lock()
+	s, ok := w.chanMap[chanId]
+	w.mu.Unlock()
+	return ok && s.Channel == CHAN_STATUS
+}
@@ -0,0 +1,90 @@
+package bitfinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribeStatus(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"status","chanId":5,"key":"deriv:tBTCF0:USTF0"}`,
+			`{"event":"subscribed","channel":"status","chanId":6,"key":"liq:global"}`,
+			`[5,[1596124822000,null,11000.5,10990,null,1000000,null,1596124800000,0.0001,1,null,-0.0002,null,null,11001,null,null,250.5,null,null,null,null,null]]`,
+			`[6,[["pos",145400868,1609144352338,null,"tETHF0:USTF0",-1.67288094,730.96,null,1,1,null,736.13]]]`,
+			`[5,"hb"]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+	c.WebSocket.StrictDecoding = true
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	deriv := make(chan StatusUpdate, 1)
+	liq := make(chan StatusUpdate, 1)
+	c.WebSocket.SubscribeStatus("deriv:tBTCF0:USTF0", deriv)
+	c.WebSocket.SubscribeStatus("liq:global", liq)
+	go c.WebSocket.Subscribe()
+
+	select {
+	case v := <-deriv:
+		if v.Key != "deriv:tBTCF0:USTF0" || !v.Timestamp.Equal(time.Unix(1596124822, 0)) ||
+			v.DerivPrice != 11000.5 || v.SpotPrice != 10990 || v.InsuranceFundBalance != 1000000 ||
+			!v.NextFunding.Equal(time.Unix(1596124800, 0)) || v.NextFundingAccrued != 0.0001 ||
+			v.NextFundingStep != 1 || v.FundingRate != -0.0002 || v.MarkPrice != 11001 || v.OpenInterest != 250.5 {
+			t.Error("Unexpected derivatives status", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the derivatives status")
+	}
+
+	expected := Liquidation{
+		PositionId:       145400868,
+		Timestamp:        time.Unix(0, 1609144352338*int64(time.Millisecond)),
+		Symbol:           "tETHF0:USTF0",
+		Amount:           -1.67288094,
+		BasePrice:        730.96,
+		IsMatch:          true,
+		IsMarketSold:     true,
+		LiquidationPrice: 736.13,
+	}
+	select {
+	case v := <-liq:
+		if len(v.Liquidations) != 1 || v.Liquidations[0] != expected {
+			t.Error("Expected", expected)
+			t.Error("Actual ", v.Liquidations)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for liquidations")
+	}
+}
+
+func TestIsStatusWhileSubscribing(t *testing.T) {
+	w := NewClient().WebSocket
+	status := &subscribeToChannel{Channel: CHAN_STATUS}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			// as done by handleEventMessage and Unsubscribe
+			w.mu.Lock()
+			w.chanMap[float64(i)] = status
+			w.mu.Unlock()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		w.isStatus([]interface{}{float64(i), 1})
+	}
+	<-done
+	if !w.isStatus([]interface{}{float64(99), 1}) || w.isStatus([]interface{}{float64(100), 1}) {
+		t.Error("Expected only linked status channels to be reported")
+	}
+}
@@ -0,0 +1,117 @@
+package bitfinex
+
+import (
+	"fmt"
+)
+
+// DecodeError is returned with StrictDecoding for a frame that doesn't
+// have the expected shape.
+type DecodeError struct {
+	// Path locates the mismatch, e.g. "book BTCUSD: row 2"
+	Path string
+	Err  string
+	// Raw is the frame as received, empty when it was decoded earlier
+	Raw string
+}
+
+func (e *DecodeError) Error() string {
+	if e.Raw == "" {
+		return fmt.Sprintf("decoding %s: %s", e.Path, e.Err)
+	}
+	return fmt.Sprintf("decoding %s: %s in %s", e.Path, e.Err, e.Raw)
+}
+
+// checkPayload verifies a frame decoded by the generic decoder: a numeric
+// chanId followed by a heartbeat, a term or numbers.
+func checkPayload(payload []interface{}) error {
+	if len(payload) < 2 {
+		return &DecodeError{Path: "frame", Err: fmt.Sprintf("%d elements, want at least 2", len(payload))}
+	}
+	if _, ok := payload[0].(float64); !ok {
+		return &DecodeError{Path: "chanId", Err: fmt.Sprintf("%T, want a number", payload[0])}
+	}
+
+	switch v := payload[1].(type) {
+	case string:
+		if v == "hb" {
+			return nil
+		}
+		if len(payload) == 3 {
+			if row, ok := payload[2].([]interface{}); ok {
+				// v2 trades term
+				return checkNumbers("term "+v, row)
+			}
+		}
+		if len(payload) < 4 {
+			return &DecodeError{Path: "term " + v, Err: fmt.Sprintf("%d elements, want at least 4", len(payload))}
+		}
+		return checkNumbers("term "+v, payload[3:])
+	case []interface{}:
+		if len(v) > 0 {
+			if _, ok := v[0].(float64); ok {
+				return checkNumbers("update", v)
+			}
+		}
+		for i, item := range v {
+			row, ok := item.([]interface{})
+			if !ok {
+				return &DecodeError{Path: fmt.Sprintf("snapshot row %d", i), Err: fmt.Sprintf("%T, want an array", item)}
+			}
+			if err := checkNumbers(fmt.Sprintf("snapshot row %d", i), row); err != nil {
+				return err
+			}
+		}
+		return nil
+	case float64:
+		return checkNumbers("update", payload[1:])
+	}
+	return &DecodeError{Path: "data", Err: fmt.Sprintf("%T, want a heartbeat, a term, a snapshot or a number", payload[1])}
+}
+
+func checkNumbers(path string, items []interface{}) error {
+	for i, item := range items {
+		if _, ok := item.(float64); !ok {
+			return &DecodeError{Path: fmt.Sprintf("%s field %d", path, i), Err: fmt.Sprintf("%T, want a number", item)}
+		}
+	}
+	return nil
+}
+
+// checkFrame verifies the row lengths the typed decoders of channel expect.
+func checkFrame(channel, pair string, f dataFrame) error {
+	want := 0
+	switch channel {
+	case CHAN_BOOK:
+		want = 3
+	case CHAN_TICKER:
+		want = 10
+	case CHAN_CANDLES:
+		want = 6
+	case CHAN_TRADE:
+		switch {
+		case f.Snapshot:
+			want = 4
+		case f.Term == "te":
+			want = 3
+		case f.Term == "tu":
+			want = 4
+		}
+	}
+	for i, row := range f.Rows {
+		if len(row) < want {
+			return &DecodeError{
+				Path: fmt.Sprintf("%s %s: row %d", channel, pair, i),
+				Err:  fmt.Sprintf("%d fields, want %d", len(row), want),
+			}
+		}
+	}
+	return nil
+}
+
+func isPrivateHeartbeat(data []interface{}) bool {
+	if len(data) != 2 {
+		return false
+	}
+	hb, _ := data[1].(string)
+	return hb == "hb"
+}
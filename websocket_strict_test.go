@@ -0,0 +1,70 @@
+package bitfinex
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestStrictDecoding(t *testing.T) {
+	frames := map[string]string{
+		`[5,[[450,2]]]`:       "decoding book BTCUSD: row 0: 2 fields, want 3 in [5,[[450,2]]]",
+		`[5,450,"x",1]`:       `decoding update field 1: string, want a number in [5,450,"x",1]`,
+		`[5,{"price":450}]`:   `decoding data: map[string]interface {}, want a heartbeat, a term, a snapshot or a number in [5,{"price":450}]`,
+		`[5,[[450,2,1],450]]`: "decoding snapshot row 1: float64, want an array in [5,[[450,2,1],450]]",
+		` `:                   "decoding frame: empty message",
+	}
+	for frame, expected := range frames {
+		srv, c := newMockServer(t, func(ws *websocket.Conn) {
+			readSubscribe(t, ws)
+			writeFrames(ws,
+				`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+				`[5,"hb"]`,
+				frame,
+			)
+			ws.ReadMessage()
+		})
+
+		c.WebSocket.StrictDecoding = true
+		c.WebSocket.AutoReconnect = true
+		if err := c.WebSocket.Connect(); err != nil {
+			t.Fatal(err)
+		}
+		c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64, 10))
+		done := make(chan error)
+		go func() { done <- c.WebSocket.Subscribe() }()
+
+		select {
+		case err := <-done:
+			if _, ok := err.(*DecodeError); !ok || err.Error() != expected {
+				t.Error("Expected", expected)
+				t.Error("Actual ", err)
+			}
+		case <-time.After(time.Second):
+			t.Error("timed out waiting for the decode error of", frame)
+		}
+		c.WebSocket.Close()
+		srv.Close()
+	}
+}
+
+func TestStrictDecodingPrivate(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readAuth(t, ws)
+		writeFrames(ws, `[0,"hb"]`, `[0,"ws","exchange"]`)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.StrictDecoding = true
+	c.WebSocket.AutoReconnect = true
+	terms := make(chan TermData, 10)
+	go c.WebSocket.ConnectPrivate(terms)
+	defer c.WebSocket.ClosePrivate()
+
+	if v := receiveTerm(t, terms); !strings.HasPrefix(v.Error, "decoding term ws: string, want an array") {
+		t.Error("Expected a decode error, got", v)
+	}
+}
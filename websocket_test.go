@@ -0,0 +1,1365 @@
+package bitfinex
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// newMockServer starts a websocket server running handler for every
+// connection and returns a client pointed at it.
+func newMockServer(t testing.TB, handler func(ws *websocket.Conn)) (*httptest.Server, *Client) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		ws, err := upgrader.Upgrade(rw, req, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer ws.Close()
+		handler(ws)
+	}))
+
+	c := NewClient()
+	c.WebSocketURL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	return srv, c
+}
+
+// readSubscribe reads one subscribe request sent by the client.
+func readSubscribe(t *testing.T, ws *websocket.Conn) SubscribeMsg {
+	var msg SubscribeMsg
+	if err := ws.ReadJSON(&msg); err != nil {
+		t.Error(err)
+	}
+	return msg
+}
+
+// readSubscribeOrClose is readSubscribe for a reconnect test where the
+// client may disconnect first, leaving msg empty.
+func readSubscribeOrClose(ws *websocket.Conn) SubscribeMsg {
+	var msg SubscribeMsg
+	ws.ReadJSON(&msg)
+	return msg
+}
+
+func writeFrames(ws *websocket.Conn, frames ...string) {
+	for _, f := range frames {
+		ws.WriteMessage(websocket.TextMessage, []byte(f))
+	}
+}
+
+func TestSubscribeRawBook(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1.5],[451,1,-2]]]`,
+			`[5,"hb"]`,
+			`[5,450.5,1,0.5]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	go c.WebSocket.Subscribe()
+
+	snapshot := receiveRaw(t, book)
+	if len(snapshot) != 3 || snapshot[1][0] != 450 {
+		t.Error("Expected snapshot with reset marker and 2 levels")
+		t.Error("Actual ", snapshot)
+	}
+
+	update := receiveRaw(t, book)
+	if len(update) != 1 || update[0][0] != 450.5 || len(update[0]) != 3 {
+		t.Error("Expected", [][]float64{{450.5, 1, 0.5}})
+		t.Error("Actual ", update)
+	}
+
+	select {
+	case v := <-book:
+		t.Error("Unexpected message", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func receiveRaw(t *testing.T, c chan [][]float64) [][]float64 {
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for data")
+	}
+	return nil
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[`+strings.Repeat(`[450,2,1.5],`, 100)+`[451,1,-2]]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.MaxMessageSize = 512
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64, 10))
+	if err := c.WebSocket.Subscribe(); err != websocket.ErrReadLimit {
+		t.Error("Expected", websocket.ErrReadLimit)
+		t.Error("Actual ", err)
+	}
+}
+
+func TestHandshakeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		http.Error(rw, "invalid api key", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.WebSocketURL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	var debugStatus int
+	c.WebSocket.DebugHandshake = func(resp *http.Response) {
+		debugStatus = resp.StatusCode
+	}
+
+	err := c.WebSocket.Connect()
+	herr, ok := err.(*HandshakeError)
+	if !ok {
+		t.Fatal("Expected *HandshakeError, got", err)
+	}
+	if herr.Response.StatusCode != http.StatusForbidden || !strings.Contains(err.Error(), "invalid api key") {
+		t.Error("Expected status and body in error")
+		t.Error("Actual ", err)
+	}
+	if debugStatus != http.StatusForbidden {
+		t.Error("Expected", http.StatusForbidden)
+		t.Error("Actual ", debugStatus)
+	}
+}
+
+func TestSubprotocols(t *testing.T) {
+	var offered []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		offered = websocket.Subprotocols(req)
+		ws, err := (&websocket.Upgrader{}).Upgrade(rw, req, nil)
+		if err == nil {
+			ws.Close()
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.WebSocketURL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	c.WebSocket.Close()
+	if len(offered) != 0 {
+		t.Error("Expected no subprotocols, got", offered)
+	}
+
+	c.WebSocket.Subprotocols = []string{"custom"}
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	c.WebSocket.Close()
+	if len(offered) != 1 || offered[0] != "custom" {
+		t.Error("Expected", []string{"custom"})
+		t.Error("Actual ", offered)
+	}
+}
+
+// newConnectProxy starts an HTTP CONNECT proxy, recording the requests it
+// tunnels. If check is set it may reject a request with a status code.
+func newConnectProxy(t *testing.T, check func(req *http.Request) int) (*httptest.Server, *[]string) {
+	var tunneled []string
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if check != nil {
+			if code := check(req); code != 0 {
+				rw.WriteHeader(code)
+				return
+			}
+		}
+		if req.Method != http.MethodConnect {
+			rw.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", req.Host)
+		if err != nil {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		tunneled = append(tunneled, req.Host)
+		rw.WriteHeader(http.StatusOK)
+		conn, _, err := rw.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		go func() {
+			io.Copy(upstream, conn)
+			upstream.Close()
+		}()
+		io.Copy(conn, upstream)
+		conn.Close()
+	}))
+	return srv, &tunneled
+}
+
+func TestProxy(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	proxy, tunneled := newConnectProxy(t, nil)
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	c.WebSocket.Proxy = http.ProxyURL(proxyURL)
+	// the default environment proxy does not apply to localhost, so a
+	// tunneled request shows the option was used
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	c.WebSocket.Close()
+
+	if len(*tunneled) != 1 || (*tunneled)[0] != strings.TrimPrefix(srv.URL, "http://") {
+		t.Error("Expected connection tunneled to", srv.URL)
+		t.Error("Actual ", *tunneled)
+	}
+}
+
+func TestProxyAuth(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	expected := "Basic " + base64.StdEncoding.EncodeToString([]byte("user:s3cret"))
+	proxy, tunneled := newConnectProxy(t, func(req *http.Request) int {
+		if req.Header.Get("Proxy-Authorization") != expected {
+			return http.StatusProxyAuthRequired
+		}
+		return 0
+	})
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	c.WebSocket.Proxy = http.ProxyURL(proxyURL)
+	if err := c.WebSocket.Connect(); err == nil || !strings.Contains(err.Error(), "Proxy Authentication Required") {
+		t.Fatal("Expected the proxy to require authentication, got", err)
+	}
+
+	proxyURL.User = url.UserPassword("user", "s3cret")
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	c.WebSocket.Close()
+	if len(*tunneled) != 1 {
+		t.Error("Expected the authenticated connection to be tunneled, got", *tunneled)
+	}
+}
+
+func TestMaintenanceInfo(t *testing.T) {
+	var connections int32
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		n := atomic.AddInt32(&connections, 1)
+		readSubscribe(t, ws)
+		if n == 1 {
+			writeFrames(ws,
+				`{"event":"info","version":1}`,
+				`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+				`[5,[[450,2,1.5]]]`,
+				`{"event":"info","code":20060,"msg":"Entering in Maintenance mode"}`,
+				`[5,450.5,1,0.5]`,
+				`{"event":"info","code":20061,"msg":"Maintenance ended"}`,
+			)
+		} else {
+			writeFrames(ws,
+				`{"event":"subscribed","channel":"book","chanId":6,"pair":"BTCUSD"}`,
+				`[6,[[451,1,1]]]`,
+			)
+		}
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	maintenance := make(chan bool, 2)
+	c.WebSocket.OnMaintenance = func(active bool) { maintenance <- active }
+	c.WebSocket.ReconnectInterval = 10 * time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	go c.WebSocket.Subscribe()
+
+	if v := receiveRaw(t, book); v[1][0] != 450 {
+		t.Error("Unexpected first snapshot", v)
+	}
+	// the update sent during maintenance is dropped
+	if v := receiveRaw(t, book); len(v) != 2 || v[1][0] != 451 {
+		t.Error("Expected snapshot after resubscribe, got", v)
+	}
+	if a, b := <-maintenance, <-maintenance; !a || b {
+		t.Error("Expected maintenance start then end, got", a, b)
+	}
+	if c.WebSocket.InMaintenance() {
+		t.Error("Expected maintenance to be over")
+	}
+}
+
+func TestAddSubscribeDuplicate(t *testing.T) {
+	w := NewClient().WebSocket
+	if err := w.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SubscribeBook(BTCUSD, 100, make(chan *OrderBook)); err != ErrAlreadySubscribed {
+		t.Error("Expected", ErrAlreadySubscribed)
+		t.Error("Actual ", err)
+	}
+	if err := w.SubscribeTrades(BTCUSD, make(chan TradeUpdate)); err != nil {
+		t.Error("Expected a trades subscription to be accepted, got", err)
+	}
+	if len(w.subscribes) != 2 {
+		t.Error("Expected 2 subscriptions, got", len(w.subscribes))
+	}
+}
+
+func TestOnBeforeResubscribe(t *testing.T) {
+	var connections int32
+	subscribed := make(chan []SubscribeMsg, 2)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		if atomic.AddInt32(&connections, 1) == 1 {
+			readSubscribe(t, ws)
+			readSubscribe(t, ws)
+			return
+		}
+		subscribed <- []SubscribeMsg{readSubscribe(t, ws), readSubscribe(t, ws)}
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = 10 * time.Millisecond
+	c.WebSocket.OnBeforeResubscribe = func(current []SubscriptionInfo) []SubscriptionInfo {
+		if len(current) != 2 || current[1].Pair != ETHUSD {
+			t.Error("Unexpected current subscriptions", current)
+		}
+		book := current[0]
+		book.Len = 100
+		return []SubscriptionInfo{book, {Channel: CHAN_TRADE, Pair: LTCUSD, Chan: make(chan [][]float64)}}
+	}
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64))
+	c.WebSocket.AddSubscribe(CHAN_TRADE, ETHUSD, 0, make(chan [][]float64))
+	go c.WebSocket.Subscribe()
+
+	select {
+	case msgs := <-subscribed:
+		if msgs[0].Pair != BTCUSD || msgs[0].Len != "100" || msgs[1].Channel != CHAN_TRADE || msgs[1].Pair != LTCUSD {
+			t.Error("Unexpected resubscribe", msgs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for resubscribe")
+	}
+}
+
+func TestOnBlockedSend(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1.5]]]`,
+			`[5,450.5,1,0.5]`,
+			`[5,451,1,-1]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	blocked := make(chan []float64, 2)
+	c.WebSocket.OnBlockedSend = func(chanId float64, backlog int) {
+		blocked <- []float64{chanId, float64(backlog)}
+	}
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 1)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	go c.WebSocket.Subscribe()
+
+	select {
+	case v := <-blocked:
+		if v[0] != 5 || v[1] != 1 {
+			t.Error("Expected chanId 5 with a backlog of 1, got", v)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the blocked send")
+	}
+	receiveRaw(t, book)
+	receiveRaw(t, book)
+	receiveRaw(t, book)
+	if len(blocked) != 0 {
+		t.Error("Expected a single report")
+	}
+}
+
+func TestFramesBeforeSubscribed(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`[5,[[450,2,1.5]]]`,
+			`[5,"hb"]`,
+			`[7,[[1,1,1]]]`,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,450.5,1,0.5]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	go c.WebSocket.Subscribe()
+
+	if v := receiveRaw(t, book); len(v) != 2 || v[1][0] != 450 {
+		t.Error("Expected the early snapshot, got", v)
+	}
+	if v := receiveRaw(t, book); v[0][0] != 450.5 {
+		t.Error("Expected the update, got", v)
+	}
+}
+
+func TestHoldPendingBounds(t *testing.T) {
+	w := NewClient().WebSocket
+	for i := 0; i < maxPendingFrames+10; i++ {
+		w.holdPending(1, dataFrame{Rows: [][]float64{{float64(i)}}})
+	}
+	// chanIds that are never linked
+	for i := 0; i < maxPendingChanIds+10; i++ {
+		w.holdPending(float64(100+i), dataFrame{})
+	}
+	if n := len(w.pending[1]); n != maxPendingFrames {
+		t.Error("Expected", maxPendingFrames)
+		t.Error("Actual ", n)
+	}
+	if n := len(w.pending); n != maxPendingChanIds {
+		t.Error("Expected", maxPendingChanIds)
+		t.Error("Actual ", n)
+	}
+	// a chanId already held keeps its frames
+	w.pending[1] = w.pending[1][:1]
+	w.holdPending(1, dataFrame{})
+	if n := len(w.pending[1]); n != 2 {
+		t.Error("Expected", 2)
+		t.Error("Actual ", n)
+	}
+}
+
+func TestMaxReconnectAttempts(t *testing.T) {
+	var requests int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if atomic.AddInt32(&requests, 1) > 1 {
+			http.Error(rw, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		ws, err := upgrader.Upgrade(rw, req, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		readSubscribe(t, ws)
+		ws.Close()
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.WebSocketURL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = time.Millisecond
+	c.WebSocket.MaxReconnectAttempts = 3
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64))
+	err := c.WebSocket.Subscribe()
+
+	rerr, ok := err.(*ReconnectError)
+	if !ok || rerr.Attempts != 3 {
+		t.Fatal("Expected a ReconnectError after 3 attempts, got", err)
+	}
+	if _, ok := rerr.Err.(*HandshakeError); !ok {
+		t.Error("Expected the last handshake error, got", rerr.Err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Error("Expected", 4)
+		t.Error("Actual ", n)
+	}
+}
+
+func TestSubscribeWithStop(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1.5]]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = 10 * time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	stop, done := c.WebSocket.SubscribeWithStop()
+	receiveRaw(t, book)
+
+	stop()
+	stop()
+	select {
+	case err := <-done:
+		if err != ErrStopped {
+			t.Error("Expected", ErrStopped)
+			t.Error("Actual ", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for Subscribe to return")
+	}
+}
+
+func TestCloseHandshake(t *testing.T) {
+	closeCode := make(chan int, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1.5]]]`,
+		)
+		_, _, err := ws.ReadMessage()
+		if ce, ok := err.(*websocket.CloseError); ok {
+			closeCode <- ce.Code
+		}
+		close(closeCode)
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	done := make(chan error, 1)
+	go func() { done <- c.WebSocket.Subscribe() }()
+	receiveRaw(t, book)
+
+	c.WebSocket.Close()
+	if code := <-closeCode; code != websocket.CloseNormalClosure {
+		t.Error("Expected", websocket.CloseNormalClosure)
+		t.Error("Actual ", code)
+	}
+	// the read loop sees the acknowledgment instead of a broken connection
+	if err := <-done; !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Error("Expected a normal closure, got", err)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		ws, err := upgrader.Upgrade(rw, req, nil)
+		if err != nil {
+			return
+		}
+		ws.Close()
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.WebSocketURL = "wss" + strings.TrimPrefix(srv.URL, "https")
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	if err := c.WebSocket.ConnectOnce(); err == nil {
+		t.Error("Expected an unknown certificate authority")
+	}
+	c.WebSocket.TLSConfig = &tls.Config{RootCAs: roots}
+	if err := c.WebSocket.ConnectOnce(); err != nil {
+		t.Error("Expected the custom root CA to verify the server, got", err)
+	}
+	c.WebSocket.Close()
+
+	// TLSConfig takes precedence over skipping the verification
+	c.WebSocketTLSSkipVerify = true
+	c.WebSocket.TLSConfig = &tls.Config{RootCAs: x509.NewCertPool()}
+	if err := c.WebSocket.ConnectOnce(); err == nil {
+		t.Error("Expected the verification with TLSConfig")
+		c.WebSocket.Close()
+	}
+}
+
+func TestSubscribePriority(t *testing.T) {
+	var connections int32
+	subscribed := make(chan []SubscribeMsg, 2)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		msgs := []SubscribeMsg{readSubscribe(t, ws), readSubscribe(t, ws), readSubscribe(t, ws)}
+		subscribed <- msgs
+		if atomic.AddInt32(&connections, 1) == 1 {
+			return
+		}
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = 10 * time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	c.WebSocket.AddSubscribe(CHAN_TRADE, ETHUSD, 0, make(chan [][]float64))
+	c.WebSocket.AddSubscribe(CHAN_TICKER, ETHUSD, 0, make(chan [][]float64))
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64))
+	if err := c.WebSocket.SetPriority(CHAN_BOOK, BTCUSD, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WebSocket.SetPriority(CHAN_BOOK, LTCUSD, 1); err != ErrNotSubscribed {
+		t.Error("Expected", ErrNotSubscribed)
+		t.Error("Actual ", err)
+	}
+	go c.WebSocket.Subscribe()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case msgs := <-subscribed:
+			if msgs[0].Channel != CHAN_BOOK || msgs[1].Channel != CHAN_TRADE || msgs[2].Channel != CHAN_TICKER {
+				t.Error("Expected", "book, trades, ticker")
+				t.Error("Actual ", msgs)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for subscribe")
+		}
+	}
+}
+
+func TestIsSnapshotReset(t *testing.T) {
+	rows := map[string]bool{
+		"[0 0 0]":   true,
+		"[450 0 0]": false,
+		"[0 0 1]":   false,
+		"[0 0 0 0]": false,
+		"[]":        false,
+	}
+	for name, expected := range rows {
+		var row []float64
+		json.Unmarshal([]byte(strings.ReplaceAll(name, " ", ",")), &row)
+		if IsSnapshotReset(row) != expected {
+			t.Error("Expected", expected, "for", name)
+		}
+	}
+}
+
+func TestSnapshotReset(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[450,1,1],[451,1,-1]]]`,
+			`[5,450,0,1]`,
+			`[5,[[452,1,-1]]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	go c.WebSocket.Subscribe()
+
+	// the sentinel starts every snapshot, once, and never an update
+	for _, expected := range []struct{ rows, resets int }{{3, 1}, {1, 0}, {2, 1}} {
+		v := receiveRaw(t, book)
+		resets := 0
+		for _, row := range v {
+			if IsSnapshotReset(row) {
+				resets++
+			}
+		}
+		if len(v) != expected.rows || resets != expected.resets || (resets == 1 && !IsSnapshotReset(v[0])) {
+			t.Error("Unexpected frame", v)
+		}
+		if len(v) > 0 {
+			v[0][0] = 1
+		}
+	}
+	if !IsSnapshotReset(SnapshotReset) {
+		t.Error("Expected receivers unable to change SnapshotReset, got", SnapshotReset)
+	}
+}
+
+func TestResubscribedChanIds(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1]]]`,
+			// the book is subscribed again under a new chanId
+			`{"event":"subscribed","channel":"book","chanId":7,"pair":"BTCUSD"}`,
+			`[5,451,1,1]`,
+			`[7,[[452,1,1]]]`,
+			// and its old chanId is reused for the ticker
+			`{"event":"subscribed","channel":"ticker","chanId":5,"pair":"BTCUSD"}`,
+			`[5,1,2,3,4,5,6,7,8,9,10]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	ticker := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	c.WebSocket.AddSubscribe(CHAN_TICKER, BTCUSD, 0, ticker)
+	go c.WebSocket.Subscribe()
+
+	if v := receiveRaw(t, book); v[1][0] != 450 {
+		t.Error("Unexpected first snapshot", v)
+	}
+	if v := receiveRaw(t, book); len(v) != 2 || v[1][0] != 452 {
+		t.Error("Expected the second snapshot, got", v)
+	}
+	if v := receiveRaw(t, ticker); len(v[0]) != 10 {
+		t.Error("Expected the ticker update, got", v)
+	}
+	select {
+	case v := <-book:
+		t.Error("Unexpected message", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestDuplicateSubscribedEvent(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`[5,[[450,2,1]]]`,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,451,1,1]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	go c.WebSocket.Subscribe()
+
+	// the held snapshot is flushed once, then updates follow
+	if v := receiveRaw(t, book); v[1][0] != 450 {
+		t.Error("Unexpected snapshot", v)
+	}
+	if v := receiveRaw(t, book); v[0][0] != 451 {
+		t.Error("Unexpected update", v)
+	}
+	select {
+	case v := <-book:
+		t.Error("Unexpected message", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.WebSocket.mu.Lock()
+	defer c.WebSocket.mu.Unlock()
+	if len(c.WebSocket.chanMap) != 1 || c.WebSocket.chanMap[5] == nil || c.WebSocket.retired[5] {
+		t.Error("Expected chanId 5 linked once, got", c.WebSocket.chanMap, c.WebSocket.retired)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	unsubscribed := make(chan unsubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"trades","chanId":6,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1]]]`,
+		)
+		var msg unsubscribeMsg
+		ws.ReadJSON(&msg)
+		unsubscribed <- msg
+		writeFrames(ws,
+			`[5,451,1,1]`,
+			`[6,"te",1,1443659698,236.42,0.5]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	trades := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	c.WebSocket.AddSubscribe(CHAN_TRADE, BTCUSD, 0, trades)
+	c.WebSocket.AddSubscribe(CHAN_TICKER, BTCUSD, 0, make(chan [][]float64))
+	go c.WebSocket.Subscribe()
+	receiveRaw(t, book)
+
+	if err := c.WebSocket.Unsubscribe(CHAN_BOOK, LTCUSD); err != ErrNotSubscribed {
+		t.Error("Expected", ErrNotSubscribed)
+		t.Error("Actual ", err)
+	}
+	// never confirmed, removed without unsubscribing
+	if err := c.WebSocket.Unsubscribe(CHAN_TICKER, BTCUSD); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.WebSocket.Unsubscribe(CHAN_BOOK, BTCUSD); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case msg := <-unsubscribed:
+		if msg.Event != "unsubscribe" || msg.ChanId != 5 {
+			t.Error("Expected", unsubscribeMsg{Event: "unsubscribe", ChanId: 5})
+			t.Error("Actual ", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for unsubscribe")
+	}
+	if v := receiveRaw(t, trades); len(v) != 1 || v[0][1] != 236.42 {
+		t.Error("Unexpected trade", v)
+	}
+	select {
+	case v := <-book:
+		t.Error("Unexpected book update", v)
+	default:
+	}
+
+	c.WebSocket.mu.Lock()
+	defer c.WebSocket.mu.Unlock()
+	if len(c.WebSocket.subscribes) != 1 || c.WebSocket.subscribes[0].Channel != CHAN_TRADE || len(c.WebSocket.chanMap) != 1 {
+		t.Error("Expected only the trades subscription, got", c.WebSocket.subscribes, c.WebSocket.chanMap)
+	}
+}
+
+func TestClearSubscriptions(t *testing.T) {
+	cleared := make(chan struct{})
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1]]]`,
+		)
+		<-cleared
+		writeFrames(ws, `[5,451,1,1]`)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	go c.WebSocket.Subscribe()
+	receiveRaw(t, book)
+
+	c.WebSocket.ClearSubscriptions()
+	c.WebSocket.mu.Lock()
+	if len(c.WebSocket.subscribes) != 0 || len(c.WebSocket.chanMap) != 0 {
+		t.Error("Expected no subscription, got", c.WebSocket.subscribes, c.WebSocket.chanMap)
+	}
+	c.WebSocket.mu.Unlock()
+	close(cleared)
+
+	// the frames of the cleared channel aren't routed anymore
+	select {
+	case v := <-book:
+		t.Error("Unexpected book update", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if err := c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book); err != nil {
+		t.Error("Expected the book can be added again, got", err)
+	}
+}
+
+func TestClosedConsumerChannel(t *testing.T) {
+	unsubscribed := make(chan unsubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"trades","chanId":6,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1]]]`,
+		)
+		var msg unsubscribeMsg
+		ws.ReadJSON(&msg)
+		unsubscribed <- msg
+		writeFrames(ws,
+			`[5,451,1,1]`,
+			`[6,"te",1,1443659698,236.42,0.5]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64)
+	trades := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	c.WebSocket.AddSubscribe(CHAN_TRADE, BTCUSD, 0, trades)
+	close(book)
+	go c.WebSocket.Subscribe()
+
+	select {
+	case msg := <-unsubscribed:
+		if msg.Event != "unsubscribe" || msg.ChanId != 5 {
+			t.Error("Expected", unsubscribeMsg{Event: "unsubscribe", ChanId: 5})
+			t.Error("Actual ", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for unsubscribe")
+	}
+	// the other subscriptions keep working
+	if v := receiveRaw(t, trades); len(v) != 1 || v[0][1] != 236.42 {
+		t.Error("Unexpected trade", v)
+	}
+	if h := c.WebSocket.Health(); h.Subscriptions != 1 {
+		t.Error("Expected", 1)
+		t.Error("Actual ", h.Subscriptions)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	errs := map[error]bool{
+		io.ErrUnexpectedEOF: true,
+		&websocket.CloseError{Code: websocket.CloseAbnormalClosure}: true,
+		&websocket.CloseError{Code: websocket.CloseNormalClosure}:   false,
+		context.Canceled:            false,
+		errPrivateAuth:              false,
+		&DecodeError{Path: "frame"}: false,
+		&HandshakeError{Response: &http.Response{StatusCode: 401}}: false,
+		&HandshakeError{Response: &http.Response{StatusCode: 503}}: true,
+	}
+	for err, expected := range errs {
+		if isRetryable(err) != expected {
+			t.Error("Expected", expected, "for", err)
+		}
+	}
+}
+
+func TestNormalClosureNotRetried(t *testing.T) {
+	var connections int32
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		atomic.AddInt32(&connections, 1)
+		// the last connection is closed by the test
+		readSubscribeOrClose(ws)
+		ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = 10 * time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, make(chan [][]float64))
+
+	if err := c.WebSocket.Subscribe(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Error("Expected a normal closure, got", err)
+	}
+
+	// with a custom classification every error is retried
+	c.WebSocket.IsRetryable = func(err error) bool { return true }
+	c.WebSocket.MaxReconnectAttempts = 1
+	c.WebSocket.Connect()
+	done := make(chan error, 1)
+	go func() { done <- c.WebSocket.Subscribe() }()
+	time.Sleep(100 * time.Millisecond)
+	c.WebSocket.Close()
+	<-done
+	if n := atomic.LoadInt32(&connections); n < 3 {
+		t.Error("Expected reconnects with IsRetryable, got", n, "connections")
+	}
+}
+
+func TestAddSubscribeFunc(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"trades","chanId":6,"pair":"BTCUSD"}`,
+			`[5,[[450,2,1.5]]]`,
+			`[6,"te",1,1443659698,236.42,0.5]`,
+			`[5,450.5,1,0.5]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	received := make(chan interface{}, 10)
+	c.WebSocket.AddSubscribeFunc(CHAN_BOOK, BTCUSD, 25, func(rows [][]float64) { received <- rows })
+	c.WebSocket.SubscribeTradesFunc(BTCUSD, func(t TradeUpdate) { received <- t })
+	if err := c.WebSocket.AddSubscribeFunc(CHAN_BOOK, BTCUSD, 25, func([][]float64) {}); err != ErrAlreadySubscribed {
+		t.Error("Expected", ErrAlreadySubscribed)
+		t.Error("Actual ", err)
+	}
+	go c.WebSocket.Subscribe()
+
+	next := func() interface{} {
+		select {
+		case v := <-received:
+			return v
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for a callback")
+		}
+		return nil
+	}
+	if v, ok := next().([][]float64); !ok || len(v) != 2 || v[1][0] != 450 {
+		t.Error("Expected the snapshot with a reset marker, got", v)
+	}
+	if v, ok := next().(TradeUpdate); !ok || v.Price != 236.42 {
+		t.Error("Expected the trade, got", v)
+	}
+	if v, ok := next().([][]float64); !ok || len(v) != 1 || v[0][0] != 450.5 {
+		t.Error("Expected the update, got", v)
+	}
+}
+
+func TestAddSubscribeNormalizesPair(t *testing.T) {
+	w := NewClient().WebSocket
+	if err := w.AddSubscribe(CHAN_BOOK, "btc/usd", 25, make(chan [][]float64)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.SubscribeBook("tBTCUSD", 25, make(chan *OrderBook)); err != ErrAlreadySubscribed {
+		t.Error("Expected", ErrAlreadySubscribed)
+		t.Error("Actual ", err)
+	}
+	if w.subscribes[0].Pair != BTCUSD {
+		t.Error("Expected", BTCUSD)
+		t.Error("Actual ", w.subscribes[0].Pair)
+	}
+}
+
+func TestFlappingReconnect(t *testing.T) {
+	const flaps = 3
+	var connections int32
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		n := atomic.AddInt32(&connections, 1)
+		if msg := readSubscribe(t, ws); msg.Pair != BTCUSD {
+			return
+		}
+		writeFrames(ws,
+			fmt.Sprintf(`{"event":"subscribed","channel":"book","chanId":%d,"pair":"BTCUSD"}`, 10+n),
+			fmt.Sprintf(`[%d,[[%d,1,1],[%d,1,-1]]]`, 10+n, 100*n, 100*n+1),
+			fmt.Sprintf(`[%d,%d,2,0.5]`, 10+n, 100*n),
+		)
+		if n <= flaps {
+			// drop the connection without a close frame
+			return
+		}
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	go c.WebSocket.Subscribe()
+
+	for n := 1; n <= flaps+1; n++ {
+		// every connection delivers a snapshot behind the reset marker,
+		// then its update
+		for _, snapshot := range []bool{true, false} {
+			select {
+			case rows := <-book:
+				price := float64(100 * n)
+				if snapshot && (len(rows) != 3 || rows[0][0] != 0 || rows[1][0] != price) {
+					t.Fatal("Expected a snapshot from connection", n, "got", rows)
+				}
+				if !snapshot && (len(rows) != 1 || rows[0][0] != price) {
+					t.Fatal("Expected an update from connection", n, "got", rows)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("timed out waiting for connection", n)
+			}
+		}
+	}
+	if h := c.WebSocket.Health(); h.Reconnects != flaps || !h.Connected || h.Confirmed != 1 {
+		t.Error("Unexpected health after reconnecting", h)
+	}
+}
+
+func TestMalformedFrames(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws, `{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`)
+		ws.WriteMessage(websocket.BinaryMessage, []byte{0x1f, 0x8b, 0x08})
+		writeFrames(ws, ``, `<html>502 Bad Gateway</html>`, `null`, `{}`, `"x"`, `[]`, `[5]`, `[[5]]`, `["5",1]`, `[5,{}]`)
+		writeFrames(ws, `[5,450.5,1,0.5]`)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	done := make(chan error, 1)
+	go func() { done <- c.WebSocket.Subscribe() }()
+
+	select {
+	case v := <-book:
+		if len(v) != 1 || v[0][0] != 450.5 {
+			t.Error("Expected the update, got", v)
+		}
+	case err := <-done:
+		t.Fatal("Expected malformed frames to be skipped, got", err)
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the update")
+	}
+}
+
+func TestReconnectOrdering(t *testing.T) {
+	var connections int32
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		if atomic.AddInt32(&connections, 1) == 1 {
+			writeFrames(ws,
+				`{"event":"subscribed","channel":"book","chanId":5,"pair":"BTCUSD"}`,
+				`[5,[[100,1,1]]]`,
+				`[5,101,1,1]`,
+				`[5,102,1,1]`,
+				// chanId 6 is not linked on this connection, the frame is
+				// held and must not reach the subscription linked to 6 later
+				`[6,999,1,1]`,
+			)
+			return
+		}
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"book","chanId":6,"pair":"BTCUSD"}`,
+			`[6,[[200,1,1]]]`,
+			`[6,201,1,1]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	// the consumer only starts reading once both connections delivered
+	book := make(chan [][]float64, 10)
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, 25, book)
+	go c.WebSocket.Subscribe()
+	deadline := time.Now().Add(time.Second)
+	for len(book) < 5 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	expected := [][]float64{{0, 100}, {101}, {102}, {0, 200}, {201}}
+	for _, prices := range expected {
+		v := receiveRaw(t, book)
+		if len(v) != len(prices) {
+			t.Fatal("Expected levels", prices, "got", v)
+		}
+		for i, price := range prices {
+			if v[i][0] != price {
+				t.Fatal("Expected levels", prices, "got", v)
+			}
+		}
+	}
+	select {
+	case v := <-book:
+		t.Error("Unexpected frame", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestConnectRetry(t *testing.T) {
+	var requests int32
+	upgrader := websocket.Upgrader{}
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if n := atomic.AddInt32(&requests, 1); n <= 2 || n == 4 {
+			http.Error(rw, "maintenance", http.StatusServiceUnavailable)
+			return
+		}
+		ws, err := upgrader.Upgrade(rw, req, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		ws.Close()
+	}))
+	defer srv.Close()
+
+	c := NewClient()
+	c.WebSocketURL = "ws" + strings.TrimPrefix(srv.URL, "http")
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = time.Millisecond
+	// unbounded retries need a context that may end them
+	if _, ok := c.WebSocket.Connect().(*HandshakeError); !ok {
+		t.Error("Expected Connect to fail on the first handshake error without MaxReconnectAttempts")
+	}
+	c.WebSocket.MaxReconnectAttempts = 1
+	if _, ok := c.WebSocket.Connect().(*ReconnectError); !ok {
+		t.Error("Expected a ReconnectError after a single attempt")
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Error("Expected", 2)
+		t.Error("Actual ", n)
+	}
+	atomic.StoreInt32(&requests, 0)
+
+	c.WebSocket.MaxReconnectAttempts = 3
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	c.WebSocket.Close()
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Error("Expected", 3)
+		t.Error("Actual ", n)
+	}
+
+	if _, ok := c.WebSocket.ConnectOnce().(*HandshakeError); !ok {
+		t.Error("Expected ConnectOnce to fail on the first handshake error")
+	}
+
+	srv.Config.Handler = http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		http.Error(rw, "maintenance", http.StatusServiceUnavailable)
+	})
+	c.WebSocket.MaxReconnectAttempts = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := c.WebSocket.ConnectContext(ctx)
+	if rerr, ok := err.(*ReconnectError); !ok || rerr.Attempts < 2 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Error("Expected a ReconnectError for the deadline after retries, got", err)
+	}
+}
+
+func TestChannelDefaultLen(t *testing.T) {
+	subscribed := make(chan []SubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		subscribed <- []SubscribeMsg{readSubscribe(t, ws), readSubscribe(t, ws), readSubscribe(t, ws)}
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	c.WebSocket.AddSubscribe(CHAN_BOOK, BTCUSD, ChannelDefaultLen, make(chan [][]float64))
+	c.WebSocket.AddSubscribe(CHAN_TRADE, BTCUSD, ChannelDefaultLen, make(chan [][]float64))
+	c.WebSocket.SubscribeTicker(BTCUSD, make(chan TickerUpdate))
+	go c.WebSocket.Subscribe()
+
+	select {
+	case msgs := <-subscribed:
+		if msgs[0].Len != "25" || msgs[1].Len != "" || msgs[2].Channel != CHAN_TICKER || msgs[2].Len != "" {
+			t.Error("Unexpected subscribe messages", msgs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the subscribe messages")
+	}
+}
@@ -0,0 +1,214 @@
+package bitfinex
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// TickerUpdate is a ticker channel update.
+type TickerUpdate struct {
+	Bid             float64
+	BidSize         float64
+	Ask             float64
+	AskSize         float64
+	DailyChange     float64
+	DailyChangePerc float64
+	LastPrice       float64
+	Volume          float64
+	High            float64
+	Low             float64
+	// Snapshot is set on the first update after subscribing, and again
+	// after every reconnect: the ticker as of the subscription rather than
+	// a change.
+	Snapshot bool
+	// ServerTime is the time the server sent the update, set with CONF_TIMESTAMP.
+	ServerTime time.Time
+}
+
+// SubscribeTicker adds a ticker subscription for pair delivering typed
+// updates to c once Subscribe is called.
+func (w *WebSocketService) SubscribeTicker(pair string, c chan TickerUpdate) error {
+	return w.subscribeTicker(pair, func(t TickerUpdate) { c <- t }, c)
+}
+
+// SubscribeTickerFunc is like SubscribeTicker, calling fn on the read
+// goroutine instead, see AddSubscribeFunc.
+func (w *WebSocketService) SubscribeTickerFunc(pair string, fn func(TickerUpdate)) error {
+	return w.subscribeTicker(pair, fn, nil)
+}
+
+func (w *WebSocketService) subscribeTicker(pair string, fn func(TickerUpdate), out interface{}) error {
+	first := true
+	return w.addSubscribe(&subscribeToChannel{
+		Channel: CHAN_TICKER,
+		Pair:    pair,
+		Len:     ChannelDefaultLen,
+		out:     out,
+		deliver: func(f dataFrame) {
+			if t, ok := decodeTicker(f); ok {
+				t.Snapshot, first = first, false
+				fn(t)
+			}
+		},
+		reset: func() {
+			first = true
+		},
+	})
+}
+
+// SubscribeLastPrice is like SubscribeTicker, but only forwards the updates
+// where LastPrice changed, leaving out the ones that only move the bid or
+// the ask. The snapshot is always forwarded.
+func (w *WebSocketService) SubscribeLastPrice(pair string, c chan TickerUpdate) error {
+	var (
+		last  float64
+		first = true
+	)
+	return w.addSubscribe(&subscribeToChannel{
+		Channel: CHAN_TICKER,
+		Pair:    pair,
+		Len:     ChannelDefaultLen,
+		out:     c,
+		deliver: func(f dataFrame) {
+			t, ok := decodeTicker(f)
+			if !ok || (!first && t.LastPrice == last) {
+				return
+			}
+			last, t.Snapshot, first = t.LastPrice, first, false
+			c <- t
+		},
+		reset: func() {
+			first = true
+		},
+	})
+}
+
+// SubscribeAllTickers subscribes the ticker of every pair listed by
+// Pairs.All and returns the channel of each pair, keyed by the upper case
+// pair name. It fails without subscribing anything if the pairs don't fit
+// in the MaxChannelsPerConnection of this connection; a Pool spreads them
+// over several connections instead. When a pair fails to subscribe, the
+// ones subscribed before are unsubscribed again.
+func (w *WebSocketService) SubscribeAllTickers() (map[string]chan TickerUpdate, error) {
+	pairs, err := w.client.Pairs.All()
+	if err != nil {
+		return nil, err
+	}
+	if len(w.subscriptions())+len(pairs) > MaxChannelsPerConnection {
+		return nil, fmt.Errorf("%d pairs exceed the limit of %d channels per connection", len(pairs), MaxChannelsPerConnection)
+	}
+	return subscribeTickers(pairs, w.SubscribeTicker, func(pair string) error {
+		return w.Unsubscribe(CHAN_TICKER, pair)
+	})
+}
+
+// subscribeTickers subscribes a ticker channel for each of pairs, all or
+// none: on failure the pairs subscribed so far are unsubscribed.
+func subscribeTickers(pairs []string, subscribe func(pair string, c chan TickerUpdate) error, unsubscribe func(pair string) error) (map[string]chan TickerUpdate, error) {
+	tickers := make(map[string]chan TickerUpdate, len(pairs))
+	for _, pair := range pairs {
+		pair = NormalizeSymbol(pair, SYMBOL_WEBSOCKET)
+		c := make(chan TickerUpdate, 1)
+		if err := subscribe(pair, c); err != nil {
+			for subscribed := range tickers {
+				if uerr := unsubscribe(subscribed); uerr != nil {
+					log.Println("Error unsubscribing ticker", subscribed, uerr)
+				}
+			}
+			return nil, fmt.Errorf("%s: %v", pair, err)
+		}
+		tickers[pair] = c
+	}
+	return tickers, nil
+}
+
+// ErrWaitTimeout is returned by WaitForPrice when no ticker update matched
+// in time.
+var ErrWaitTimeout = errors.New("timed out waiting for a matching ticker update")
+
+// WaitForPrice watches the ticker of pair until predicate returns true for
+// an update, which is returned, or until timeout. It uses a connection of
+// its own, with the dial settings of w, that is closed before returning,
+// so w itself can be connected or not.
+//
+// For instance, to wait for BTCUSD to cross 10000:
+//
+//	t, err := w.WaitForPrice(BTCUSD, func(t TickerUpdate) bool { return t.LastPrice >= 10000 }, time.Hour)
+func (w *WebSocketService) WaitForPrice(pair string, predicate func(TickerUpdate) bool, timeout time.Duration) (TickerUpdate, error) {
+	ws := NewWebSocketService(w.client)
+	ws.MaxMessageSize = w.MaxMessageSize
+	ws.IdleTimeout = w.IdleTimeout
+	ws.StrictDecoding = w.StrictDecoding
+	ws.Version = w.Version
+	ws.Unmarshal = w.Unmarshal
+	ws.Subprotocols = w.Subprotocols
+	ws.Proxy = w.Proxy
+	ws.TLSConfig = w.TLSConfig
+	ws.OnSendFrame = w.OnSendFrame
+	ws.DebugHandshake = w.DebugHandshake
+
+	if err := ws.Connect(); err != nil {
+		return TickerUpdate{}, err
+	}
+	defer ws.Close()
+
+	// closed before the connection, so that the read loop never blocks on
+	// an update nobody waits for anymore
+	stop := make(chan struct{})
+	tickers := make(chan TickerUpdate, 1)
+	err := ws.SubscribeTickerFunc(pair, func(t TickerUpdate) {
+		select {
+		case tickers <- t:
+		case <-stop:
+		}
+	})
+	if err != nil {
+		return TickerUpdate{}, err
+	}
+	defer ws.Unsubscribe(CHAN_TICKER, pair)
+	defer close(stop)
+	done := make(chan error, 1)
+	go func() {
+		done <- ws.Subscribe()
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		select {
+		case t := <-tickers:
+			if predicate(t) {
+				return t, nil
+			}
+		case err := <-done:
+			return TickerUpdate{}, err
+		case <-timer.C:
+			return TickerUpdate{}, ErrWaitTimeout
+		}
+	}
+}
+
+// decodeTicker converts a ticker frame:
+// [BID, BID_SIZE, ASK, ASK_SIZE, DAILY_CHANGE, DAILY_CHANGE_PERC,
+// LAST_PRICE, VOLUME, HIGH, LOW]
+func decodeTicker(f dataFrame) (TickerUpdate, bool) {
+	if len(f.Rows) == 0 || len(f.Rows[0]) < 10 {
+		return TickerUpdate{}, false
+	}
+	row := f.Rows[0]
+	return TickerUpdate{
+		Bid:             row[0],
+		BidSize:         row[1],
+		Ask:             row[2],
+		AskSize:         row[3],
+		DailyChange:     row[4],
+		DailyChangePerc: row[5],
+		LastPrice:       row[6],
+		Volume:          row[7],
+		High:            row[8],
+		Low:             row[9],
+		ServerTime:      f.ServerTime,
+	}, true
+}
@@ -0,0 +1,279 @@
+package bitfinex
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribeTicker(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`[3,"hb"]`,
+			`[3,449,1,451,2,-1,-0.01,450,1000,460,440]`,
+			`[3,449,1,451,2,-1,-0.01,450,1000,460,440]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	tickers := make(chan TickerUpdate, 10)
+	c.WebSocket.SubscribeTicker(BTCUSD, tickers)
+	go c.WebSocket.Subscribe()
+
+	expected := TickerUpdate{
+		Bid: 449, BidSize: 1, Ask: 451, AskSize: 2, DailyChange: -1, DailyChangePerc: -0.01,
+		LastPrice: 450, Volume: 1000, High: 460, Low: 440, Snapshot: true,
+	}
+	// only the first update is the snapshot
+	for _, snapshot := range []bool{true, false} {
+		expected.Snapshot = snapshot
+		select {
+		case v := <-tickers:
+			if v != expected {
+				t.Error("Expected", expected)
+				t.Error("Actual ", v)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for ticker")
+		}
+	}
+}
+
+func TestTickerSnapshotReconnect(t *testing.T) {
+	var connections int32
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`[3,449,1,451,2,-1,-0.01,450,1000,460,440]`,
+			`[3,449,1,451,2,-1,-0.01,451,1000,460,440]`,
+		)
+		if atomic.AddInt32(&connections, 1) == 1 {
+			return
+		}
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.AutoReconnect = true
+	c.WebSocket.ReconnectInterval = 10 * time.Millisecond
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	tickers := make(chan TickerUpdate, 10)
+	c.WebSocket.SubscribeTicker(BTCUSD, tickers)
+	go c.WebSocket.Subscribe()
+
+	for i, snapshot := range []bool{true, false, true, false} {
+		select {
+		case v := <-tickers:
+			if v.Snapshot != snapshot {
+				t.Error("Expected", snapshot, "for update", i)
+				t.Error("Actual ", v.Snapshot)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for ticker")
+		}
+	}
+}
+
+func TestSubscribeLastPrice(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`[3,449,1,451,2,-1,-0.01,450,1000,460,440]`,
+			`[3,449.5,1,451,2,-1,-0.01,450,1000,460,440]`,
+			`[3,449.5,1,450.5,2,-1,-0.01,450,1000,460,440]`,
+			`[3,450,1,451,2,-1,-0.01,450.5,1001,460,440]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	tickers := make(chan TickerUpdate, 10)
+	c.WebSocket.SubscribeLastPrice(BTCUSD, tickers)
+	go c.WebSocket.Subscribe()
+
+	for _, price := range []float64{450, 450.5} {
+		select {
+		case v := <-tickers:
+			if v.LastPrice != price {
+				t.Error("Expected", price)
+				t.Error("Actual ", v.LastPrice)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for ticker")
+		}
+	}
+	select {
+	case v := <-tickers:
+		t.Error("Unexpected update", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestSubscribeAllTickers(t *testing.T) {
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`["btcusd","ltcusd"]`)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`{"event":"subscribed","channel":"ticker","chanId":4,"pair":"LTCUSD"}`,
+			`[4,3.1,1,3.2,2,0.1,0.03,3.15,100,3.3,3]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	tickers, err := c.WebSocket.SubscribeAllTickers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tickers) != 2 || tickers[BTCUSD] == nil {
+		t.Fatal("Unexpected tickers", tickers)
+	}
+	go c.WebSocket.Subscribe()
+
+	select {
+	case v := <-tickers[LTCUSD]:
+		if v.LastPrice != 3.15 {
+			t.Error("Expected", 3.15)
+			t.Error("Actual ", v.LastPrice)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for ticker")
+	}
+}
+
+func TestSubscribeAllTickersLimit(t *testing.T) {
+	pairs := make([]string, MaxChannelsPerConnection+1)
+	for i := range pairs {
+		pairs[i] = fmt.Sprintf("p%02dusd", i)
+	}
+	msg, _ := json.Marshal(pairs)
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBuffer(msg)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+
+	w := NewClient().WebSocket
+	if _, err := w.SubscribeAllTickers(); err == nil {
+		t.Error("Expected an error for too many pairs")
+	}
+	if len(w.subscribes) != 0 {
+		t.Error("Expected no subscriptions, got", len(w.subscribes))
+	}
+}
+
+func TestSubscribeAllTickersRollback(t *testing.T) {
+	httpDo = func(req *http.Request) (*http.Response, error) {
+		resp := http.Response{
+			Body:       ioutil.NopCloser(bytes.NewBufferString(`["btcusd","ltcusd","ethusd"]`)),
+			StatusCode: 200,
+		}
+		return &resp, nil
+	}
+
+	w := NewClient().WebSocket
+	if err := w.SubscribeTicker(ETHUSD, make(chan TickerUpdate)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.SubscribeAllTickers(); err == nil {
+		t.Error("Expected an error for the pair already subscribed")
+	}
+	subscribes := w.subscriptions()
+	if len(subscribes) != 1 || subscribes[0].Pair != ETHUSD {
+		t.Error("Expected only the ETHUSD ticker left, got", subscribes)
+	}
+}
+
+func TestWaitForPrice(t *testing.T) {
+	unsubscribed := make(chan unsubscribeMsg, 2)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"ticker","chanId":3,"pair":"BTCUSD"}`,
+			`[3,449,1,451,1,0,0,450,1000,460,440]`,
+			`[3,9999,1,10001,1,0,0,10000,1000,10010,440]`,
+			// nobody waits for these anymore
+			`[3,9999,1,10001,1,0,0,10001,1000,10010,440]`,
+			`[3,9999,1,10001,1,0,0,10002,1000,10010,440]`,
+		)
+		for {
+			var msg unsubscribeMsg
+			if err := ws.ReadJSON(&msg); err != nil {
+				return
+			}
+			unsubscribed <- msg
+		}
+	})
+	defer srv.Close()
+
+	start := time.Now()
+	tick, err := c.WebSocket.WaitForPrice(BTCUSD, func(t TickerUpdate) bool { return t.LastPrice >= 10000 }, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tick.LastPrice != 10000 {
+		t.Error("Expected", 10000)
+		t.Error("Actual ", tick.LastPrice)
+	}
+	// the close handshake doesn't wait for the read loop to time out
+	if elapsed := time.Since(start); elapsed >= closeTimeout {
+		t.Error("Expected WaitForPrice to return quickly, took", elapsed)
+	}
+	select {
+	case msg := <-unsubscribed:
+		if msg.Event != "unsubscribe" || msg.ChanId != 3 {
+			t.Error("Expected", unsubscribeMsg{Event: "unsubscribe", ChanId: 3})
+			t.Error("Actual ", msg)
+		}
+	case <-time.After(time.Second):
+		t.Error("Expected the ticker to be unsubscribed")
+	}
+
+	_, err = c.WebSocket.WaitForPrice(BTCUSD, func(t TickerUpdate) bool { return false }, 50*time.Millisecond)
+	if err != ErrWaitTimeout {
+		t.Error("Expected", ErrWaitTimeout)
+		t.Error("Actual ", err)
+	}
+}
@@ -0,0 +1,98 @@
+package bitfinex
+
+import (
+	"sort"
+	"time"
+)
+
+// TradeUpdate is a single trade received on the trades channel.
+type TradeUpdate struct {
+	// ID is the trade id. It is only known for snapshot trades.
+	ID        int64
+	Timestamp int64
+	Price     float64
+	// Amount is signed: positive for buys, negative for sells
+	Amount float64
+	// Side is the taker side, derived from the sign of Amount
+	Side Side
+	// Snapshot is set for the recent trades sent right after subscribing.
+	Snapshot bool
+	// ServerTime is the time the server sent the trade, set with CONF_TIMESTAMP.
+	ServerTime time.Time
+}
+
+func (el *TradeUpdate) Time() *time.Time {
+	t := time.Unix(el.Timestamp, 0)
+	return &t
+}
+
+// SubscribeTrades adds a trades subscription for pair delivering typed
+// updates to c once Subscribe is called. The snapshot of recent trades is
+// sent first, one TradeUpdate per trade in chronological order, followed
+// by live executions. Bitfinex repeats each execution later as a "tu"
+// message; those are not forwarded so every trade is delivered once.
+func (w *WebSocketService) SubscribeTrades(pair string, c chan TradeUpdate) error {
+	return w.subscribeTrades(pair, func(t TradeUpdate) { c <- t }, c)
+}
+
+// SubscribeTradesFunc is like SubscribeTrades, calling fn on the read
+// goroutine instead, see AddSubscribeFunc.
+func (w *WebSocketService) SubscribeTradesFunc(pair string, fn func(TradeUpdate)) error {
+	return w.subscribeTrades(pair, fn, nil)
+}
+
+func (w *WebSocketService) subscribeTrades(pair string, fn func(TradeUpdate), out interface{}) error {
+	return w.addSubscribe(&subscribeToChannel{
+		Channel: CHAN_TRADE,
+		Pair:    pair,
+		Len:     ChannelDefaultLen,
+		out:     out,
+		deliver: func(f dataFrame) {
+			for _, t := range decodeTrades(f) {
+				fn(t)
+			}
+		},
+	})
+}
+
+// decodeTrades converts a trades channel frame into trade updates.
+func decodeTrades(f dataFrame) []TradeUpdate {
+	if !f.Snapshot {
+		// [TIMESTAMP, PRICE, AMOUNT]
+		if f.Term != "te" || len(f.Rows[0]) < 3 {
+			return nil
+		}
+		row := f.Rows[0]
+		return []TradeUpdate{{
+			Timestamp:  int64(row[0]),
+			Price:      row[1],
+			Amount:     row[2],
+			Side:       SideOf(row[2]),
+			ServerTime: f.ServerTime,
+		}}
+	}
+
+	// [[ID, TIMESTAMP, PRICE, AMOUNT], ...], newest first
+	trades := make([]TradeUpdate, 0, len(f.Rows))
+	for _, row := range f.Rows {
+		if len(row) < 4 {
+			continue
+		}
+		trades = append(trades, TradeUpdate{
+			ID:         int64(row[0]),
+			Timestamp:  int64(row[1]),
+			Price:      row[2],
+			Amount:     row[3],
+			Side:       SideOf(row[3]),
+			Snapshot:   true,
+			ServerTime: f.ServerTime,
+		})
+	}
+	sort.SliceStable(trades, func(i, j int) bool {
+		if trades[i].Timestamp != trades[j].Timestamp {
+			return trades[i].Timestamp < trades[j].Timestamp
+		}
+		return trades[i].ID < trades[j].ID
+	})
+	return trades
+}
@@ -0,0 +1,54 @@
+package bitfinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestSubscribeTrades(t *testing.T) {
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"trades","chanId":7,"pair":"BTCUSD"}`,
+			`[7,[[12,1444276599,451,-0.2],[11,1444276598,450,0.5]]]`,
+			`[7,"te","1234-BTCUSD",1444276600,452,0.1]`,
+			`[7,"tu","1234-BTCUSD",13,1444276600,452,0.1]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	trades := make(chan TradeUpdate, 10)
+	c.WebSocket.SubscribeTrades(BTCUSD, trades)
+	go c.WebSocket.Subscribe()
+
+	expected := []TradeUpdate{
+		{ID: 11, Timestamp: 1444276598, Price: 450, Amount: 0.5, Side: BUY, Snapshot: true},
+		{ID: 12, Timestamp: 1444276599, Price: 451, Amount: -0.2, Side: SELL, Snapshot: true},
+		{Timestamp: 1444276600, Price: 452, Amount: 0.1, Side: BUY},
+	}
+	for _, e := range expected {
+		select {
+		case v := <-trades:
+			if v != e {
+				t.Error("Expected", e)
+				t.Error("Actual ", v)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for trade")
+		}
+	}
+
+	select {
+	case v := <-trades:
+		t.Error("Unexpected trade", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,58 @@
+package bitfinex
+
+import (
+	"strings"
+)
+
+// ProtocolVersion is the version of the Bitfinex websocket protocol a
+// service speaks.
+type ProtocolVersion int
+
+const (
+	// PROTOCOL_V1 is spoken on DefaultWebSocketURL
+	PROTOCOL_V1 ProtocolVersion = 1
+	// PROTOCOL_V2 is spoken on DefaultWebSocketV2URL. Subscriptions are
+	// sent with a v2 symbol, e.g. tBTCUSD, and trades frames are converted
+	// to the v1 layouts, with timestamps in seconds, before they are
+	// delivered.
+	PROTOCOL_V2 ProtocolVersion = 2
+)
+
+// Protocol returns the protocol version of the service: Version when set,
+// otherwise PROTOCOL_V2 for a client WebSocketURL ending with /ws/2 and
+// PROTOCOL_V1 for any other.
+func (w *WebSocketService) Protocol() ProtocolVersion {
+	if w.Version != 0 {
+		return w.Version
+	}
+	if strings.HasSuffix(strings.TrimSuffix(w.client.WebSocketURL, "/"), "/ws/2") {
+		return PROTOCOL_V2
+	}
+	return PROTOCOL_V1
+}
+
+// v1Frame converts the rows of a v2 frame for s to the v1 layouts the
+// decoders expect. Only the trades layouts differ.
+func v1Frame(s *subscribeToChannel, f dataFrame) dataFrame {
+	if s.Channel != CHAN_TRADE {
+		return f
+	}
+	rows := make([][]float64, 0, len(f.Rows))
+	for _, row := range f.Rows {
+		// [ID, MTS, AMOUNT, PRICE]
+		if len(row) < 4 {
+			rows = append(rows, row)
+			continue
+		}
+		id, ts, amount, price := row[0], float64(int64(row[1])/1000), row[2], row[3]
+		if f.Term == "te" {
+			// [TIMESTAMP, PRICE, AMOUNT]
+			rows = append(rows, []float64{ts, price, amount})
+		} else {
+			// [ID, TIMESTAMP, PRICE, AMOUNT]
+			rows = append(rows, []float64{id, ts, price, amount})
+		}
+	}
+	f.Rows = rows
+	return f
+}
@@ -0,0 +1,73 @@
+package bitfinex
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestProtocol(t *testing.T) {
+	c := NewClient()
+	if v := c.WebSocket.Protocol(); v != PROTOCOL_V1 {
+		t.Error("Expected", PROTOCOL_V1)
+		t.Error("Actual ", v)
+	}
+	c.WebSocketURL = DefaultWebSocketV2URL
+	if v := c.WebSocket.Protocol(); v != PROTOCOL_V2 {
+		t.Error("Expected", PROTOCOL_V2)
+		t.Error("Actual ", v)
+	}
+	c.WebSocket.Version = PROTOCOL_V1
+	if v := c.WebSocket.Protocol(); v != PROTOCOL_V1 {
+		t.Error("Expected", PROTOCOL_V1)
+		t.Error("Actual ", v)
+	}
+}
+
+func TestSubscribeTradesV2(t *testing.T) {
+	subscribed := make(chan SubscribeMsg, 1)
+	srv, c := newMockServer(t, func(ws *websocket.Conn) {
+		subscribed <- readSubscribe(t, ws)
+		writeFrames(ws,
+			`{"event":"subscribed","channel":"trades","chanId":3,"symbol":"tBTCUSD"}`,
+			`[3,[[2,1444141857000,0.5,11.2],[1,1444141800000,-1,11]]]`,
+			`[3,"hb"]`,
+			`[3,"te",[3,1444141900000,-0.2,11.4]]`,
+			`[3,"tu",[3,1444141900000,-0.2,11.4]]`,
+		)
+		ws.ReadMessage()
+	})
+	defer srv.Close()
+
+	c.WebSocket.Version = PROTOCOL_V2
+	c.WebSocket.StrictDecoding = true
+	if err := c.WebSocket.Connect(); err != nil {
+		t.Fatal(err)
+	}
+	defer c.WebSocket.Close()
+
+	trades := make(chan TradeUpdate, 10)
+	c.WebSocket.SubscribeTrades(BTCUSD, trades)
+	go c.WebSocket.Subscribe()
+
+	if msg := <-subscribed; msg.Symbol != "tBTCUSD" || msg.Pair != "" {
+		t.Error("Unexpected subscribe message", msg)
+	}
+	expected := []TradeUpdate{
+		{ID: 1, Timestamp: 1444141800, Price: 11, Amount: -1, Side: SELL, Snapshot: true},
+		{ID: 2, Timestamp: 1444141857, Price: 11.2, Amount: 0.5, Side: BUY, Snapshot: true},
+		{Timestamp: 1444141900, Price: 11.4, Amount: -0.2, Side: SELL},
+	}
+	for _, e := range expected {
+		select {
+		case v := <-trades:
+			if v != e {
+				t.Error("Expected", e)
+				t.Error("Actual ", v)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for trades")
+		}
+	}
+}